@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"sync"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
@@ -21,9 +22,48 @@ type Enricher interface {
 	IsIM(string) bool
 }
 
+// Enrichers holds the enricher of each chat backend, so requests are resolved
+// against the backend they belong to
+type Enrichers struct {
+	fallback  Enricher
+	enrichers map[string]Enricher
+	m         sync.RWMutex
+}
+
+// NewEnrichers returns a new set of enrichers that uses the fallback one for unknown origins
+func NewEnrichers(fallback Enricher) *Enrichers {
+	return &Enrichers{
+		fallback:  fallback,
+		enrichers: make(map[string]Enricher),
+	}
+}
+
+// Register registers the enricher of the chat backend with the given origin
+func (e *Enrichers) Register(origin string, enricher Enricher) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	e.enrichers[origin] = enricher
+}
+
+// For returns the enricher registered for the origin, or the fallback one
+func (e *Enrichers) For(origin string) Enricher {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	enricher, ok := e.enrichers[origin]
+	if !ok {
+		if origin != "" {
+			logrus.Warnf("no enricher registered for origin %s, using the default one", origin)
+		}
+		return e.fallback
+	}
+	return enricher
+}
+
 // Service provides a service suitable to manage command requests through the API:w
 type Service struct {
-	enricher   Enricher
+	enrichers  *Enrichers
 	requestsCh chan meeseeks.Request
 	shutdown   chan bool
 }
@@ -31,7 +71,7 @@ type Service struct {
 // New returns a new API service instance
 func New(enricher Enricher, path string) *Service {
 	s := &Service{
-		enricher,
+		NewEnrichers(enricher),
 		make(chan meeseeks.Request),
 		make(chan bool),
 	}
@@ -39,15 +79,22 @@ func New(enricher Enricher, path string) *Service {
 	return s
 }
 
+// RegisterEnricher registers the enricher used for the tokens issued in the chat backend with the given origin
+func (s *Service) RegisterEnricher(origin string, enricher Enricher) {
+	s.enrichers.Register(origin, enricher)
+}
+
 func (s *Service) sendMessage(token meeseeks.APIToken, message string) error {
-	channelID, err := s.enricher.ParseChannelLink(token.ChannelLink)
+	enricher := s.enrichers.For(token.Origin)
+
+	channelID, err := enricher.ParseChannelLink(token.ChannelLink)
 	if err != nil {
 		logrus.Errorf("Failed to parse channel link %s: %s. Dropping message!", token.ChannelLink, err)
 		// TODO: this error should go to the administration channel
 		return err
 	}
 
-	userID, err := s.enricher.ParseUserLink(token.UserLink)
+	userID, err := enricher.ParseUserLink(token.UserLink)
 	if err != nil {
 		logrus.Errorf("Failed to parse user link %s: %s. Dropping message!", token.UserLink, err)
 		// TODO: this error should go to the administration channel
@@ -63,12 +110,13 @@ func (s *Service) sendMessage(token meeseeks.APIToken, message string) error {
 		Command:     args[0],
 		Args:        args[1:],
 		UserID:      userID,
-		Username:    s.enricher.GetUsername(userID),
-		UserLink:    s.enricher.GetUserLink(userID),
+		Username:    enricher.GetUsername(userID),
+		UserLink:    enricher.GetUserLink(userID),
 		ChannelID:   channelID,
-		Channel:     s.enricher.GetChannel(channelID),
-		ChannelLink: s.enricher.GetChannelLink(channelID),
-		IsIM:        s.enricher.IsIM(channelID),
+		Channel:     enricher.GetChannel(channelID),
+		ChannelLink: enricher.GetChannelLink(channelID),
+		IsIM:        enricher.IsIM(channelID),
+		Origin:      token.Origin,
 	}
 	return nil
 }
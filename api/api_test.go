@@ -68,13 +68,25 @@ func TestAPIServer(t *testing.T) {
 			"someoneLink",
 			"generalLink",
 			"echo something",
+			"",
 		)
 		mocks.Must(t, "failed to create the token", err)
 
+		matrixToken, err := persistence.APITokens().Create(
+			"someoneLink",
+			"generalLink",
+			"echo something",
+			"matrix",
+		)
+		mocks.Must(t, "failed to create the matrix token", err)
+
 		s := api.New(mocks.EnricherStub{
 			IM: false,
 		}, "/api")
 		defer s.Shutdown()
+		s.RegisterEnricher("matrix", mocks.EnricherStub{
+			IM: true,
+		})
 
 		ch := make(chan meeseeks.Request)
 		go s.Listen(ch)
@@ -127,6 +139,7 @@ func TestAPIServer(t *testing.T) {
 					mocks.AssertEquals(t, "<@someone>", req.UserLink)
 					mocks.AssertEquals(t, "someone", req.UserID)
 					mocks.AssertEquals(t, false, req.IsIM)
+					mocks.AssertEquals(t, "", req.Origin)
 				},
 			},
 			{
@@ -140,6 +153,18 @@ func TestAPIServer(t *testing.T) {
 					mocks.AssertEquals(t, []string{"something", "with", "arguments", "that", "will", "be", "attached"}, req.Args)
 				},
 			},
+			{
+				"valid call with a token issued in another backend",
+				matrixToken,
+				"",
+				assertHttpStatus(http.StatusAccepted),
+				func(t *testing.T, ch chan meeseeks.Request) {
+					req := <-ch
+					mocks.AssertEquals(t, "echo", req.Command)
+					mocks.AssertEquals(t, "matrix", req.Origin)
+					mocks.AssertEquals(t, true, req.IsIM)
+				},
+			},
 		}
 		for _, tc := range tt {
 			t.Run(tc.name, func(t *testing.T) {
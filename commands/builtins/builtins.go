@@ -12,6 +12,7 @@ import (
 	"gitlab.com/yakshaving.art/meeseeks-box/commands"
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
+	"gitlab.com/yakshaving.art/meeseeks-box/scheduler"
 	"gitlab.com/yakshaving.art/meeseeks-box/text/template"
 	"gitlab.com/yakshaving.art/meeseeks-box/version"
 	"github.com/renstrom/dedent"
@@ -33,6 +34,14 @@ const (
 	BuiltinLogsCommand      = "logs"
 	BuiltinCancelJobCommand = "cancel"
 	BuiltinKillJobCommand   = "kill"
+	BuiltinApproveCommand   = "approve"
+	BuiltinDenyCommand      = "deny"
+	BuiltinConfirmCommand   = "confirm"
+	BuiltinDismissCommand   = "dismiss"
+	BuiltinLocksCommand     = "locks"
+	BuiltinSchedulesCommand = "schedules"
+	BuiltinScheduleCommand  = "schedule"
+	BuiltinAtCommand        = "at"
 
 	BuiltinNewAPITokenCommand    = "token-new"
 	BuiltinListAPITokenCommand   = "tokens"
@@ -86,6 +95,7 @@ var Commands = map[string]meeseeks.Command{
 	BuiltinAuditLogsCommand: auditLogsCommand{
 		help: newHelp(
 			"shows the logs of a job by ID (admin only)",
+			"-stream: only show the lines of stdout or stderr",
 			"job ID to look up for, mandatory",
 		),
 		cmd: cmd{BuiltinAuditLogsCommand},
@@ -107,6 +117,7 @@ var Commands = map[string]meeseeks.Command{
 		help: newHelp(
 			"returns the last lines of the last executed job, or one selected by job ID",
 			"-limit: how many lines to show",
+			"-stream: only show the lines of stdout or stderr",
 			"job ID to look for, optional, if not provided the last executed one will be looked up",
 		),
 		cmd: cmd{BuiltinTailCommand},
@@ -115,6 +126,7 @@ var Commands = map[string]meeseeks.Command{
 		help: newHelp(
 			"returns the top N log lines of a command output or error",
 			"-limit: how many lines to show",
+			"-stream: only show the lines of stdout or stderr",
 			"job ID to look for, optional, if not provided the last executed one will be looked up",
 		),
 		cmd: cmd{BuiltinHeadCommand},
@@ -122,6 +134,7 @@ var Commands = map[string]meeseeks.Command{
 	BuiltinLogsCommand: logsCommand{
 		help: newHelp(
 			"returns the full output of the job passed as argument",
+			"-stream: only show the lines of stdout or stderr",
 			"job ID to look for, mandatory",
 		),
 		cmd: cmd{BuiltinLogsCommand},
@@ -171,6 +184,30 @@ var Commands = map[string]meeseeks.Command{
 		),
 		cmd: cmd{BuiltinGetAliasesCommand},
 	},
+	BuiltinSchedulesCommand: schedulesCommand{
+		help: newHelp(
+			"lists the configured schedules and when they will run next",
+		),
+		cmd: cmd{BuiltinSchedulesCommand},
+	},
+	BuiltinScheduleCommand: scheduleCommand{
+		help: newHelp(
+			"manages the schedules of the current user",
+			"add: schedules a command to run periodically, like add \"every weekday 09:00\" command args",
+			"list: lists the schedules of the current user",
+			"rm: removes a schedule by ID",
+		),
+		cmd: cmd{BuiltinScheduleCommand},
+	},
+	BuiltinAtCommand: atCommand{
+		help: newHelp(
+			"schedules a command to run once at a given time",
+			"time to run the command at, like 18:00 or 2006-01-02T15:04, mandatory",
+			"command to run, mandatory",
+			"arguments to pass to the command, optional",
+		),
+		cmd: cmd{BuiltinAtCommand},
+	},
 	BuiltinHelpCommand: helpCommand{
 		help: newHelp(
 			"shows the help for all the commands, or a single one",
@@ -183,14 +220,24 @@ var Commands = map[string]meeseeks.Command{
 	// Added as a placeholder so they are recognized as a builtin command
 	BuiltinCancelJobCommand: nil,
 	BuiltinKillJobCommand:   nil,
+	BuiltinApproveCommand:   nil,
+	BuiltinDenyCommand:      nil,
+	BuiltinConfirmCommand:   nil,
+	BuiltinDismissCommand:   nil,
+	BuiltinLocksCommand:     nil,
 }
 
 var errNoJobIDAsArgument = fmt.Errorf("no job id passed")
 
 // LoadBuiltins loads the builtin commands
-func LoadBuiltins(cancelCommand, killCommand meeseeks.Command) error {
+func LoadBuiltins(cancelCommand, killCommand, approveCommand, denyCommand, confirmCommand, dismissCommand, locksCommand meeseeks.Command) error {
 	Commands[BuiltinCancelJobCommand] = cancelCommand
 	Commands[BuiltinKillJobCommand] = killCommand
+	Commands[BuiltinApproveCommand] = approveCommand
+	Commands[BuiltinDenyCommand] = denyCommand
+	Commands[BuiltinConfirmCommand] = confirmCommand
+	Commands[BuiltinDismissCommand] = dismissCommand
+	Commands[BuiltinLocksCommand] = locksCommand
 
 	reg := make([]commands.CommandRegistration, 0)
 
@@ -384,15 +431,18 @@ type cancelJobCommand struct {
 	allowAll
 	anyChannel
 	defaultTimeout
-	cancelFunc func(jobID uint64)
+	cancelFunc StopJobFunc
 }
 
+// StopJobFunc stops a job on behalf of the user that sent the request
+type StopJobFunc func(jobID uint64, requester meeseeks.Request)
+
 // NewCancelJobCommand creates a command that will invoke the passed cancel job function when executed
-func NewCancelJobCommand(f func(jobID uint64)) meeseeks.Command {
+func NewCancelJobCommand(f StopJobFunc) meeseeks.Command {
 	return cancelJobCommand{
 		help: newHelp(
-			"sends a cancellation signal to a job owned by the current user",
-			"job ID to send the signal to",
+			"asks a job owned by the current user to finish, killing it if it doesn't in its grace period",
+			"job ID to cancel",
 		),
 		cancelFunc: f,
 	}
@@ -410,7 +460,7 @@ func (c cancelJobCommand) Execute(_ context.Context, job meeseeks.Job) (string,
 	if job.Request.Username != j.Request.Username {
 		return "", meeseeks.ErrNoJobWithID
 	}
-	c.cancelFunc(jobID)
+	c.cancelFunc(jobID, job.Request)
 	return fmt.Sprintf("Issued command cancellation to job %d", jobID), nil
 }
 
@@ -423,17 +473,17 @@ type killJobCommand struct {
 	allowAdmins
 	anyChannel
 	defaultTimeout
-	cancelFunc func(jobID uint64)
+	killFunc StopJobFunc
 }
 
-// NewKillJobCommand creates a command that will invoke the passed cancel job function when executed
-func NewKillJobCommand(f func(jobID uint64)) meeseeks.Command {
+// NewKillJobCommand creates a command that will invoke the passed kill job function when executed
+func NewKillJobCommand(f StopJobFunc) meeseeks.Command {
 	return killJobCommand{
 		help: newHelp(
-			"sends a cancellation signal to a job, admin only",
-			"job ID to send the signal to",
+			"kills a job right away, admin only",
+			"job ID to kill",
 		),
-		cancelFunc: f,
+		killFunc: f,
 	}
 }
 
@@ -446,8 +496,183 @@ func (k killJobCommand) Execute(_ context.Context, job meeseeks.Job) (string, er
 	if err != nil {
 		return "", err
 	}
-	k.cancelFunc(jobID)
-	return fmt.Sprintf("Issued command cancellation to job %d", jobID), nil
+	k.killFunc(jobID, job.Request)
+	return fmt.Sprintf("Issued command kill to job %d", jobID), nil
+}
+
+// DecideJobFunc records the decision of the user that sent the request on a job pending approval
+type DecideJobFunc func(jobID uint64, approver meeseeks.Request) (meeseeks.Job, error)
+
+type approveJobCommand struct {
+	cmd
+	help
+	noHandshake
+	noRecord
+	emptyArgs
+	allowAll
+	anyChannel
+	defaultTimeout
+	approveFunc DecideJobFunc
+}
+
+// NewApproveJobCommand creates a command that will invoke the passed approve job function when executed
+func NewApproveJobCommand(f DecideJobFunc) meeseeks.Command {
+	return approveJobCommand{
+		help: newHelp(
+			"approves a job that is pending approval, approvers only",
+			"job ID to approve",
+		),
+		cmd:         cmd{BuiltinApproveCommand},
+		approveFunc: f,
+	}
+}
+
+func (a approveJobCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
+	jobID, err := parseJobID(job.Request.Args)
+	if err != nil {
+		return "", err
+	}
+	j, err := a.approveFunc(jobID, job.Request)
+	if err != nil {
+		return "", err
+	}
+	if j.Status == meeseeks.JobPendingApprovalStatus {
+		return fmt.Sprintf("Approved job %d, it still needs more approvals to run", jobID), nil
+	}
+	return fmt.Sprintf("Approved job %d, it is running now", jobID), nil
+}
+
+type denyJobCommand struct {
+	cmd
+	help
+	noHandshake
+	noRecord
+	emptyArgs
+	allowAll
+	anyChannel
+	defaultTimeout
+	denyFunc DecideJobFunc
+}
+
+// NewDenyJobCommand creates a command that will invoke the passed deny job function when executed
+func NewDenyJobCommand(f DecideJobFunc) meeseeks.Command {
+	return denyJobCommand{
+		help: newHelp(
+			"denies a job that is pending approval, approvers only",
+			"job ID to deny",
+		),
+		cmd:      cmd{BuiltinDenyCommand},
+		denyFunc: f,
+	}
+}
+
+func (d denyJobCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
+	jobID, err := parseJobID(job.Request.Args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := d.denyFunc(jobID, job.Request); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Denied job %d", jobID), nil
+}
+
+// AnswerConfirmationFunc answers a pending confirmation on behalf of a user,
+// returning the request that was answered
+type AnswerConfirmationFunc func(confirmationID, userID string, approved bool) (meeseeks.Request, error)
+
+type confirmCommand struct {
+	cmd
+	help
+	noHandshake
+	noRecord
+	emptyArgs
+	allowAll
+	anyChannel
+	defaultTimeout
+	approved   bool
+	answerFunc AnswerConfirmationFunc
+}
+
+// NewConfirmCommand creates a command that confirms a request of the calling user
+func NewConfirmCommand(f AnswerConfirmationFunc) meeseeks.Command {
+	return confirmCommand{
+		help: newHelp(
+			"confirms a request of the current user that is waiting for confirmation",
+			"confirmation ID, mandatory",
+		),
+		cmd:        cmd{BuiltinConfirmCommand},
+		approved:   true,
+		answerFunc: f,
+	}
+}
+
+// NewDismissCommand creates a command that dismisses a request of the calling user
+func NewDismissCommand(f AnswerConfirmationFunc) meeseeks.Command {
+	return confirmCommand{
+		help: newHelp(
+			"dismisses a request of the current user that is waiting for confirmation",
+			"confirmation ID, mandatory",
+		),
+		cmd:        cmd{BuiltinDismissCommand},
+		approved:   false,
+		answerFunc: f,
+	}
+}
+
+func (c confirmCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
+	if len(job.Request.Args) != 1 {
+		return "", fmt.Errorf("%s requires only one argument: the confirmation ID", c.GetCmd())
+	}
+	req, err := c.answerFunc(job.Request.Args[0], job.Request.UserID, c.approved)
+	if err != nil {
+		return "", err
+	}
+
+	verb := "Dismissed"
+	if c.approved {
+		verb = "Confirmed"
+	}
+	return fmt.Sprintf("%s `%s`", verb, strings.Join(append([]string{req.Command}, req.Args...), " ")), nil
+}
+
+// ListLocksFunc returns the locks held by running jobs
+type ListLocksFunc func() []meeseeks.Lock
+
+type locksCommand struct {
+	cmd
+	help
+	noHandshake
+	noRecord
+	emptyArgs
+	allowAll
+	anyChannel
+	defaultTimeout
+	locksFunc ListLocksFunc
+}
+
+// NewLocksCommand creates a command that shows the locks held by running jobs
+func NewLocksCommand(f ListLocksFunc) meeseeks.Command {
+	return locksCommand{
+		help: newHelp(
+			"shows the locks held by running jobs",
+		),
+		cmd:       cmd{BuiltinLocksCommand},
+		locksFunc: f,
+	}
+}
+
+var locksTemplate = `{{ if eq (len .locks) 0 }}No lock is held{{ else }}{{ range $l := .locks }}- *{{ $l.Name }}* - held by job *{{ $l.Job.ID }}* ` + "`" + `{{ $l.Job.Request.Command }}{{ range $arg := $l.Job.Request.Args }} {{ $arg }}{{ end }}` + "`" + ` by *{{ $l.Job.Request.Username }}* since {{ HumanizeTime $l.Job.StartTime }}{{ with $l.Waiting }}, {{ . }} waiting{{ end }}
+{{ end }}{{ end }}`
+
+func (l locksCommand) Execute(_ context.Context, _ meeseeks.Job) (string, error) {
+	tmpl, err := template.New("locks", locksTemplate)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(map[string]interface{}{
+		"locks": l.locksFunc(),
+	})
 }
 
 type groupsCommand struct {
@@ -515,21 +740,23 @@ func jobsMultiMatch(matchers ...func(meeseeks.Job) bool) func(meeseeks.Job) bool
 	}
 }
 
+// statusFlagHelp is the help of the flag that filters jobs per status
+const statusFlagHelp = "filter jobs per status (running, queued, pendingapproval, denied, failed, warning, cancelled, killed or successful)"
+
 func (j jobsCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
 	flags := flag.NewFlagSet("jobs", flag.ContinueOnError)
 	limit := flags.Int("limit", 5, "how many jobs to return")
-	status := flags.String("status", "", "filter jobs per status (running, failed or successful)")
+	status := flags.String("status", "", statusFlagHelp)
 	if err := flags.Parse(job.Request.Args); err != nil {
 		return "", err
 	}
 
 	callingUser := job.Request.Username
-	requestedStatus := strings.Title(*status)
 	jobs, err := persistence.Jobs().Find(meeseeks.JobFilter{
 		Limit: *limit,
 		Match: jobsMultiMatch(
 			isUser(callingUser),
-			isStatusOrEmpty(requestedStatus),
+			isStatusOrEmpty(*status),
 		),
 	})
 
@@ -560,17 +787,15 @@ func (j auditCommand) Execute(_ context.Context, job meeseeks.Job) (string, erro
 	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
 	limit := flags.Int("limit", 5, "how many jobs to return")
 	user := flags.String("user", "", "the user to audit")
-	status := flags.String("status", "", "filter jobs per status (running, failed or successful)")
+	status := flags.String("status", "", statusFlagHelp)
 	if err := flags.Parse(job.Request.Args); err != nil {
 		return "", err
 	}
 
-	requestedStatus := strings.Title(*status)
-
 	jobs, err := persistence.Jobs().Find(meeseeks.JobFilter{
 		Limit: *limit,
 		Match: jobsMultiMatch(
-			isStatusOrEmpty(requestedStatus),
+			isStatusOrEmpty(*status),
 			func(j meeseeks.Job) bool {
 				if *user == "" {
 					return true
@@ -605,14 +830,26 @@ type lastCommand struct {
 
 var jobTemplate = `
 {{- with $job := .job }}{{ with $r := $job.Request }}* *ID* {{ $job.ID }}
-* *Status* {{ $job.Status}}
+* *Status* {{ $job.Status}}{{ with $job.StoppedBy }} by {{ . }}{{ end }}
 * *Command* {{ $r.Command }}{{ with $args := $r.Args }}
 * *Args* "{{ Join $args "\" \"" }}" {{ end }}
 * *Where* {{ if $r.IsIM }}IM{{ else }}{{ $r.ChannelLink }}{{ end }}
 * *When* {{ HumanizeTime $job.StartTime }}
-{{- end }}{{- end }}
+{{- if not $job.EndTime.IsZero }}
+* *Took* {{ $job.Duration }}{{ end }}
+{{- with $res := $job.Result }}
+* *Exit code* {{ $res.ExitCode }}{{ with $res.Signal }} ({{ . }}){{ end }}
+* *Host* {{ $res.Hostname }}{{ with $res.AgentID }} (agent {{ . }}){{ end }}
+* *Output* {{ HumanizeSize $res.OutputBytes }}{{ if $res.Truncated }}, truncated{{ end }}
+{{- end }}{{- end }}{{- end }}
 `
 
+var auditJobTemplate = jobTemplate + `{{ with $approvals := .job.Approvals }}* *Approvals*
+{{- range $a := $approvals }}
+  - {{ if $a.Approved }}approved{{ else }}denied{{ end }} by {{ $a.Username }} {{ HumanizeTime $a.Time }}
+{{- end }}
+{{ end }}`
+
 func (l lastCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
 	callingUser := job.Request.Username
 	jobs, err := persistence.Jobs().Find(meeseeks.JobFilter{
@@ -702,7 +939,7 @@ func (l auditJobCommand) Execute(_ context.Context, job meeseeks.Job) (string, e
 		return "", fmt.Errorf("job not found")
 	}
 
-	tmpl, err := template.New("job", jobTemplate)
+	tmpl, err := template.New("job", auditJobTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -723,7 +960,16 @@ type auditLogsCommand struct {
 }
 
 func (t auditLogsCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
-	id, err := parseJobID(job.Request.Args)
+	flags := flag.NewFlagSet("auditlogs", flag.ContinueOnError)
+	stream := streamFlag(flags)
+	if err := flags.Parse(job.Request.Args); err != nil {
+		return "", err
+	}
+	if err := checkStream(*stream); err != nil {
+		return "", err
+	}
+
+	id, err := parseJobID(flags.Args())
 	if err != nil {
 		return "", err
 	}
@@ -741,7 +987,7 @@ func (t auditLogsCommand) Execute(_ context.Context, job meeseeks.Job) (string,
 	}
 	j := jobs[0]
 
-	jobLogs, err := persistence.LogReader().Get(j.ID)
+	jobLogs, err := persistence.LogReader().Get(j.ID, *stream)
 	if err != nil {
 		return "", err
 	}
@@ -762,8 +1008,12 @@ type tailCommand struct {
 func (t tailCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
 	flags := flag.NewFlagSet("tail", flag.ContinueOnError)
 	limit := flags.Int("limit", 5, "how many lines to return")
+	stream := streamFlag(flags)
 
 	flags.Parse(job.Request.Args)
+	if err := checkStream(*stream); err != nil {
+		return "", err
+	}
 
 	jobID, err := parseJobID(flags.Args())
 
@@ -774,7 +1024,7 @@ func (t tailCommand) Execute(_ context.Context, job meeseeks.Job) (string, error
 		return "", err
 	}
 
-	jobLogs, err := persistence.LogReader().Tail(jobID, *limit)
+	jobLogs, err := persistence.LogReader().Tail(jobID, *stream, *limit)
 	if err != nil {
 		return "", err
 	}
@@ -795,8 +1045,12 @@ type headCommand struct {
 func (h headCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
 	flags := flag.NewFlagSet("head", flag.ContinueOnError)
 	limit := flags.Int("limit", 5, "how many lines to return")
+	stream := streamFlag(flags)
 
 	flags.Parse(job.Request.Args)
+	if err := checkStream(*stream); err != nil {
+		return "", err
+	}
 
 	jobID, err := parseJobID(flags.Args())
 
@@ -807,7 +1061,7 @@ func (h headCommand) Execute(_ context.Context, job meeseeks.Job) (string, error
 		return "", err
 	}
 
-	jobLogs, err := persistence.LogReader().Head(jobID, *limit)
+	jobLogs, err := persistence.LogReader().Head(jobID, *stream, *limit)
 	if err != nil {
 		return "", err
 	}
@@ -826,7 +1080,16 @@ type logsCommand struct {
 }
 
 func (t logsCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
-	id, err := parseJobID(job.Request.Args)
+	flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	stream := streamFlag(flags)
+	if err := flags.Parse(job.Request.Args); err != nil {
+		return "", err
+	}
+	if err := checkStream(*stream); err != nil {
+		return "", err
+	}
+
+	id, err := parseJobID(flags.Args())
 	if err != nil {
 		return "", err
 	}
@@ -846,13 +1109,25 @@ func (t logsCommand) Execute(_ context.Context, job meeseeks.Job) (string, error
 	}
 	j := jobs[0]
 
-	jobLogs, err := persistence.LogReader().Get(j.ID)
+	jobLogs, err := persistence.LogReader().Get(j.ID, *stream)
 	if err != nil {
 		return "", err
 	}
 	return jobLogs.Output, jobLogs.GetError()
 }
 
+func streamFlag(flags *flag.FlagSet) *string {
+	return flags.String("stream", "", "only show the lines of a stream (stdout or stderr)")
+}
+
+func checkStream(stream string) error {
+	switch stream {
+	case "", meeseeks.StreamStdout, meeseeks.StreamStderr:
+		return nil
+	}
+	return fmt.Errorf("invalid stream %s, it should be stdout or stderr", stream)
+}
+
 type newAPITokenCommand struct {
 	cmd
 	help
@@ -873,6 +1148,7 @@ func (n newAPITokenCommand) Execute(_ context.Context, job meeseeks.Job) (string
 		job.Request.Args[0],
 		job.Request.Args[1],
 		strings.Join(job.Request.Args[2:], " "),
+		job.Request.Origin,
 	)
 	return fmt.Sprintf("created token %s", t), err
 }
@@ -1044,6 +1320,181 @@ func (l getAliasesCommand) Execute(_ context.Context, job meeseeks.Job) (string,
 	})
 }
 
+type schedulesCommand struct {
+	cmd
+	help
+	noHandshake
+	noRecord
+	allowAll
+	anyChannel
+	emptyArgs
+	defaultTimeout
+}
+
+var schedulesTemplate = `{{ if eq (len .schedules) 0 }}No schedule is configured{{ else }}{{ range $s := .schedules }}- *{{ $s.Name }}* - ` + "`" + `{{ $s.Schedule.Command }}{{ range $arg := $s.Schedule.Args }} {{ $arg }}{{ end }}` + "`" + ` as {{ $s.Schedule.User }}{{ with $s.Schedule.Channel }} in {{ . }}{{ end }}, next run {{ if $s.Next.IsZero }}never{{ else }}{{ HumanizeTime $s.Next }}{{ end }} ({{ $s.Schedule.Cron }})
+{{ end }}{{ end }}`
+
+func (s schedulesCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
+	tmpl, err := template.New("schedules", schedulesTemplate)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(map[string]interface{}{
+		"schedules": scheduler.List(time.Now()),
+	})
+}
+
+const scheduleTimeLayout = "2006-01-02 15:04"
+
+type scheduleCommand struct {
+	cmd
+	help
+	noHandshake
+	noRecord
+	allowAll
+	anyChannel
+	emptyArgs
+	defaultTimeout
+}
+
+var userSchedulesTemplate = `{{ if eq (len .schedules) 0 }}No schedule could be found{{ else }}{{ range $s := .schedules }}- *{{ $s.ID }}* - ` + "`" + `{{ $s.Request.Command }}{{ range $arg := $s.Request.Args }} {{ $arg }}{{ end }}` + "`" + ` {{ if $s.IsRecurring }}{{ $s.Spec }}{{ else }}at {{ $s.Spec }}{{ end }}, next run {{ $s.Next }}
+{{ end }}{{ end }}`
+
+func (s scheduleCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
+	args := job.Request.Args
+	if len(args) == 0 {
+		return "", fmt.Errorf("schedule requires a subcommand: add, list or rm")
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 3 {
+			return "", fmt.Errorf("schedule add requires at least two arguments: the schedule and the command")
+		}
+		c, err := scheduler.ParseEvery(args[1])
+		if err != nil {
+			return "", err
+		}
+		return addSchedule(job.Request, meeseeks.Schedule{
+			Spec: args[1],
+			Cron: c.String(),
+		}, args[2], args[3:])
+
+	case "list":
+		schedules, err := persistence.Schedules().Find(meeseeks.ScheduleFilter{
+			Match: func(s meeseeks.Schedule) bool {
+				return s.Request.UserID == job.Request.UserID
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		list := make([]scheduleWithNextRun, 0, len(schedules))
+		for _, sch := range schedules {
+			list = append(list, scheduleWithNextRun{sch, nextRun(sch)})
+		}
+		tmpl, err := template.New("schedules", userSchedulesTemplate)
+		if err != nil {
+			return "", err
+		}
+		return tmpl.Render(map[string]interface{}{
+			"schedules": list,
+		})
+
+	case "rm":
+		id, err := parseScheduleID(args[1:])
+		if err != nil {
+			return "", err
+		}
+		sch, err := persistence.Schedules().Get(id)
+		if err != nil {
+			return "", err
+		}
+		if sch.Request.UserID != job.Request.UserID {
+			return "", meeseeks.ErrNoScheduleWithID
+		}
+		if err := scheduler.Remove(id); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Removed schedule %d", id), nil
+	}
+	return "", fmt.Errorf("unknown schedule subcommand %s, it should be add, list or rm", args[0])
+}
+
+type atCommand struct {
+	cmd
+	help
+	noHandshake
+	noRecord
+	allowAll
+	anyChannel
+	emptyArgs
+	defaultTimeout
+}
+
+func (a atCommand) Execute(_ context.Context, job meeseeks.Job) (string, error) {
+	args := job.Request.Args
+	if len(args) < 2 {
+		return "", fmt.Errorf("at requires at least two arguments: the time and the command")
+	}
+	runAt, err := scheduler.ParseAt(args[0], time.Now())
+	if err != nil {
+		return "", err
+	}
+	return addSchedule(job.Request, meeseeks.Schedule{
+		Spec:  args[0],
+		RunAt: runAt,
+	}, args[1], args[2:])
+}
+
+// addSchedule persists a schedule that runs the command as the user that sent
+// the request, failing if the user is not allowed to run it right now
+func addSchedule(req meeseeks.Request, s meeseeks.Schedule, command string, args []string) (string, error) {
+	req.Command = command
+	req.Args = args
+	req.ThreadID = ""
+
+	found := req
+	cmd, ok := commands.Find(&found)
+	if !ok {
+		return "", fmt.Errorf("unknown command %s", command)
+	}
+	if err := auth.Check(found, cmd); err != nil {
+		return "", fmt.Errorf("you are not allowed to run %s here", command)
+	}
+
+	s.Request = req
+	s, err := scheduler.Add(s)
+	if err != nil {
+		return "", fmt.Errorf("could not create the schedule: %s", err)
+	}
+	return fmt.Sprintf("Scheduled %s as schedule %d, next run %s", command, s.ID, nextRun(s)), nil
+}
+
+type scheduleWithNextRun struct {
+	meeseeks.Schedule
+	Next string
+}
+
+func nextRun(s meeseeks.Schedule) string {
+	next := scheduler.NextRun(s, time.Now())
+	if next.IsZero() {
+		return "never"
+	}
+	return next.Format(scheduleTimeLayout)
+}
+
+func parseScheduleID(args []string) (uint64, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("no schedule id passed")
+	}
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule id %s: %s", args[0], err)
+	}
+	return id, nil
+}
+
 // Helper functions from now on
 
 func parseJobID(args []string) (uint64, error) {
@@ -1075,7 +1526,7 @@ func isStatusOrEmpty(status string) func(meeseeks.Job) bool {
 		if status == "" {
 			return true
 		}
-		return j.Status == status
+		return strings.EqualFold(j.Status, status)
 	}
 }
 
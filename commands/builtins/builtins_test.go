@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/auth"
 	"gitlab.com/yakshaving.art/meeseeks-box/commands"
@@ -13,6 +14,7 @@ import (
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
+	"gitlab.com/yakshaving.art/meeseeks-box/scheduler"
 )
 
 var basicGroups = map[string][]string{
@@ -33,10 +35,39 @@ var req = meeseeks.Request{
 func Test_BuiltinCommands(t *testing.T) {
 	auth.Configure(basicGroups)
 
-	cancelCmd := builtins.NewCancelJobCommand(func(_ uint64) {})
-	killCmd := builtins.NewKillJobCommand(func(_ uint64) {})
+	cancelCmd := builtins.NewCancelJobCommand(func(_ uint64, _ meeseeks.Request) {})
+	killCmd := builtins.NewKillJobCommand(func(_ uint64, _ meeseeks.Request) {})
 
-	builtins.LoadBuiltins(cancelCmd, killCmd)
+	decideFunc := func(jobID uint64, _ meeseeks.Request) (meeseeks.Job, error) {
+		return meeseeks.Job{ID: jobID, Status: meeseeks.JobRunningStatus}, nil
+	}
+	approveCmd := builtins.NewApproveJobCommand(decideFunc)
+	denyCmd := builtins.NewDenyJobCommand(decideFunc)
+
+	answerFunc := func(confirmationID, userID string, _ bool) (meeseeks.Request, error) {
+		if confirmationID != "abc123" || userID != "userid" {
+			return meeseeks.Request{}, fmt.Errorf("unknown confirmation")
+		}
+		return meeseeks.Request{Command: "deploy", Args: []string{"app"}}, nil
+	}
+	confirmCmd := builtins.NewConfirmCommand(answerFunc)
+	dismissCmd := builtins.NewDismissCommand(answerFunc)
+
+	locksCmd := builtins.NewLocksCommand(func() []meeseeks.Lock {
+		return []meeseeks.Lock{
+			{
+				Name: "deploy-prod",
+				Job: meeseeks.Job{
+					ID:        42,
+					Request:   meeseeks.Request{Command: "deploy", Args: []string{"prod"}, Username: "someone"},
+					StartTime: time.Now(),
+				},
+				Waiting: 2,
+			},
+		}
+	})
+
+	builtins.LoadBuiltins(cancelCmd, killCmd, approveCmd, denyCmd, confirmCmd, dismissCmd, locksCmd)
 
 	tt := []struct {
 		name                    string
@@ -84,18 +115,26 @@ func Test_BuiltinCommands(t *testing.T) {
 			job: meeseeks.Job{Request: meeseeks.Request{Args: []string{"-all"}}},
 			expected: `- alias: adds an alias for a command for the current user
 - aliases: list all the aliases for the current user
+- approve: approves a job that is pending approval, approvers only
+- at: schedules a command to run once at a given time
 - audit: lists jobs from all users or a specific one (admin only)
 - auditjob: shows a command metadata by job ID (admin only)
 - auditlogs: shows the logs of a job by ID (admin only)
-- cancel: sends a cancellation signal to a job owned by the current user
+- cancel: asks a job owned by the current user to finish, killing it if it doesn't in its grace period
+- confirm: confirms a request of the current user that is waiting for confirmation
+- deny: denies a job that is pending approval, approvers only
+- dismiss: dismisses a request of the current user that is waiting for confirmation
 - groups: prints the configured groups
 - head: returns the top N log lines of a command output or error
 - help: shows the help for all the commands, or a single one
 - job: show metadata of one job by id
 - jobs: shows the last executed jobs for the calling user
-- kill: sends a cancellation signal to a job, admin only
+- kill: kills a job right away, admin only
 - last: shows the last job metadata executed by the current user
+- locks: shows the locks held by running jobs
 - logs: returns the full output of the job passed as argument
+- schedule: manages the schedules of the current user
+- schedules: lists the configured schedules and when they will run next
 - tail: returns the last lines of the last executed job, or one selected by job ID
 - token-new: creates a new API token
 - token-revoke: revokes an API token
@@ -240,6 +279,33 @@ func Test_BuiltinCommands(t *testing.T) {
 			expectedAuthStrategy:    auth.AuthStrategyAny,
 			expectedChannelStrategy: auth.ChannelStrategyAny,
 		},
+		{
+			name: "test last command with result",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinLastCommand,
+				UserID:  "userid",
+			},
+
+			job: meeseeks.Job{
+				Request: meeseeks.Request{Username: "someone"},
+			},
+			setup: func() {
+				j, err := persistence.Jobs().Create(req)
+				mocks.Must(t, "create job", err)
+				mocks.Must(t, "set result", persistence.Jobs().SetResult(j.ID, meeseeks.JobResult{
+					ExitCode:    -1,
+					Signal:      "killed",
+					AgentID:     "agent-1",
+					Hostname:    "worker-1",
+					OutputBytes: 2048,
+					Truncated:   true,
+				}))
+			},
+			expected: "* *ID* 1\n* *Status* Running\n* *Command* command\n* *Args* \"arg1\" \"arg2\" \n* *Where* <#123>\n* *When* now\n" +
+				"* *Exit code* -1 (killed)\n* *Host* worker-1 (agent agent-1)\n* *Output* 2.0 kB, truncated\n",
+			expectedAuthStrategy:    auth.AuthStrategyAny,
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
 		{
 			name: "test find command",
 			req: meeseeks.Request{
@@ -327,6 +393,33 @@ func Test_BuiltinCommands(t *testing.T) {
 			expectedAuthStrategy:    auth.AuthStrategyAny,
 			expectedChannelStrategy: auth.ChannelStrategyAny,
 		},
+		{
+			name: "test schedules command",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinSchedulesCommand,
+				UserID:  "userid",
+			},
+			job: meeseeks.Job{
+				Request: meeseeks.Request{
+					Command: "schedules",
+					UserID:  "userid",
+				},
+			},
+			setup: func() {
+				mocks.Must(t, "configure schedules", scheduler.Configure(map[string]scheduler.Schedule{
+					"never": {
+						Cron:    "0 0 30 2 *",
+						Command: "echo",
+						Args:    []string{"hello", "world"},
+						User:    "someone",
+						Channel: "general",
+					},
+				}))
+			},
+			expected:                "- *never* - `echo hello world` as someone in general, next run never (0 0 30 2 *)\n",
+			expectedAuthStrategy:    auth.AuthStrategyAny,
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
 		{
 			name: "test alias execution",
 			req: meeseeks.Request{
@@ -389,6 +482,108 @@ func Test_BuiltinCommands(t *testing.T) {
 			expectedAllowedGroups:   []string{auth.AdminGroup},
 			expectedChannelStrategy: auth.ChannelStrategyAny,
 		},
+		{
+			name: "test auditjob command with approvals",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinAuditJobCommand,
+				UserID:  "userid",
+			},
+
+			job: meeseeks.Job{
+				Request: meeseeks.Request{Username: "someone", Args: []string{"1"}},
+			},
+			setup: func() {
+				j, err := persistence.Jobs().RequestApproval(req)
+				mocks.Must(t, "request approval", err)
+				_, err = persistence.Jobs().AddApproval(j.ID, meeseeks.Approval{
+					Username: "admin_user",
+					Approved: true,
+					Time:     time.Now(),
+				}, 2)
+				mocks.Must(t, "approve job", err)
+			},
+			expected: "* *ID* 1\n* *Status* PendingApproval\n* *Command* command\n* *Args* \"arg1\" \"arg2\" \n* *Where* <#123>\n* *When* now\n" +
+				"* *Approvals*\n  - approved by admin_user now\n",
+			expectedAuthStrategy:    auth.AuthStrategyAllowedGroup,
+			expectedAllowedGroups:   []string{auth.AdminGroup},
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
+		{
+			name: "test approve command",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinApproveCommand,
+				UserID:  "userid",
+			},
+			job: meeseeks.Job{
+				Request: meeseeks.Request{Username: "admin_user", Args: []string{"1"}},
+			},
+			expected:                "Approved job 1, it is running now",
+			expectedAuthStrategy:    auth.AuthStrategyAny,
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
+		{
+			name: "test deny command",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinDenyCommand,
+				UserID:  "userid",
+			},
+			job: meeseeks.Job{
+				Request: meeseeks.Request{Username: "admin_user", Args: []string{"1"}},
+			},
+			expected:                "Denied job 1",
+			expectedAuthStrategy:    auth.AuthStrategyAny,
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
+		{
+			name: "test confirm command",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinConfirmCommand,
+				UserID:  "userid",
+			},
+			job: meeseeks.Job{
+				Request: meeseeks.Request{UserID: "userid", Args: []string{"abc123"}},
+			},
+			expected:                "Confirmed `deploy app`",
+			expectedAuthStrategy:    auth.AuthStrategyAny,
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
+		{
+			name: "test dismiss command",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinDismissCommand,
+				UserID:  "userid",
+			},
+			job: meeseeks.Job{
+				Request: meeseeks.Request{UserID: "userid", Args: []string{"abc123"}},
+			},
+			expected:                "Dismissed `deploy app`",
+			expectedAuthStrategy:    auth.AuthStrategyAny,
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
+		{
+			name: "test locks command",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinLocksCommand,
+				UserID:  "userid",
+			},
+			job:                     meeseeks.Job{},
+			expected:                "- *deploy-prod* - held by job *42* `deploy prod` by *someone* since now, 2 waiting\n",
+			expectedAuthStrategy:    auth.AuthStrategyAny,
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
+		{
+			name: "test confirm command from another user",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinConfirmCommand,
+				UserID:  "userid",
+			},
+			job: meeseeks.Job{
+				Request: meeseeks.Request{UserID: "otherid", Args: []string{"abc123"}},
+			},
+			expectedError:           fmt.Errorf("unknown confirmation"),
+			expectedAuthStrategy:    auth.AuthStrategyAny,
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
 		{
 			name: "test tail command with jobID",
 			req: meeseeks.Request{
@@ -406,23 +601,45 @@ func Test_BuiltinCommands(t *testing.T) {
 				mocks.Must(t, "create job", err)
 
 				w := persistence.LogWriter()
-				w.Append(j.ID, "line 1.1")
-				w.Append(j.ID, "line 1.2")
-				w.Append(j.ID, "line 1.3")
-				w.Append(j.ID, "line 1.4")
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.1"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.2"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.3"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.4"})
 
 				j, err = persistence.Jobs().Create(req)
 				mocks.Must(t, "create job", err)
 
 				w = persistence.LogWriter()
-				w.Append(j.ID, "line 2.1")
-				w.Append(j.ID, "line 2.2")
-				w.Append(j.ID, "line 2.3")
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.1"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.2"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.3"})
 			},
 			expected:                "line 1.4",
 			expectedAuthStrategy:    auth.AuthStrategyAny,
 			expectedChannelStrategy: auth.ChannelStrategyAny,
 		},
+		{
+			name: "test tail command with stream",
+			req: meeseeks.Request{
+				Command: builtins.BuiltinTailCommand,
+				UserID:  "someone",
+			},
+			job: meeseeks.Job{
+				Request: meeseeks.Request{Username: "someone", Args: []string{"-stream", "stderr", "1"}},
+			},
+			setup: func() {
+				j, err := persistence.Jobs().Create(req)
+				mocks.Must(t, "create job", err)
+
+				w := persistence.LogWriter()
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.1"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "warning 1.2", Stream: meeseeks.StreamStderr})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.3"})
+			},
+			expected:                "warning 1.2",
+			expectedAuthStrategy:    auth.AuthStrategyAny,
+			expectedChannelStrategy: auth.ChannelStrategyAny,
+		},
 		{
 			name: "test tail command",
 			req: meeseeks.Request{
@@ -439,17 +656,17 @@ func Test_BuiltinCommands(t *testing.T) {
 				mocks.Must(t, "create job", err)
 
 				w := persistence.LogWriter()
-				w.Append(j.ID, "line 1.1")
-				w.Append(j.ID, "line 1.2")
-				w.Append(j.ID, "line 1.3")
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.1"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.2"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.3"})
 
 				j, err = persistence.Jobs().Create(req)
 				mocks.Must(t, "create job", err)
 
 				w = persistence.LogWriter()
-				w.Append(j.ID, "line 2.1")
-				w.Append(j.ID, "line 2.2")
-				w.Append(j.ID, "line 2.3")
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.1"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.2"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.3"})
 			},
 			expected:                "line 2.2\nline 2.3",
 			expectedAuthStrategy:    auth.AuthStrategyAny,
@@ -469,15 +686,15 @@ func Test_BuiltinCommands(t *testing.T) {
 				mocks.Must(t, "create job", err)
 
 				w := persistence.LogWriter()
-				w.Append(j.ID, "line 1.1\nline 1.2\nsomething to say 1")
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.1\nline 1.2\nsomething to say 1"})
 
 				j, err = persistence.Jobs().Create(req)
 				mocks.Must(t, "create job", err)
 
 				w = persistence.LogWriter()
-				w.Append(j.ID, "line 2.1")
-				w.Append(j.ID, "line 2.2")
-				w.Append(j.ID, "something to say 2")
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.1"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.2"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "something to say 2"})
 			},
 			expected:                "line 2.1\nline 2.2",
 			expectedAuthStrategy:    auth.AuthStrategyAny,
@@ -497,17 +714,17 @@ func Test_BuiltinCommands(t *testing.T) {
 				mocks.Must(t, "create job", err)
 
 				w := persistence.LogWriter()
-				w.Append(j.ID, "line 1.1")
-				w.Append(j.ID, "line 1.2")
-				w.Append(j.ID, "something to say 1")
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.1"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 1.2"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "something to say 1"})
 
 				j, err = persistence.Jobs().Create(req)
 				mocks.Must(t, "create job", err)
 
 				w = persistence.LogWriter()
-				w.Append(j.ID, "line 2.1")
-				w.Append(j.ID, "line 2.2")
-				w.Append(j.ID, "something to say 2")
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.1"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "line 2.2"})
+				w.Append(j.ID, meeseeks.LogLine{Line: "something to say 2"})
 			},
 			expected:                "line 1.1",
 			expectedAuthStrategy:    auth.AuthStrategyAny,
@@ -527,12 +744,12 @@ func Test_BuiltinCommands(t *testing.T) {
 				j, err := persistence.Jobs().Create(req)
 				mocks.Must(t, "create job", err)
 				w := persistence.LogWriter()
-				w.Append(j.ID, "something to say 1")
+				w.Append(j.ID, meeseeks.LogLine{Line: "something to say 1"})
 
 				j, err = persistence.Jobs().Create(req)
 				mocks.Must(t, "create job", err)
 				w = persistence.LogWriter()
-				w.Append(j.ID, "something to say 2")
+				w.Append(j.ID, meeseeks.LogLine{Line: "something to say 2"})
 			},
 			expected:                "something to say 1",
 			expectedAuthStrategy:    auth.AuthStrategyAny,
@@ -552,12 +769,12 @@ func Test_BuiltinCommands(t *testing.T) {
 				j, err := persistence.Jobs().Create(req)
 				mocks.Must(t, "create job", err)
 				w := persistence.LogWriter()
-				w.Append(j.ID, "something to say 1")
+				w.Append(j.ID, meeseeks.LogLine{Line: "something to say 1"})
 
 				j, err = persistence.Jobs().Create(req)
 				mocks.Must(t, "create job", err)
 				w = persistence.LogWriter()
-				w.Append(j.ID, "something to say 2")
+				w.Append(j.ID, meeseeks.LogLine{Line: "something to say 2"})
 			},
 			expected:                "something to say 1",
 			expectedAuthStrategy:    auth.AuthStrategyAny,
@@ -593,6 +810,7 @@ func Test_BuiltinCommands(t *testing.T) {
 					"userLink",
 					"channelLink",
 					"something",
+					"",
 				)
 				mocks.Must(t, "create token", err)
 
@@ -621,7 +839,7 @@ func Test_BuiltinCommands(t *testing.T) {
 				_, err = persistence.Jobs().Create(req)
 				mocks.Must(t, "create job", err)
 			},
-			expected:                "Issued command cancellation to job 1",
+			expected:                "Issued command kill to job 1",
 			expectedAuthStrategy:    auth.AuthStrategyAllowedGroup,
 			expectedAllowedGroups:   []string{auth.AdminGroup},
 			expectedChannelStrategy: auth.ChannelStrategyAny,
@@ -772,6 +990,15 @@ func Test_FilterJobsAudit(t *testing.T) {
 			t.Fatalf("Failed to execute audit: %s", err)
 		}
 		mocks.AssertEquals(t, "*4* - now - *command* by *someone* in *<#123>* - *Running*\n*3* - now - *command* by *someone* in *<#123>* - *Running*\n", limit)
+
+		persistence.Jobs().RequestApproval(r1)
+		pending, err := cmd.Execute(context.Background(), meeseeks.Job{
+			Request: meeseeks.Request{Args: []string{"-status", "pendingapproval"}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to execute audit: %s", err)
+		}
+		mocks.AssertEquals(t, "*6* - now - *command* by *someone* in *<#123>* - *PendingApproval*\n", pending)
 	}))
 }
 
@@ -831,3 +1058,95 @@ func TestAPITokenLifecycle(t *testing.T) {
 		mocks.AssertEquals(t, "No tokens could be found", out)
 	}))
 }
+
+func TestScheduleLifecycle(t *testing.T) {
+	exec := func(r meeseeks.Request) (string, error) {
+		cmd, ok := commands.Find(&r)
+		if !ok {
+			t.Fatalf("could not find command %s", r.Command)
+		}
+
+		return cmd.Execute(context.Background(), persistence.Jobs().Null(r))
+	}
+
+	mocks.Must(t, "failed to run the schedules lifecycle", mocks.WithTmpDB(func(_ string) {
+		out, err := exec(meeseeks.Request{
+			Command:  builtins.BuiltinScheduleCommand,
+			Username: "user_one",
+			UserID:   "userid",
+			Args:     []string{"list"},
+		})
+		mocks.Must(t, "can't list schedules:", err)
+		mocks.AssertEquals(t, "No schedule could be found", out)
+
+		out, err = exec(meeseeks.Request{
+			Command:  builtins.BuiltinScheduleCommand,
+			Username: "user_one",
+			UserID:   "userid",
+			Args:     []string{"add", "every weekday 09:00", "version"},
+		})
+		mocks.Must(t, "can't add a recurring schedule:", err)
+		mocks.AssertMatches(t, "Scheduled version as schedule 1, next run \\d{4}-\\d{2}-\\d{2} 09:00", out)
+
+		out, err = exec(meeseeks.Request{
+			Command:  builtins.BuiltinAtCommand,
+			Username: "user_one",
+			UserID:   "userid",
+			Args:     []string{"2099-01-01T18:00", "version"},
+		})
+		mocks.Must(t, "can't add a one-shot schedule:", err)
+		mocks.AssertEquals(t, "Scheduled version as schedule 2, next run 2099-01-01 18:00", out)
+
+		_, err = exec(meeseeks.Request{
+			Command:  builtins.BuiltinAtCommand,
+			Username: "user_one",
+			UserID:   "userid",
+			Args:     []string{"18:00", "kill", "1"},
+		})
+		mocks.AssertEquals(t, "you are not allowed to run kill here", err.Error())
+
+		_, err = exec(meeseeks.Request{
+			Command:  builtins.BuiltinScheduleCommand,
+			Username: "user_one",
+			UserID:   "userid",
+			Args:     []string{"add", "every day", "nonexisting"},
+		})
+		mocks.AssertEquals(t, "unknown command nonexisting", err.Error())
+
+		out, err = exec(meeseeks.Request{
+			Command:  builtins.BuiltinScheduleCommand,
+			Username: "user_one",
+			UserID:   "userid",
+			Args:     []string{"list"},
+		})
+		mocks.Must(t, "can't list schedules:", err)
+		mocks.AssertMatches(t, "- \\*1\\* - `version` every weekday 09:00, next run .*\n"+
+			"- \\*2\\* - `version` at 2099-01-01T18:00, next run 2099-01-01 18:00\n", out)
+
+		_, err = exec(meeseeks.Request{
+			Command:  builtins.BuiltinScheduleCommand,
+			Username: "user_two",
+			UserID:   "otherid",
+			Args:     []string{"rm", "1"},
+		})
+		mocks.AssertEquals(t, meeseeks.ErrNoScheduleWithID, err)
+
+		out, err = exec(meeseeks.Request{
+			Command:  builtins.BuiltinScheduleCommand,
+			Username: "user_one",
+			UserID:   "userid",
+			Args:     []string{"rm", "1"},
+		})
+		mocks.Must(t, "can't remove a schedule:", err)
+		mocks.AssertEquals(t, "Removed schedule 1", out)
+
+		out, err = exec(meeseeks.Request{
+			Command:  builtins.BuiltinScheduleCommand,
+			Username: "user_one",
+			UserID:   "userid",
+			Args:     []string{"list"},
+		})
+		mocks.Must(t, "can't list schedules:", err)
+		mocks.AssertEquals(t, "- *2* - `version` at 2099-01-01T18:00, next run 2099-01-01 18:00\n", out)
+	}))
+}
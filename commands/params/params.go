@@ -0,0 +1,227 @@
+package params
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+)
+
+// Check validates the declaration of a list of params
+func Check(params []meeseeks.Param) error {
+	seen := make(map[string]bool)
+	optional := ""
+	for _, p := range params {
+		if p.Name == "" || strings.HasPrefix(p.Name, "-") || strings.ContainsAny(p.Name, " =") {
+			return fmt.Errorf("invalid param name %q", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("param %s is declared twice", p.Name)
+		}
+		seen[p.Name] = true
+
+		switch p.Type {
+		case "", meeseeks.ParamTypeString, meeseeks.ParamTypeInt, meeseeks.ParamTypeBool, meeseeks.ParamTypeDuration:
+		case meeseeks.ParamTypeEnum:
+			if len(p.Values) == 0 {
+				return fmt.Errorf("enum param %s has no values", p.Name)
+			}
+		default:
+			return fmt.Errorf("param %s has an unknown type %s", p.Name, p.Type)
+		}
+		if p.Regex != "" {
+			if _, err := regexp.Compile(p.Regex); err != nil {
+				return fmt.Errorf("param %s has an invalid regex: %s", p.Name, err)
+			}
+		}
+		if p.Default != "" {
+			if p.Required {
+				return fmt.Errorf("param %s is required so it can't have a default", p.Name)
+			}
+			if err := validate(p, p.Default); err != nil {
+				return fmt.Errorf("param %s has an invalid default: %s", p.Name, err)
+			}
+		}
+
+		if p.Flag {
+			continue
+		}
+		if p.Type == meeseeks.ParamTypeBool {
+			return fmt.Errorf("bool param %s has to be a flag", p.Name)
+		}
+		if p.Required && optional != "" {
+			return fmt.Errorf("required param %s can't follow the optional param %s", p.Name, optional)
+		}
+		if !p.Required {
+			optional = p.Name
+		}
+	}
+	return nil
+}
+
+// Parse validates the arguments of a request against the params, returning
+// them with the defaults filled in, the positional arguments first and the
+// flags after them in the order they are declared
+func Parse(params []meeseeks.Param, args []string) ([]string, error) {
+	values := make(map[string]string)
+	positional := make([]meeseeks.Param, 0)
+	flags := make(map[string]meeseeks.Param)
+	for _, p := range params {
+		if p.Flag {
+			flags[p.Name] = p
+		} else {
+			positional = append(positional, p)
+		}
+	}
+
+	next := 0
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			if next >= len(positional) {
+				return nil, fmt.Errorf("unexpected argument %s", arg)
+			}
+			if err := validate(positional[next], arg); err != nil {
+				return nil, err
+			}
+			values[positional[next].Name] = arg
+			next++
+			continue
+		}
+
+		name, value := strings.TrimPrefix(arg, "--"), ""
+		hasValue := false
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name, value, hasValue = name[:idx], name[idx+1:], true
+		}
+		p, ok := flags[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown flag --%s", name)
+		}
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("flag --%s is passed more than once", name)
+		}
+		if !hasValue {
+			if p.Type == meeseeks.ParamTypeBool {
+				value = "true"
+			} else {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("flag --%s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+		}
+		if err := validate(p, value); err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+
+	parsed := make([]string, 0, len(args))
+	for _, p := range positional {
+		value, ok := valueOf(p, values)
+		if !ok {
+			if p.Required {
+				return nil, fmt.Errorf("missing required argument %s", p.Name)
+			}
+			break
+		}
+		parsed = append(parsed, value)
+	}
+	for _, p := range params {
+		if !p.Flag {
+			continue
+		}
+		value, ok := valueOf(p, values)
+		switch {
+		case !ok && p.Required:
+			return nil, fmt.Errorf("missing required flag --%s", p.Name)
+		case !ok:
+		case p.Type == meeseeks.ParamTypeBool:
+			if b, _ := strconv.ParseBool(value); b {
+				parsed = append(parsed, "--"+p.Name)
+			}
+		default:
+			parsed = append(parsed, "--"+p.Name, value)
+		}
+	}
+	return parsed, nil
+}
+
+// Help returns a line describing each param
+func Help(params []meeseeks.Param) []string {
+	lines := make([]string, 0, len(params))
+	for _, p := range params {
+		name := p.Name
+		if p.Flag {
+			name = "--" + p.Name
+		}
+
+		kind := p.Type
+		switch p.Type {
+		case "":
+			kind = meeseeks.ParamTypeString
+		case meeseeks.ParamTypeEnum:
+			kind = "one of " + strings.Join(p.Values, ", ")
+		}
+		if p.Regex != "" {
+			kind += " matching " + p.Regex
+		}
+
+		line := fmt.Sprintf("%s (%s)", name, kind)
+		if p.Required {
+			line += ", required"
+		} else if p.Default != "" {
+			line += ", defaults to " + p.Default
+		}
+		if p.Description != "" {
+			line += ": " + p.Description
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func valueOf(p meeseeks.Param, values map[string]string) (string, bool) {
+	if value, ok := values[p.Name]; ok {
+		return value, true
+	}
+	return p.Default, p.Default != ""
+}
+
+func validate(p meeseeks.Param, value string) error {
+	switch p.Type {
+	case meeseeks.ParamTypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("invalid value %s for %s: expected an int", value, p.Name)
+		}
+	case meeseeks.ParamTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value %s for %s: expected true or false", value, p.Name)
+		}
+	case meeseeks.ParamTypeDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("invalid value %s for %s: expected a duration like 90s or 5m", value, p.Name)
+		}
+	case meeseeks.ParamTypeEnum:
+		found := false
+		for _, v := range p.Values {
+			found = found || v == value
+		}
+		if !found {
+			return fmt.Errorf("invalid value %s for %s: expected one of %s", value, p.Name, strings.Join(p.Values, ", "))
+		}
+	}
+
+	if p.Regex != "" {
+		// Anchored so the whole value has to match
+		if ok, _ := regexp.MatchString("^(?:"+p.Regex+")$", value); !ok {
+			return fmt.Errorf("invalid value %s for %s: expected it to match %s", value, p.Name, p.Regex)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package params_test
+
+import (
+	"testing"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/commands/params"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+)
+
+var deployParams = []meeseeks.Param{
+	{Name: "env", Type: meeseeks.ParamTypeEnum, Values: []string{"staging", "prod"}, Required: true, Description: "where to deploy"},
+	{Name: "version", Regex: `v\d+\.\d+`, Default: "v1.0"},
+	{Name: "replicas", Type: meeseeks.ParamTypeInt, Flag: true, Default: "2"},
+	{Name: "timeout", Type: meeseeks.ParamTypeDuration, Flag: true},
+	{Name: "force", Type: meeseeks.ParamTypeBool, Flag: true},
+}
+
+func TestParsingArguments(t *testing.T) {
+	tt := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{name: "defaults", args: []string{"prod"}, expected: []string{"prod", "v1.0", "--replicas", "2"}},
+		{name: "all positional", args: []string{"staging", "v2.3"}, expected: []string{"staging", "v2.3", "--replicas", "2"}},
+		{
+			name:     "flags in any order",
+			args:     []string{"--force", "prod", "--timeout=5m", "--replicas", "3"},
+			expected: []string{"prod", "v1.0", "--replicas", "3", "--timeout", "5m", "--force"},
+		},
+		{name: "false bool flag", args: []string{"prod", "--force=false"}, expected: []string{"prod", "v1.0", "--replicas", "2"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			args, err := params.Parse(deployParams, tc.args)
+			mocks.Must(t, "could not parse arguments", err)
+			mocks.AssertEquals(t, tc.expected, args)
+		})
+	}
+}
+
+func TestInvalidArguments(t *testing.T) {
+	tt := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "missing required", args: []string{}, expected: "missing required argument env"},
+		{name: "not in enum", args: []string{"dev"}, expected: "invalid value dev for env: expected one of staging, prod"},
+		{name: "not matching", args: []string{"prod", "v1.0; rm -rf /"}, expected: `invalid value v1.0; rm -rf / for version: expected it to match v\d+\.\d+`},
+		{name: "not an int", args: []string{"prod", "--replicas", "many"}, expected: "invalid value many for replicas: expected an int"},
+		{name: "not a duration", args: []string{"prod", "--timeout=soon"}, expected: "invalid value soon for timeout: expected a duration like 90s or 5m"},
+		{name: "not a bool", args: []string{"prod", "--force=maybe"}, expected: "invalid value maybe for force: expected true or false"},
+		{name: "unknown flag", args: []string{"prod", "--dry-run"}, expected: "unknown flag --dry-run"},
+		{name: "flag without value", args: []string{"prod", "--replicas"}, expected: "flag --replicas requires a value"},
+		{name: "repeated flag", args: []string{"prod", "--force", "--force"}, expected: "flag --force is passed more than once"},
+		{name: "too many", args: []string{"prod", "v1.0", "extra"}, expected: "unexpected argument extra"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := params.Parse(deployParams, tc.args)
+			if err == nil {
+				t.Fatalf("arguments %v should be invalid", tc.args)
+			}
+			mocks.AssertEquals(t, tc.expected, err.Error())
+		})
+	}
+}
+
+func TestCheckingParams(t *testing.T) {
+	mocks.Must(t, "valid params", params.Check(deployParams))
+
+	tt := []struct {
+		name     string
+		params   []meeseeks.Param
+		expected string
+	}{
+		{name: "no name", params: []meeseeks.Param{{}}, expected: `invalid param name ""`},
+		{name: "twice", params: []meeseeks.Param{{Name: "a"}, {Name: "a"}}, expected: "param a is declared twice"},
+		{name: "unknown type", params: []meeseeks.Param{{Name: "a", Type: "float"}}, expected: "param a has an unknown type float"},
+		{name: "empty enum", params: []meeseeks.Param{{Name: "a", Type: meeseeks.ParamTypeEnum}}, expected: "enum param a has no values"},
+		{name: "bad regex", params: []meeseeks.Param{{Name: "a", Regex: "("}}, expected: "param a has an invalid regex: error parsing regexp: missing closing ): `(`"},
+		{name: "bad default", params: []meeseeks.Param{{Name: "a", Type: meeseeks.ParamTypeInt, Default: "x"}}, expected: "param a has an invalid default: invalid value x for a: expected an int"},
+		{name: "positional bool", params: []meeseeks.Param{{Name: "a", Type: meeseeks.ParamTypeBool}}, expected: "bool param a has to be a flag"},
+		{
+			name:     "required after optional",
+			params:   []meeseeks.Param{{Name: "a"}, {Name: "b", Required: true}},
+			expected: "required param b can't follow the optional param a",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mocks.AssertEquals(t, tc.expected, params.Check(tc.params).Error())
+		})
+	}
+}
+
+func TestHelpIsGeneratedFromParams(t *testing.T) {
+	mocks.AssertEquals(t, []string{
+		"env (one of staging, prod), required: where to deploy",
+		`version (string matching v\d+\.\d+), defaults to v1.0`,
+		"--replicas (int), defaults to 2",
+		"--timeout (duration)",
+		"--force (bool)",
+	}, params.Help(deployParams))
+}
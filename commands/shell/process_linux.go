@@ -0,0 +1,167 @@
+// +build linux
+
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rlimitsEnv tells a copy of the meeseeks to apply the resource limits and
+// exec the command, as there is no way of running code between fork and exec
+const rlimitsEnv = "MEESEEKS_SHELL_RLIMITS"
+
+// rlimitNproc is not in the syscall package, it's the same in all the
+// architectures meeseeks is built for
+const rlimitNproc = 0x6
+
+var rlimitResources = map[string]int{
+	"cpu":    syscall.RLIMIT_CPU,
+	"as":     syscall.RLIMIT_AS,
+	"nofile": syscall.RLIMIT_NOFILE,
+	"nproc":  rlimitNproc,
+}
+
+func init() {
+	if limits, ok := os.LookupEnv(rlimitsEnv); ok {
+		execWithLimits(limits)
+	}
+}
+
+// configureProcess runs the command in its own process group, as the
+// configured user and group, and with the resource limits applied
+func (c shellCommand) configureProcess(cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	credential, err := lookupCredential(c.GetUser(), c.GetGroup())
+	if err != nil {
+		return err
+	}
+	cmd.SysProcAttr.Credential = credential
+
+	limits := c.GetLimits()
+	if limits.IsZero() {
+		return nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not find the meeseeks executable to apply the resource limits: %s", err)
+	}
+
+	values := make([]string, 0)
+	for name, value := range map[string]uint64{
+		"cpu":    limits.CPU,
+		"as":     limits.AddressSpace,
+		"nofile": limits.OpenFiles,
+		"nproc":  limits.Processes,
+	} {
+		if value > 0 {
+			values = append(values, fmt.Sprintf("%s=%d", name, value))
+		}
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", rlimitsEnv, strings.Join(values, ",")))
+	cmd.Args = append([]string{self, cmd.Path}, cmd.Args...)
+	cmd.Path = self
+	return nil
+}
+
+func lookupCredential(username, groupname string) (*syscall.Credential, error) {
+	if username == "" && groupname == "" {
+		return nil, nil
+	}
+
+	credential := &syscall.Credential{
+		Uid: uint32(os.Getuid()),
+		Gid: uint32(os.Getgid()),
+	}
+	if username != "" {
+		u, err := user.Lookup(username)
+		if err != nil {
+			return nil, fmt.Errorf("could not find user %s: %s", username, err)
+		}
+		uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+		credential.Uid, credential.Gid = uint32(uid), uint32(gid)
+	}
+	if groupname != "" {
+		g, err := user.LookupGroup(groupname)
+		if err != nil {
+			return nil, fmt.Errorf("could not find group %s: %s", groupname, err)
+		}
+		gid, _ := strconv.ParseUint(g.Gid, 10, 32)
+		credential.Gid = uint32(gid)
+	}
+	return credential, nil
+}
+
+// execWithLimits applies the limits and replaces this process with the
+// command, it never returns
+func execWithLimits(limits string) {
+	os.Unsetenv(rlimitsEnv)
+
+	if err := setLimits(limits); err != nil {
+		fmt.Fprintf(os.Stderr, "could not apply the resource limits: %s\n", err)
+		os.Exit(126)
+	}
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "no command to run with the resource limits")
+		os.Exit(127)
+	}
+	err := syscall.Exec(os.Args[1], os.Args[2:], os.Environ())
+	fmt.Fprintf(os.Stderr, "could not run %s: %s\n", os.Args[1], err)
+	os.Exit(127)
+}
+
+func setLimits(limits string) error {
+	for _, limit := range strings.Split(limits, ",") {
+		parts := strings.SplitN(limit, "=", 2)
+		resource, ok := rlimitResources[parts[0]]
+		if !ok || len(parts) != 2 {
+			return fmt.Errorf("invalid limit %s", limit)
+		}
+		value, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid limit %s: %s", limit, err)
+		}
+
+		var rlimit syscall.Rlimit
+		if err := syscall.Getrlimit(resource, &rlimit); err != nil {
+			return fmt.Errorf("could not get limit %s: %s", parts[0], err)
+		}
+		// The hard limit is lowered too so the command can't raise it back
+		if value < rlimit.Max {
+			rlimit.Max = value
+		}
+		rlimit.Cur = rlimit.Max
+		if err := syscall.Setrlimit(resource, &rlimit); err != nil {
+			return fmt.Errorf("could not set limit %s: %s", limit, err)
+		}
+	}
+	return nil
+}
+
+// killProcessGroup kills the command along with all the processes it started
+func killProcessGroup(cmd *exec.Cmd) {
+	signalProcessGroup(cmd, syscall.SIGKILL)
+}
+
+// terminateProcessGroup asks the command and all the processes it started to finish
+func terminateProcessGroup(cmd *exec.Cmd) {
+	signalProcessGroup(cmd, syscall.SIGTERM)
+}
+
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, sig); err != nil && err != syscall.ESRCH {
+		logrus.Errorf("could not send %s to process group %d: %s", sig, cmd.Process.Pid, err)
+	}
+}
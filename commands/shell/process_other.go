@@ -0,0 +1,33 @@
+// +build !linux
+
+package shell
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// configureProcess fails when the command has to run as another user or with
+// resource limits, as they are only supported on linux
+func (c shellCommand) configureProcess(_ *exec.Cmd) error {
+	if c.GetUser() != "" || c.GetGroup() != "" || !c.GetLimits().IsZero() {
+		return fmt.Errorf("running commands as another user or with resource limits is only supported on linux")
+	}
+	return nil
+}
+
+// killProcessGroup kills the command, the processes it started may outlive it
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+}
+
+// terminateProcessGroup asks the command to finish, the processes it started
+// may outlive it
+func terminateProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		cmd.Process.Signal(syscall.SIGTERM)
+	}
+}
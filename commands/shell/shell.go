@@ -6,7 +6,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
@@ -26,23 +33,34 @@ type shellCommand struct {
 
 // Execute implements Command.Execute for the ShellCommand
 func (c shellCommand) Execute(ctx context.Context, job meeseeks.Job) (string, error) {
+	out, _, err := c.ExecuteWithResult(ctx, job)
+	return out, err
+}
+
+// ExecuteWithResult implements ReportingCommand.ExecuteWithResult for the ShellCommand
+func (c shellCommand) ExecuteWithResult(ctx context.Context, job meeseeks.Job) (string, meeseeks.JobResult, error) {
 	cmdArgs := append(c.GetArgs(), job.Request.Args...)
 	logrus.Debugf("Calling command %s with args %#v", c.GetCmd(), cmdArgs)
 
 	ctx, cancelFunc := context.WithTimeout(ctx, c.GetTimeout())
 	defer cancelFunc()
 
+	result := meeseeks.JobResult{
+		ExitCode: -1,
+		Hostname: hostname(),
+	}
+
 	outputBuffer := bytes.NewBufferString("")
 
 	logW := persistence.LogWriter()
 
-	AppendLogs := func(line string) {
+	AppendLogs := func(line meeseeks.LogLine) {
 
-		outputBuffer.WriteString(line)
+		outputBuffer.WriteString(line.Line)
 		outputBuffer.WriteString("\n")
 
 		if e := logW.Append(job.ID, line); e != nil {
-			logrus.Errorf("Could not append '%s' to job %d logs: %s", line, job.ID, e)
+			logrus.Errorf("Could not append '%s' to job %d logs: %s", line.Line, job.ID, e)
 		}
 
 	}
@@ -53,48 +71,172 @@ func (c shellCommand) Execute(ctx context.Context, job meeseeks.Job) (string, er
 		return err
 	}
 
-	cmd := exec.CommandContext(ctx, c.GetCmd(), cmdArgs...)
+	// The context is handled below instead of letting exec kill the process
+	// so cancelled commands get a chance to finish
+	cmd := exec.Command(c.GetCmd(), cmdArgs...)
+	cmd.Env = c.environment(job)
+	cmd.Dir = c.GetWorkDir()
+	if err := c.configureProcess(cmd); err != nil {
+		return "", result, SetError(fmt.Errorf("could not configure the command process: %s", err))
+	}
 	op, err := cmd.StdoutPipe()
 	if err != nil {
-		return "", SetError(fmt.Errorf("could not create stdout pipe: %s", err))
+		return "", result, SetError(fmt.Errorf("could not create stdout pipe: %s", err))
 	}
 	ep, err := cmd.StderrPipe()
 	if err != nil {
-		return "", SetError(fmt.Errorf("could not create stderr pipe: %s", err))
+		return "", result, SetError(fmt.Errorf("could not create stderr pipe: %s", err))
 	}
 
-	tr := io.MultiReader(op, ep)
+	// Both streams are read at the same time so the lines are logged in the
+	// order they are written and a full stderr never blocks the command
+	lines := make(chan meeseeks.LogLine)
+	readers := sync.WaitGroup{}
+	truncated := int32(0)
+	readStream := func(stream string, r io.Reader) {
+		defer readers.Done()
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			lines <- meeseeks.LogLine{Line: s.Text(), Stream: stream, Time: time.Now()}
+		}
+		if s.Err() == bufio.ErrTooLong {
+			// Keep the command from blocking on a stream nobody reads
+			atomic.StoreInt32(&truncated, 1)
+			io.Copy(ioutil.Discard, r)
+		}
+	}
+	readers.Add(2)
+	go readStream(meeseeks.StreamStdout, op)
+	go readStream(meeseeks.StreamStderr, ep)
+	go func() {
+		readers.Wait()
+		close(lines)
+	}()
 
 	done := make(chan struct{})
 
 	go func() {
-		s := bufio.NewScanner(tr)
-		for s.Scan() {
-			AppendLogs(s.Text())
+		for line := range lines {
+			AppendLogs(line)
 		}
-		done <- struct{}{}
+		close(done)
 	}()
 
 	err = cmd.Start()
 	if err != nil {
 		logrus.Errorf("command failed to start: %s", err)
-		return "", SetError(err)
+		return "", result, SetError(err)
 	}
 
 	// Wait for the command to be done or the context to be cancelled
 	select {
 	case <-ctx.Done():
-		// We are finishing because the context was called
+		// We are finishing because the context was called, take down any
+		// process the command started along with it
 		err = ctx.Err()
+		c.stop(ctx, cmd, done)
+		// Waiting closes the pipes, which stops the readers
+		cmd.Wait()
+		<-done
 	case <-done:
 		// We are finishing because we are actually done
 		err = cmd.Wait()
 	}
 
+	result.OutputBytes = uint64(outputBuffer.Len())
+	result.Truncated = atomic.LoadInt32(&truncated) == 1
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+		result.Signal = exitSignal(cmd.ProcessState)
+	}
+	if _, exited := err.(*exec.ExitError); exited || err == nil {
+		if c.isExpectedExitCode(result.ExitCode) {
+			err = nil
+		} else if err == nil {
+			err = fmt.Errorf("exit status %d", result.ExitCode)
+		}
+	}
+
 	if err != nil {
 		logrus.Errorf("command failed: %s", err)
-		return "", SetError(err)
+		return "", result, SetError(err)
+	}
+
+	return outputBuffer.String(), result, err
+}
+
+// isExpectedExitCode returns whether the exit code finishes the job as a
+// success or with warnings instead of failing it
+func (c shellCommand) isExpectedExitCode(code int) bool {
+	for _, expected := range append(c.GetSuccessExitCodes(), c.GetWarningExitCodes()...) {
+		if code == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// stop kills the command along with any process it started. When the job was
+// cancelled the command is first asked to finish, and killed once it does or
+// the grace period is over
+func (c shellCommand) stop(ctx context.Context, cmd *exec.Cmd, done <-chan struct{}) {
+	if stop, ok := meeseeks.StopOf(ctx); ok && stop.Kind == meeseeks.StopCancel {
+		terminateProcessGroup(cmd)
+		select {
+		case <-done:
+		case <-time.After(c.GetGracePeriod()):
+			logrus.Infof("command %s did not finish in %s after being cancelled, killing it", c.GetCmd(), c.GetGracePeriod())
+		}
+	}
+	killProcessGroup(cmd)
+}
+
+// exitSignal returns the name of the signal that killed the process, if any
+func exitSignal(state *os.ProcessState) string {
+	status, ok := state.Sys().(interface {
+		Signaled() bool
+		Signal() syscall.Signal
+	})
+	if !ok || !status.Signaled() {
+		return ""
+	}
+	return status.Signal().String()
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		logrus.Errorf("could not get the hostname: %s", err)
+	}
+	return name
+}
+
+// environment returns the environment of the process, the job variables go
+// last so they can't be overridden
+func (c shellCommand) environment(job meeseeks.Job) []string {
+	env := make([]string, 0)
+	if c.InheritsEnv() {
+		env = append(env, os.Environ()...)
+	}
+
+	names := make([]string, 0, len(c.GetEnv()))
+	for name := range c.GetEnv() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, fmt.Sprintf("%s=%s", name, c.GetEnv()[name]))
 	}
 
-	return outputBuffer.String(), err
+	req := job.Request
+	return append(env,
+		fmt.Sprintf("MEESEEKS_JOB_ID=%d", job.ID),
+		fmt.Sprintf("MEESEEKS_COMMAND=%s", req.Command),
+		fmt.Sprintf("MEESEEKS_USER=%s", req.Username),
+		fmt.Sprintf("MEESEEKS_USER_ID=%s", req.UserID),
+		fmt.Sprintf("MEESEEKS_CHANNEL=%s", req.Channel),
+		fmt.Sprintf("MEESEEKS_CHANNEL_ID=%s", req.ChannelID),
+		fmt.Sprintf("MEESEEKS_IS_IM=%t", req.IsIM),
+		fmt.Sprintf("MEESEEKS_ORIGIN=%s", req.Origin),
+	)
 }
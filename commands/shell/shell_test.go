@@ -2,12 +2,19 @@ package shell_test
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/commands/shell"
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
 )
 
 var echoCommand = shell.New(meeseeks.CommandOpts{
@@ -73,3 +80,231 @@ func TestSleepingCanBeWokenUp(t *testing.T) {
 		mocks.AssertEquals(t, "context canceled", err.Error())
 	})
 }
+
+func TestJobContextIsInTheEnvironment(t *testing.T) {
+	mocks.WithTmpDB(func(_ string) {
+		envCommand := shell.New(meeseeks.CommandOpts{
+			Cmd:      "env",
+			Env:      map[string]string{"DEPLOY_TARGET": "prod", "MEESEEKS_USER": "impostor"},
+			CleanEnv: true,
+		})
+		out, err := envCommand.Execute(context.Background(), meeseeks.Job{
+			ID: 4,
+			Request: meeseeks.Request{
+				Command:   "env",
+				Username:  "someone",
+				UserID:    "U1",
+				Channel:   "general",
+				ChannelID: "C1",
+				Origin:    "slack",
+			},
+		})
+		mocks.Must(t, "failed to execute env command", err)
+
+		env := strings.Split(strings.TrimSpace(out), "\n")
+		sort.Strings(env)
+		mocks.AssertEquals(t, []string{
+			"DEPLOY_TARGET=prod",
+			"MEESEEKS_CHANNEL=general",
+			"MEESEEKS_CHANNEL_ID=C1",
+			"MEESEEKS_COMMAND=env",
+			"MEESEEKS_IS_IM=false",
+			"MEESEEKS_JOB_ID=4",
+			"MEESEEKS_ORIGIN=slack",
+			"MEESEEKS_USER=someone",
+			"MEESEEKS_USER_ID=U1",
+		}, env)
+	})
+}
+
+func TestEnvironmentIsInheritedByDefault(t *testing.T) {
+	mocks.WithTmpDB(func(_ string) {
+		os.Setenv("MEESEEKS_TEST_INHERITED", "yes")
+		defer os.Unsetenv("MEESEEKS_TEST_INHERITED")
+
+		out, err := shell.New(meeseeks.CommandOpts{Cmd: "env"}).Execute(context.Background(), meeseeks.Job{ID: 5})
+		mocks.Must(t, "failed to execute env command", err)
+		mocks.AssertEquals(t, true, strings.Contains(out, "MEESEEKS_TEST_INHERITED=yes\n"))
+		mocks.AssertEquals(t, true, strings.Contains(out, "MEESEEKS_JOB_ID=5\n"))
+	})
+}
+
+func TestCommandsRunInTheirWorkDir(t *testing.T) {
+	mocks.WithTmpDB(func(_ string) {
+		dir, err := ioutil.TempDir("", "meeseeks-workdir")
+		mocks.Must(t, "could not create workdir", err)
+		defer os.RemoveAll(dir)
+		dir, err = filepath.EvalSymlinks(dir)
+		mocks.Must(t, "could not resolve workdir", err)
+
+		out, err := shell.New(meeseeks.CommandOpts{Cmd: "pwd", WorkDir: dir}).Execute(context.Background(), meeseeks.Job{ID: 6})
+		mocks.Must(t, "failed to execute pwd command", err)
+		mocks.AssertEquals(t, dir+"\n", out)
+	})
+}
+
+func TestResourceLimitsAreApplied(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on linux")
+	}
+	mocks.WithTmpDB(func(_ string) {
+		ulimitCommand := shell.New(meeseeks.CommandOpts{
+			Cmd:    "sh",
+			Args:   []string{"-c", "ulimit -n"},
+			Limits: meeseeks.ResourceLimits{OpenFiles: 64},
+		})
+		out, err := ulimitCommand.Execute(context.Background(), meeseeks.Job{ID: 7})
+		mocks.Must(t, "failed to execute ulimit command", err)
+		mocks.AssertEquals(t, "64\n", out)
+	})
+}
+
+func TestCancellingKillsTheWholeProcessGroup(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process groups are only killed on linux")
+	}
+	mocks.WithTmpDB(func(_ string) {
+		pidFile, err := ioutil.TempFile("", "meeseeks-pid")
+		mocks.Must(t, "could not create pid file", err)
+		pidFile.Close()
+		defer os.Remove(pidFile.Name())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-time.After(100 * time.Millisecond)
+			cancel()
+		}()
+		_, err = shell.New(meeseeks.CommandOpts{
+			Cmd:  "sh",
+			Args: []string{"-c", "sleep 10 & echo $! > " + pidFile.Name() + "; wait"},
+		}).Execute(ctx, meeseeks.Job{ID: 8})
+		mocks.AssertEquals(t, "context canceled", err.Error())
+
+		pid, err := ioutil.ReadFile(pidFile.Name())
+		mocks.Must(t, "could not read the child pid", err)
+		for i := 0; i < 50; i++ {
+			// The killed child is either gone or a zombie waiting to be reaped
+			stat, err := ioutil.ReadFile("/proc/" + strings.TrimSpace(string(pid)) + "/stat")
+			if err != nil || strings.Contains(string(stat), ") Z ") {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("child process %s is still running", strings.TrimSpace(string(pid)))
+	})
+}
+
+func TestStoppingHonorsTheGracePeriodOfCancelledJobs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process groups are only signalled on linux")
+	}
+	host, err := os.Hostname()
+	mocks.Must(t, "could not get hostname", err)
+
+	tt := []struct {
+		name     string
+		stop     string
+		script   string
+		logs     string
+		expected meeseeks.JobResult
+	}{
+		{
+			name:     "cancelled",
+			stop:     meeseeks.StopCancel,
+			script:   "trap 'echo cleaning up; exit 3' TERM; sleep 10 & wait",
+			logs:     "cleaning up",
+			expected: meeseeks.JobResult{ExitCode: 3, Hostname: host, OutputBytes: 12},
+		},
+		{
+			name:     "cancelled over the grace period",
+			stop:     meeseeks.StopCancel,
+			script:   "trap '' TERM; sleep 10 & wait",
+			expected: meeseeks.JobResult{ExitCode: -1, Signal: "killed", Hostname: host},
+		},
+		{
+			name:     "killed",
+			stop:     meeseeks.StopKill,
+			script:   "trap 'echo cleaning up; exit 3' TERM; sleep 10 & wait",
+			expected: meeseeks.JobResult{ExitCode: -1, Signal: "killed", Hostname: host},
+		},
+	}
+	mocks.WithTmpDB(func(_ string) {
+		for i, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				jobID := uint64(20 + i)
+				ctx, stop := meeseeks.WithStop(context.Background())
+				go func() {
+					<-time.After(100 * time.Millisecond)
+					stop(meeseeks.Stop{Kind: tc.stop, Username: "someone"})
+				}()
+
+				cmd := shell.New(meeseeks.CommandOpts{
+					Cmd:         "sh",
+					Args:        []string{"-c", tc.script},
+					GracePeriod: 200 * time.Millisecond,
+				})
+				_, result, err := cmd.(meeseeks.ReportingCommand).ExecuteWithResult(ctx, meeseeks.Job{ID: jobID})
+				mocks.AssertEquals(t, "context canceled", err.Error())
+				mocks.AssertEquals(t, tc.expected, result)
+
+				logs, err := persistence.LogReader().Get(jobID, meeseeks.StreamStdout)
+				mocks.Must(t, "could not read stdout", err)
+				mocks.AssertEquals(t, tc.logs, logs.Output)
+			})
+		}
+	})
+}
+
+func TestStdoutAndStderrAreLoggedApart(t *testing.T) {
+	mocks.WithTmpDB(func(_ string) {
+		out, err := shell.New(meeseeks.CommandOpts{
+			Cmd:  "sh",
+			Args: []string{"-c", "echo out; echo err >&2"},
+		}).Execute(context.Background(), meeseeks.Job{ID: 9})
+		mocks.Must(t, "failed to execute sh command", err)
+		mocks.AssertEquals(t, true, strings.Contains(out, "out\n"))
+		mocks.AssertEquals(t, true, strings.Contains(out, "err\n"))
+
+		stdout, err := persistence.LogReader().Get(9, meeseeks.StreamStdout)
+		mocks.Must(t, "could not read stdout", err)
+		mocks.AssertEquals(t, "out", stdout.Output)
+
+		stderr, err := persistence.LogReader().Get(9, meeseeks.StreamStderr)
+		mocks.Must(t, "could not read stderr", err)
+		mocks.AssertEquals(t, "err", stderr.Output)
+	})
+}
+
+func TestExecutionResultsAreReported(t *testing.T) {
+	host, err := os.Hostname()
+	mocks.Must(t, "could not get hostname", err)
+
+	tt := []struct {
+		name     string
+		script   string
+		expected meeseeks.JobResult
+	}{
+		{
+			name:     "exit code",
+			script:   "echo hello; exit 3",
+			expected: meeseeks.JobResult{ExitCode: 3, Hostname: host, OutputBytes: 6},
+		},
+		{
+			name:     "signal",
+			script:   "kill -TERM $$",
+			expected: meeseeks.JobResult{ExitCode: -1, Signal: "terminated", Hostname: host},
+		},
+	}
+	mocks.WithTmpDB(func(_ string) {
+		for i, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				cmd := shell.New(meeseeks.CommandOpts{Cmd: "sh", Args: []string{"-c", tc.script}})
+				_, result, err := cmd.(meeseeks.ReportingCommand).ExecuteWithResult(context.Background(), meeseeks.Job{ID: uint64(10 + i)})
+				if err == nil {
+					t.Fatal("command should have failed")
+				}
+				mocks.AssertEquals(t, tc.expected, result)
+			})
+		}
+	})
+}
@@ -8,11 +8,14 @@ import (
 	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/commands"
+	"gitlab.com/yakshaving.art/meeseeks-box/commands/params"
 	"gitlab.com/yakshaving.art/meeseeks-box/commands/shell"
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/auth"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence/db"
+	"gitlab.com/yakshaving.art/meeseeks-box/ratelimit"
+	"gitlab.com/yakshaving.art/meeseeks-box/scheduler"
 	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
 
 	yaml "gopkg.in/yaml.v2"
@@ -34,6 +37,31 @@ func ReadFile(filename string) (Config, error) {
 
 // LoadConfiguration loads the configuration in all the dependent subsystems
 func LoadConfiguration(cnf Config) error {
+	// Validate first so an invalid configuration does not leave a half loaded one behind
+	if err := scheduler.Validate(cnf.Schedules); err != nil {
+		return fmt.Errorf("could not load schedules: %s", err)
+	}
+	for name, cmd := range cnf.Commands {
+		switch cmd.LockPolicy {
+		case "", meeseeks.LockPolicyQueue, meeseeks.LockPolicyReject:
+		default:
+			return fmt.Errorf("could not load commands: invalid lock_policy %s for command %s, it should be %s or %s",
+				cmd.LockPolicy, name, meeseeks.LockPolicyQueue, meeseeks.LockPolicyReject)
+		}
+		if err := params.Check(cmd.params()); err != nil {
+			return fmt.Errorf("could not load commands: invalid params for command %s: %s", name, err)
+		}
+		successCodes := meeseeks.CommandOpts{SuccessExitCodes: cmd.ExitCodes.Success}.GetSuccessExitCodes()
+		for _, code := range cmd.ExitCodes.Warning {
+			for _, success := range successCodes {
+				if code == success {
+					return fmt.Errorf("could not load commands: exit code %d of command %s is both a success and a warning",
+						code, name)
+				}
+			}
+		}
+	}
+
 	if err := db.Configure(cnf.Database); err != nil {
 		return fmt.Errorf("could not configure database: %s", err)
 	}
@@ -49,11 +77,33 @@ func LoadConfiguration(cnf Config) error {
 				AllowedChannels: cmd.AllowedChannels,
 				Args:            cmd.Args,
 				Handshake:       !cmd.NoHandshake,
+				Confirm:         cmd.Confirm,
 				Cmd:             cmd.Cmd,
 				Help: meeseeks.NewHelp(
 					cmd.Help.Summary,
-					cmd.Help.Args...),
-				Timeout: cmd.Timeout * time.Second,
+					cmd.helpArgs()...),
+				Timeout:           cmd.Timeout * time.Second,
+				GracePeriod:       cmd.GracePeriod * time.Second,
+				Approvers:         cmd.Approval.Approvers,
+				RequiredApprovals: cmd.Approval.RequiredApprovals,
+				MaxConcurrency:    cmd.MaxConcurrency,
+				Lock:              cmd.Lock,
+				LockPolicy:        cmd.LockPolicy,
+				Cooldown:          cmd.Cooldown * time.Second,
+				Params:            cmd.params(),
+				Env:               cmd.Env,
+				CleanEnv:          cmd.InheritEnv != nil && !*cmd.InheritEnv,
+				WorkDir:           cmd.WorkDir,
+				User:              cmd.User,
+				Group:             cmd.Group,
+				Limits: meeseeks.ResourceLimits{
+					CPU:          cmd.Limits.CPU,
+					AddressSpace: cmd.Limits.AddressSpace * 1024 * 1024,
+					OpenFiles:    cmd.Limits.OpenFiles,
+					Processes:    cmd.Limits.Processes,
+				},
+				SuccessExitCodes: cmd.ExitCodes.Success,
+				WarningExitCodes: cmd.ExitCodes.Warning,
 			}),
 		})
 	}
@@ -65,7 +115,12 @@ func LoadConfiguration(cnf Config) error {
 		return fmt.Errorf("could not load commands: %s", err)
 	}
 
+	if err := scheduler.Configure(cnf.Schedules); err != nil {
+		return fmt.Errorf("could not load schedules: %s", err)
+	}
+
 	auth.Configure(cnf.Groups)
+	ratelimit.Configure(cnf.RateLimits)
 	formatter.Configure(cnf.Format)
 
 	return nil
@@ -83,6 +138,7 @@ func New(r io.Reader) (Config, error) {
 			Colors: formatter.MessageColors{
 				Info:    formatter.DefaultInfoColorMessage,
 				Success: formatter.DefaultSuccessColorMessage,
+				Warning: formatter.DefaultWarningColorMessage,
 				Error:   formatter.DefaultErrColorMessage,
 			},
 			ReplyStyle: map[string]string{},
@@ -110,19 +166,99 @@ type Config struct {
 	Groups   map[string][]string    `yaml:"groups"`
 	Pool     int                    `yaml:"pool"`
 	Format   formatter.FormatConfig `yaml:"format"`
+
+	Schedules  map[string]scheduler.Schedule `yaml:"schedules"`
+	RateLimits ratelimit.Config              `yaml:"rate_limits"`
 }
 
 // Command is the struct that handles a command configuration
 type Command struct {
-	Cmd             string        `yaml:"command"`
-	Args            []string      `yaml:"args"`
-	AllowedGroups   []string      `yaml:"allowed_groups"`
-	AuthStrategy    string        `yaml:"auth_strategy"`
-	ChannelStrategy string        `yaml:"channel_strategy"`
-	AllowedChannels []string      `yaml:"allowed_channels"`
-	NoHandshake     bool          `yaml:"no_handshake"`
-	Timeout         time.Duration `yaml:"timeout"`
-	Help            CommandHelp   `yaml:"help"`
+	Cmd             string            `yaml:"command"`
+	Args            []string          `yaml:"args"`
+	AllowedGroups   []string          `yaml:"allowed_groups"`
+	AuthStrategy    string            `yaml:"auth_strategy"`
+	ChannelStrategy string            `yaml:"channel_strategy"`
+	AllowedChannels []string          `yaml:"allowed_channels"`
+	NoHandshake     bool              `yaml:"no_handshake"`
+	Confirm         bool              `yaml:"confirm"`
+	Timeout         time.Duration     `yaml:"timeout"`
+	GracePeriod     time.Duration     `yaml:"grace_period"`
+	Help            CommandHelp       `yaml:"help"`
+	Approval        CommandApproval   `yaml:"approval"`
+	MaxConcurrency  int               `yaml:"max_concurrency"`
+	Lock            string            `yaml:"lock"`
+	LockPolicy      string            `yaml:"lock_policy"`
+	Cooldown        time.Duration     `yaml:"cooldown"`
+	Params          []CommandParam    `yaml:"params"`
+	Env             map[string]string `yaml:"env"`
+	InheritEnv      *bool             `yaml:"inherit_env"`
+	WorkDir         string            `yaml:"workdir"`
+	User            string            `yaml:"user"`
+	Group           string            `yaml:"group"`
+	Limits          CommandLimits     `yaml:"limits"`
+	ExitCodes       CommandExitCodes  `yaml:"exit_codes"`
+}
+
+// CommandExitCodes is the struct that maps the exit codes of a command to job statuses
+type CommandExitCodes struct {
+	Success []int `yaml:"success"`
+	Warning []int `yaml:"warning"`
+}
+
+// CommandLimits is the struct that handles the resource limits of a command
+type CommandLimits struct {
+	// CPU is the CPU time in seconds
+	CPU uint64 `yaml:"cpu"`
+	// AddressSpace is the size of the virtual memory in megabytes
+	AddressSpace uint64 `yaml:"address_space"`
+	OpenFiles    uint64 `yaml:"open_files"`
+	Processes    uint64 `yaml:"processes"`
+}
+
+func (c Command) params() []meeseeks.Param {
+	if len(c.Params) == 0 {
+		return nil
+	}
+	ps := make([]meeseeks.Param, 0, len(c.Params))
+	for _, p := range c.Params {
+		ps = append(ps, meeseeks.Param{
+			Name:        p.Name,
+			Type:        p.Type,
+			Description: p.Description,
+			Regex:       p.Regex,
+			Values:      p.Values,
+			Required:    p.Required,
+			Default:     p.Default,
+			Flag:        p.Flag,
+		})
+	}
+	return ps
+}
+
+// helpArgs returns the help of the declared params, or the configured one when there are none
+func (c Command) helpArgs() []string {
+	if len(c.Params) == 0 {
+		return c.Help.Args
+	}
+	return params.Help(c.params())
+}
+
+// CommandParam is the struct that handles an argument a command accepts
+type CommandParam struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`
+	Description string   `yaml:"description"`
+	Regex       string   `yaml:"regex"`
+	Values      []string `yaml:"values"`
+	Required    bool     `yaml:"required"`
+	Default     string   `yaml:"default"`
+	Flag        bool     `yaml:"flag"`
+}
+
+// CommandApproval is the struct that handles who has to approve a command before it runs
+type CommandApproval struct {
+	Approvers         []string `yaml:"approvers"`
+	RequiredApprovals int      `yaml:"required_approvals"`
 }
 
 // CommandHelp is the struct that handles the help of a command
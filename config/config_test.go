@@ -15,6 +15,7 @@ import (
 	"gitlab.com/yakshaving.art/meeseeks-box/config"
 	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence/db"
+	"gitlab.com/yakshaving.art/meeseeks-box/scheduler"
 	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
 	"github.com/renstrom/dedent"
 )
@@ -24,6 +25,7 @@ func Test_ConfigurationReading(t *testing.T) {
 		Info:    formatter.DefaultInfoColorMessage,
 		Error:   formatter.DefaultErrColorMessage,
 		Success: formatter.DefaultSuccessColorMessage,
+		Warning: formatter.DefaultWarningColorMessage,
 	}
 	defaultDatabase := db.DatabaseConfig{
 		Path:    "meeseeks.db",
@@ -73,6 +75,7 @@ func Test_ConfigurationReading(t *testing.T) {
 				  colors:
 				    info: "#FFFFFF"
 				    success: "#CCCCCC"
+				    warning: "#FFCC00"
 				    error: "#000000"
 				`),
 			config.Config{
@@ -80,6 +83,7 @@ func Test_ConfigurationReading(t *testing.T) {
 					Colors: formatter.MessageColors{
 						Info:    "#FFFFFF",
 						Success: "#CCCCCC",
+						Warning: "#FFCC00",
 						Error:   "#000000",
 					},
 					ReplyStyle: map[string]string{},
@@ -112,6 +116,37 @@ func Test_ConfigurationReading(t *testing.T) {
 				Pool:     20,
 			},
 		},
+		{
+			"With schedules",
+			dedent.Dedent(`
+				schedules:
+				  nightly-backup:
+				    cron: "0 3 * * *"
+				    command: "backup"
+				    args: ["--all"]
+				    user: "someone"
+				    channel: "C123"
+				    origin: "matrix"
+				`),
+			config.Config{
+				Schedules: map[string]scheduler.Schedule{
+					"nightly-backup": {
+						Cron:    "0 3 * * *",
+						Command: "backup",
+						Args:    []string{"--all"},
+						User:    "someone",
+						Channel: "C123",
+						Origin:  "matrix",
+					},
+				},
+				Format: formatter.FormatConfig{
+					Colors:     defaultColors,
+					ReplyStyle: map[string]string{},
+				},
+				Database: defaultDatabase,
+				Pool:     20,
+			},
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.Name, func(t *testing.T) {
@@ -222,3 +257,80 @@ func TestReloadingConfigurationReplacesThings(t *testing.T) {
 	mocks.AssertEquals(t, first.GetAuthStrategy(), "any")
 	mocks.AssertEquals(t, second.GetAuthStrategy(), "group")
 }
+
+func TestInvalidSchedulesDoNotReloadAnything(t *testing.T) {
+	c, err := config.ReadFile("./test-fixtures/basic-config.yml")
+	mocks.Must(t, "could not read configuration file", err)
+	mocks.Must(t, "failed to load configuration", config.LoadConfiguration(c))
+
+	c.Commands["echo-3"] = config.Command{Cmd: "echo", AuthStrategy: "any"}
+	c.Groups = map[string][]string{"admin": {"someone_else"}}
+	c.Schedules = map[string]scheduler.Schedule{
+		"broken": {Cron: "61 * * * *", Command: "echo-3", User: "pablo"},
+	}
+	err = config.LoadConfiguration(c)
+	mocks.AssertEquals(t, `could not load schedules: invalid schedule broken: invalid cron expression "61 * * * *": minute 61 is out of range 0-59`, err.Error())
+
+	_, ok := commands.Find(&meeseeks.Request{
+		Command: "echo-3",
+	})
+	mocks.AssertEquals(t, false, ok)
+	mocks.AssertEquals(t, []string{"pablo"}, auth.GetGroups()["admin"])
+}
+
+func TestInvalidLockPoliciesDoNotLoad(t *testing.T) {
+	c, err := config.ReadFile("./test-fixtures/basic-config.yml")
+	mocks.Must(t, "could not read configuration file", err)
+
+	c.Commands["deploy"] = config.Command{Cmd: "deploy.sh", Lock: "deploy-prod", LockPolicy: "wait"}
+	err = config.LoadConfiguration(c)
+	mocks.AssertEquals(t, "could not load commands: invalid lock_policy wait for command deploy, it should be queue or reject", err.Error())
+
+	_, ok := commands.Find(&meeseeks.Request{
+		Command: "deploy",
+	})
+	mocks.AssertEquals(t, false, ok)
+}
+
+func TestCommandParamsGenerateTheHelp(t *testing.T) {
+	c, err := config.New(strings.NewReader(dedent.Dedent(`
+		---
+		commands:
+		  deploy:
+		    command: deploy.sh
+		    help:
+		      summary: deploys the app
+		      args: ["ignored once params are declared"]
+		    params:
+		    - name: env
+		      type: enum
+		      values: [staging, prod]
+		      required: true
+		    - name: dry-run
+		      type: bool
+		      flag: true
+		      description: only show what would change
+		`)))
+	mocks.Must(t, "could not parse configuration", err)
+	mocks.Must(t, "could not load configuration", config.LoadConfiguration(c))
+
+	cmd, ok := commands.Find(&meeseeks.Request{Command: "deploy"})
+	mocks.AssertEquals(t, true, ok)
+	mocks.AssertEquals(t, []string{
+		"env (one of staging, prod), required",
+		"--dry-run (bool): only show what would change",
+	}, cmd.GetHelp().GetArgs())
+
+	c.Commands["broken"] = config.Command{Cmd: "echo", Params: []config.CommandParam{{Name: "n", Type: "number"}}}
+	err = config.LoadConfiguration(c)
+	mocks.AssertEquals(t, "could not load commands: invalid params for command broken: param n has an unknown type number", err.Error())
+}
+
+func TestOverlappingExitCodesDoNotLoad(t *testing.T) {
+	c, err := config.ReadFile("./test-fixtures/basic-config.yml")
+	mocks.Must(t, "could not read configuration file", err)
+
+	c.Commands["check"] = config.Command{Cmd: "check.sh", ExitCodes: config.CommandExitCodes{Warning: []int{0, 2}}}
+	err = config.LoadConfiguration(c)
+	mocks.AssertEquals(t, "could not load commands: exit code 0 of command check is both a success and a warning", err.Error())
+}
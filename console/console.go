@@ -0,0 +1,217 @@
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/parser"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	nullStyle     = "null"
+	nilStyle      = "nil"
+	disabledStyle = "disabled"
+)
+
+// Origin identifies the requests that come from the console
+const Origin = "console"
+
+const (
+	prompt    = "> "
+	ansiReset = "\x1b[0m"
+	imChannel = "IM"
+)
+
+// Slack named colors mapped to ANSI escape codes
+var namedColors = map[string]string{
+	formatter.DefaultSuccessColorMessage: "\x1b[32m",
+	formatter.DefaultWarningColorMessage: "\x1b[33m",
+	formatter.DefaultErrColorMessage:     "\x1b[31m",
+}
+
+// Opts groups the console options in a single struct
+type Opts struct {
+	Username string
+	Channel  string
+
+	// OnClose is called when the input is closed
+	OnClose func()
+}
+
+// Client is a chat client that reads commands from an input and writes the replies to an output
+type Client struct {
+	in   io.Reader
+	out  io.Writer
+	opts Opts
+
+	writeLock sync.Mutex
+}
+
+// New creates a new console client, with an empty channel every request is an IM
+func New(in io.Reader, out io.Writer, opts Opts) *Client {
+	return &Client{
+		in:   in,
+		out:  out,
+		opts: opts,
+	}
+}
+
+// Listen reads lines from the input and sends them through the channel as requests
+func (c *Client) Listen(ch chan<- meeseeks.Request) {
+	logrus.Infof("Listening Console Input as %s", c.opts.Username)
+
+	c.print(prompt)
+	scanner := bufio.NewScanner(c.in)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			c.print(prompt)
+			continue
+		}
+
+		r, err := c.requestFromText(text)
+		if err != nil {
+			logrus.Debugf("Failed to parse line '%s' as a command: %s", text, err)
+			c.Reply(formatter.FailureReply(c.request(), err))
+			continue
+		}
+		ch <- r
+	}
+	if err := scanner.Err(); err != nil {
+		logrus.Errorf("failed to read console input: %s", err)
+	}
+
+	logrus.Infof("Stopped listening to messages")
+	if c.opts.OnClose != nil {
+		c.opts.OnClose()
+	}
+}
+
+func (c *Client) requestFromText(text string) (meeseeks.Request, error) {
+	args, err := parser.Parse(text)
+	logrus.Debugf("Command '%s' parsed as %#v", text, args)
+
+	if err != nil {
+		return meeseeks.Request{}, err
+	}
+	if len(args) == 0 {
+		return meeseeks.Request{}, fmt.Errorf("no command to run")
+	}
+
+	r := c.request()
+	r.Command = args[0]
+	r.Args = args[1:]
+	return r, nil
+}
+
+func (c *Client) request() meeseeks.Request {
+	channelID := c.opts.Channel
+	if channelID == "" {
+		channelID = imChannel
+	}
+	return meeseeks.Request{
+		Username:    c.opts.Username,
+		UserID:      c.opts.Username,
+		UserLink:    c.GetUserLink(c.opts.Username),
+		Channel:     c.GetChannel(channelID),
+		ChannelID:   channelID,
+		ChannelLink: c.GetChannelLink(channelID),
+		IsIM:        c.IsIM(channelID),
+		Origin:      Origin,
+	}
+}
+
+// Reply prints the rendered reply colored with the reply color
+func (c *Client) Reply(r formatter.Reply) {
+	switch r.ReplyStyle() {
+	case nullStyle, disabledStyle, nilStyle:
+		logrus.Debugf("Ignoring reply %#v, the null formatter is like this", r)
+		return
+	}
+
+	content, err := r.Render()
+	if err != nil {
+		logrus.Errorf("failed to render reply %#v: %s", r, err)
+		return
+	}
+
+	if color := ansiColor(r.Color()); color != "" {
+		content = color + content + ansiReset
+	}
+	c.print(strings.TrimRight(content, "\n") + "\n" + prompt)
+}
+
+func (c *Client) print(text string) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if _, err := io.WriteString(c.out, text); err != nil {
+		logrus.Errorf("failed to write to console: %s", err)
+	}
+}
+
+// ansiColor maps a slack named color or a hex color to an ANSI escape code
+func ansiColor(color string) string {
+	if code, ok := namedColors[color]; ok {
+		return code
+	}
+
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) != 6 || hex == color {
+		return ""
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", rgb>>16, (rgb>>8)&0xff, rgb&0xff)
+}
+
+// ParseChannelLink implements the api.Enricher interface
+func (c *Client) ParseChannelLink(channelLink string) (string, error) {
+	if !strings.HasPrefix(channelLink, "#") {
+		return "", fmt.Errorf("invalid channel link: %s", channelLink)
+	}
+	return channelLink[1:], nil
+}
+
+// ParseUserLink implements the api.Enricher interface
+func (c *Client) ParseUserLink(userLink string) (string, error) {
+	if !strings.HasPrefix(userLink, "@") {
+		return "", fmt.Errorf("invalid user link: %s", userLink)
+	}
+	return userLink[1:], nil
+}
+
+// GetUsername implements the api.Enricher interface
+func (c *Client) GetUsername(userID string) string {
+	return userID
+}
+
+// GetUserLink implements the api.Enricher interface
+func (c *Client) GetUserLink(userID string) string {
+	return "@" + userID
+}
+
+// GetChannel implements the api.Enricher interface
+func (c *Client) GetChannel(channelID string) string {
+	return channelID
+}
+
+// GetChannelLink implements the api.Enricher interface
+func (c *Client) GetChannelLink(channelID string) string {
+	return "#" + channelID
+}
+
+// IsIM implements the api.Enricher interface
+func (c *Client) IsIM(channelID string) bool {
+	return channelID == imChannel
+}
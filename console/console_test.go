@@ -0,0 +1,99 @@
+package console_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/console"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/template"
+)
+
+func TestConsoleListensToInputLines(t *testing.T) {
+	formatter.Configure(formatter.FormatConfig{})
+
+	closed := make(chan bool, 1)
+	c := console.New(strings.NewReader("echo hello\n\necho 'unclosed\n"), &bytes.Buffer{}, console.Opts{
+		Username: "someone",
+		Channel:  "general",
+		OnClose:  func() { closed <- true },
+	})
+
+	ch := make(chan meeseeks.Request, 1)
+	go c.Listen(ch)
+
+	mocks.AssertEquals(t, meeseeks.Request{
+		Command:     "echo",
+		Args:        []string{"hello"},
+		Username:    "someone",
+		UserID:      "someone",
+		UserLink:    "@someone",
+		Channel:     "general",
+		ChannelID:   "general",
+		ChannelLink: "#general",
+		Origin:      console.Origin,
+	}, <-ch)
+	mocks.AssertEquals(t, true, <-closed)
+}
+
+func TestConsoleWithoutChannelIsIM(t *testing.T) {
+	c := console.New(strings.NewReader("echo\n"), &bytes.Buffer{}, console.Opts{Username: "someone"})
+
+	ch := make(chan meeseeks.Request, 1)
+	go c.Listen(ch)
+
+	r := <-ch
+	mocks.AssertEquals(t, true, r.IsIM)
+	mocks.AssertEquals(t, "IM", r.Channel)
+}
+
+func TestConsoleRepliesWithColors(t *testing.T) {
+	formatter.Configure(formatter.FormatConfig{
+		Templates: map[string]string{
+			template.Success:   "{{ .command }} done",
+			template.Failure:   "{{ .command }} failed",
+			template.Handshake: "on it",
+		},
+		ReplyStyle: map[string]string{
+			template.Handshake: "null",
+		},
+		Colors: formatter.MessageColors{
+			Success: "#00ff80",
+			Error:   formatter.DefaultErrColorMessage,
+		},
+	})
+
+	tt := []struct {
+		name     string
+		reply    formatter.Reply
+		expected string
+	}{
+		{
+			name:     "hex color",
+			reply:    formatter.SuccessReply(meeseeks.Request{Command: "echo"}),
+			expected: "\x1b[38;2;0;255;128mecho done\x1b[0m\n> ",
+		},
+		{
+			name:     "named color",
+			reply:    formatter.FailureReply(meeseeks.Request{Command: "echo"}, fmt.Errorf("boom")),
+			expected: "\x1b[31mecho failed\x1b[0m\n> ",
+		},
+		{
+			name:     "null style",
+			reply:    formatter.HandshakeReply(meeseeks.Request{Command: "echo"}),
+			expected: "",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			console.New(strings.NewReader(""), out, console.Opts{}).Reply(tc.reply)
+			mocks.AssertEquals(t, tc.expected, out.String())
+		})
+	}
+}
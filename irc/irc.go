@@ -0,0 +1,485 @@
+package irc
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/parser"
+
+	"github.com/jpillora/backoff"
+	"github.com/sirupsen/logrus"
+)
+
+var errIgnoredMessage = fmt.Errorf("ignore this message")
+var errNoCommandToRun = fmt.Errorf("no command to run")
+var errUnidentifiedUser = fmt.Errorf("you have to be identified with the network services to run commands")
+
+// accountTagCapability is the IRCv3 capability that tags messages with the account of the sender
+const accountTagCapability = "account-tag"
+
+const (
+	nullStyle     = "null"
+	nilStyle      = "nil"
+	disabledStyle = "disabled"
+)
+
+// Origin identifies the requests that come from irc
+const Origin = "irc"
+
+// Defaults used when the connection options are not set
+const (
+	DefaultFloodDelay          = 700 * time.Millisecond
+	DefaultRegistrationTimeout = 30 * time.Second
+
+	// Lines are limited to 512 bytes, including the prefix the server adds when relaying
+	maxMessageLength = 512
+	prefixAllowance  = 100
+)
+
+// ConnectionOpts groups all the connection options in a single struct
+type ConnectionOpts struct {
+	Server   string
+	Nick     string
+	Channels []string
+
+	TLS                bool
+	InsecureSkipVerify bool
+
+	SASLUser     string
+	SASLPassword string
+
+	// TrustNicks accepts commands from any nick, even if it is not identified
+	// with an account. Anyone can take a nick, so it should only be used in
+	// networks where nicks can't be impersonated
+	TrustNicks bool
+
+	FloodDelay          time.Duration
+	RegistrationTimeout time.Duration
+}
+
+// Client is an irc chat client
+type Client struct {
+	opts ConnectionOpts
+	nick string
+
+	conn      net.Conn
+	reader    *bufio.Reader
+	writeLock sync.Mutex
+	lastWrite time.Time
+
+	matcher messageMatcher
+}
+
+// Connect builds a new chat client
+func Connect(opts ConnectionOpts) (*Client, error) {
+	if opts.Server == "" {
+		return nil, fmt.Errorf("could not connect to irc: no server address")
+	}
+	if opts.Nick == "" {
+		return nil, fmt.Errorf("could not connect to irc: no nick")
+	}
+	if opts.FloodDelay == 0 {
+		opts.FloodDelay = DefaultFloodDelay
+	}
+	if opts.RegistrationTimeout == 0 {
+		opts.RegistrationTimeout = DefaultRegistrationTimeout
+	}
+
+	c := &Client{
+		opts: opts,
+	}
+	if err := c.connect(); err != nil {
+		return nil, fmt.Errorf("could not connect to irc: %s", err)
+	}
+	return c, nil
+}
+
+func (c *Client) connect() error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+
+	c.writeLock.Lock()
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	c.writeLock.Unlock()
+
+	if err := c.register(); err != nil {
+		conn.Close()
+		return err
+	}
+	c.matcher = newMessageMatcher(c.nick)
+
+	for _, channel := range c.opts.Channels {
+		if err := c.send("JOIN %s", channel); err != nil {
+			conn.Close()
+			return fmt.Errorf("could not join %s: %s", channel, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) dial() (net.Conn, error) {
+	if c.opts.TLS {
+		return tls.Dial("tcp", c.opts.Server, &tls.Config{
+			InsecureSkipVerify: c.opts.InsecureSkipVerify,
+		})
+	}
+	return net.Dial("tcp", c.opts.Server)
+}
+
+// register performs the connection registration, authenticating with SASL PLAIN when configured
+// and requesting the account-tag capability to identify users unless nicks are trusted
+func (c *Client) register() error {
+	c.nick = c.opts.Nick
+	c.conn.SetReadDeadline(time.Now().Add(c.opts.RegistrationTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	capabilities := make([]string, 0)
+	if !c.opts.TrustNicks {
+		capabilities = append(capabilities, accountTagCapability)
+	}
+	if c.opts.SASLUser != "" {
+		capabilities = append(capabilities, "sasl")
+	}
+	for _, capability := range capabilities {
+		if err := c.send("CAP REQ :%s", capability); err != nil {
+			return err
+		}
+	}
+	negotiating, pending := len(capabilities) > 0, len(capabilities)
+	if err := c.send("NICK %s", c.nick); err != nil {
+		return err
+	}
+	if err := c.send("USER %s 0 * :Mr. Meeseeks", c.nick); err != nil {
+		return err
+	}
+
+	for {
+		m, err := c.read()
+		if err != nil {
+			return fmt.Errorf("registration failed: %s", err)
+		}
+
+		switch m.Command {
+		case "PING":
+			err = c.send("PONG :%s", m.Param(0))
+
+		case "CAP":
+			capability := strings.TrimSpace(m.Param(2))
+			switch m.Param(1) {
+			case "ACK":
+				if capability == "sasl" {
+					err = c.send("AUTHENTICATE PLAIN")
+				} else {
+					pending--
+				}
+			case "NAK":
+				if capability == "sasl" {
+					return fmt.Errorf("server does not support sasl")
+				}
+				return fmt.Errorf("server does not support %s, so users can't be identified by their account;"+
+					" nicks have to be explicitly trusted to accept commands from anyone using them", capability)
+			}
+
+		case "AUTHENTICATE":
+			if m.Param(0) == "+" {
+				err = c.send("AUTHENTICATE %s", base64.StdEncoding.EncodeToString(
+					[]byte(c.opts.SASLUser+"\x00"+c.opts.SASLUser+"\x00"+c.opts.SASLPassword)))
+			}
+
+		case "903": // RPL_SASLSUCCESS
+			pending--
+
+		case "902", "904", "905", "906": // SASL failures
+			return fmt.Errorf("sasl authentication failed: %s", m.Param(len(m.Params)-1))
+
+		case "433": // ERR_NICKNAMEINUSE
+			c.nick += "_"
+			err = c.send("NICK %s", c.nick)
+
+		case "001": // RPL_WELCOME
+			c.nick = m.Param(0)
+			logrus.Debugf("Registered in irc as %s", c.nick)
+			return nil
+
+		case "ERROR":
+			return fmt.Errorf("server closed the connection: %s", m.Param(0))
+		}
+
+		if err != nil {
+			return err
+		}
+		if negotiating && pending == 0 {
+			negotiating = false
+			if err := c.send("CAP END"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Client) read() (message, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return message{}, err
+	}
+	logrus.Debugf("IRC <- %s", strings.TrimSpace(line))
+	return parseMessage(line), nil
+}
+
+// send writes a line to the server waiting long enough between lines to not be kicked for flooding
+func (c *Client) send(format string, args ...interface{}) error {
+	line := fmt.Sprintf(format, args...)
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if wait := c.opts.FloodDelay - time.Since(c.lastWrite); wait > 0 {
+		time.Sleep(wait)
+	}
+	c.lastWrite = time.Now()
+
+	logrus.Debugf("IRC -> %s", line)
+	_, err := fmt.Fprintf(c.conn, "%s\r\n", line)
+	return err
+}
+
+// Listen listens to irc messages and sends the matching ones through the channel as requests
+func (c *Client) Listen(ch chan<- meeseeks.Request) {
+	b := &backoff.Backoff{
+		Min:    100 * time.Millisecond,
+		Max:    10 * time.Second,
+		Factor: 2,
+		Jitter: true,
+	}
+
+	logrus.Infof("Listening IRC Messages")
+	for {
+		if err := c.serve(ch); err != nil {
+			logrus.Warnf("irc connection dropped: %s. Reconnecting", err)
+		}
+		c.conn.Close()
+
+		for {
+			err := c.connect()
+			if err == nil {
+				b.Reset()
+				break
+			}
+			if b.Attempt() > 10 {
+				logrus.Errorf("failed to reconnect to irc: %s. Quitting", err)
+				logrus.Infof("Stopped listening to messages")
+				return
+			}
+			logrus.Warnf("failed to reconnect to irc: %s. Retrying", err)
+			time.Sleep(b.Duration())
+		}
+	}
+}
+
+func (c *Client) serve(ch chan<- meeseeks.Request) error {
+	for {
+		m, err := c.read()
+		if err != nil {
+			return fmt.Errorf("could not read message: %s", err)
+		}
+
+		switch m.Command {
+		case "PING":
+			if err := c.send("PONG :%s", m.Param(0)); err != nil {
+				return err
+			}
+
+		case "ERROR":
+			return fmt.Errorf("server closed the connection: %s", m.Param(0))
+
+		case "PRIVMSG":
+			text, err := c.matcher.Matches(m)
+			if err != nil {
+				continue
+			}
+
+			account := m.Tag("account")
+			if c.opts.TrustNicks {
+				account = m.Nick()
+			}
+			if account == "" {
+				logrus.Infof("Rejecting message '%s' from %s, who is not identified", text, m.Nick())
+				c.Reply(formatter.FailureReply(meeseeks.Request{ChannelID: replyTarget(m), UserLink: m.Nick()}, errUnidentifiedUser))
+				continue
+			}
+
+			r, err := requestFromMessage(text, account, m)
+			if err != nil {
+				logrus.Debugf("Failed to parse message '%s' as a command: %s", text, err)
+				c.Reply(formatter.FailureReply(meeseeks.Request{ChannelID: replyTarget(m)}, err))
+				continue
+			}
+
+			logrus.Debugf("Sending IRC message %#v to messages channel", m)
+			ch <- r
+		}
+	}
+}
+
+// replyTarget returns the channel the message was sent to, or the sender nick if it was a private message
+func replyTarget(m message) string {
+	if isChannel(m.Param(0)) {
+		return m.Param(0)
+	}
+	return m.Nick()
+}
+
+// requestFromMessage builds a request from a message sent by the user identified
+// with the passed account, which is used instead of the nick to authorize it
+func requestFromMessage(text, account string, m message) (meeseeks.Request, error) {
+	args, err := parser.Parse(text)
+	logrus.Debugf("Command '%s' parsed as %#v", text, args)
+
+	if err != nil {
+		return meeseeks.Request{}, err
+	}
+
+	if len(args) == 0 {
+		return meeseeks.Request{}, errNoCommandToRun
+	}
+
+	channelID := replyTarget(m)
+	channel := channelName(channelID)
+
+	return meeseeks.Request{
+		Command:     args[0],
+		Args:        args[1:],
+		Username:    account,
+		UserID:      account,
+		UserLink:    m.Nick(),
+		Channel:     channel,
+		ChannelID:   channelID,
+		ChannelLink: channel,
+		IsIM:        !isChannel(channelID),
+		Origin:      Origin,
+	}, nil
+}
+
+// Reply replies to the user, splitting the content in as many lines as necessary
+func (c *Client) Reply(r formatter.Reply) {
+	switch r.ReplyStyle() {
+	case nullStyle, disabledStyle, nilStyle:
+		logrus.Debugf("Ignoring reply %#v, the null formatter is like this", r)
+		return
+	}
+
+	content, err := r.Render()
+	if err != nil {
+		logrus.Errorf("failed to render reply %#v: %s", r, err)
+		return
+	}
+
+	target := r.ChannelID()
+	maxLength := maxMessageLength - prefixAllowance - len("PRIVMSG  :\r\n") - len(target)
+
+	for _, line := range splitLines(content, maxLength) {
+		if err := c.send("PRIVMSG %s :%s", target, line); err != nil {
+			logrus.Errorf("failed to send message to %s: %s", target, err)
+			return
+		}
+	}
+}
+
+// ParseChannelLink implements the api.Enricher interface
+func (c *Client) ParseChannelLink(channelLink string) (string, error) {
+	if !isChannel(channelLink) {
+		return "", fmt.Errorf("invalid channel link: %s", channelLink)
+	}
+	return channelLink, nil
+}
+
+// ParseUserLink implements the api.Enricher interface
+func (c *Client) ParseUserLink(userLink string) (string, error) {
+	nick := strings.TrimPrefix(userLink, "@")
+	if nick == "" || isChannel(nick) || strings.ContainsAny(nick, " ,") {
+		return "", fmt.Errorf("invalid user link: %s", userLink)
+	}
+	return nick, nil
+}
+
+// GetUsername implements the api.Enricher interface
+func (c *Client) GetUsername(userID string) string {
+	return userID
+}
+
+// GetUserLink implements the api.Enricher interface
+func (c *Client) GetUserLink(userID string) string {
+	return userID
+}
+
+// GetChannel implements the api.Enricher interface
+func (c *Client) GetChannel(channelID string) string {
+	return channelName(channelID)
+}
+
+// GetChannelLink implements the api.Enricher interface
+func (c *Client) GetChannelLink(channelID string) string {
+	return channelName(channelID)
+}
+
+// IsIM implements the api.Enricher interface
+func (c *Client) IsIM(channelID string) bool {
+	return !isChannel(channelID)
+}
+
+func channelName(channelID string) string {
+	if isChannel(channelID) {
+		return channelID
+	}
+	return "IM"
+}
+
+type messageMatcher struct {
+	nick          string
+	prefixMatches []string
+}
+
+func newMessageMatcher(nick string) messageMatcher {
+	nick = strings.ToLower(nick)
+	return messageMatcher{
+		nick:          nick,
+		prefixMatches: []string{nick + ":", nick + ",", nick + " "},
+	}
+}
+
+// Matches returns the text of the message that is a command for this bot
+func (m messageMatcher) Matches(msg message) (string, error) {
+	if strings.ToLower(msg.Nick()) == m.nick {
+		logrus.Debug("It's myself, ignoring message")
+		return "", errIgnoredMessage
+	}
+
+	text := msg.Param(1)
+	if strings.HasPrefix(text, "\x01") {
+		logrus.Debugf("Ignoring CTCP message '%s'", text)
+		return "", errIgnoredMessage
+	}
+
+	if !isChannel(msg.Param(0)) {
+		logrus.Debugf("Message from %s is a private message, responding...", msg.Nick())
+		return text, nil
+	}
+	for _, match := range m.prefixMatches {
+		if strings.HasPrefix(strings.ToLower(text), match) {
+			logrus.Debugf("Message '%s' matches prefix, responding...", text)
+			return strings.TrimSpace(text[len(match):]), nil
+		}
+	}
+	return "", errIgnoredMessage
+}
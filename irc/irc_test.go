@@ -0,0 +1,288 @@
+package irc_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/irc"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/template"
+)
+
+// fakeServer is a minimal irc server that registers a single client and sends it a
+// few messages once it joins a channel
+type fakeServer struct {
+	listener     net.Listener
+	saslPassword string
+	accountTag   bool
+	messages     []string
+	privmsgs     chan string
+}
+
+func newFakeServer(t *testing.T, saslPassword string, accountTag bool, messages ...string) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	mocks.Must(t, "could not listen", err)
+
+	s := &fakeServer{
+		listener:     l,
+		saslPassword: saslPassword,
+		accountTag:   accountTag,
+		messages:     messages,
+		privmsgs:     make(chan string, 100),
+	}
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeServer) Close() {
+	s.listener.Close()
+}
+
+func (s *fakeServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	send := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+
+	nick := ""
+	negotiating := false
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		parts := strings.SplitN(line, " ", 2)
+
+		switch parts[0] {
+		case "CAP":
+			switch parts[1] {
+			case "REQ :sasl":
+				negotiating = true
+				send(":irc.test CAP * ACK :sasl")
+			case "REQ :account-tag":
+				negotiating = true
+				if s.accountTag {
+					send(":irc.test CAP * ACK :account-tag")
+				} else {
+					send(":irc.test CAP * NAK :account-tag")
+				}
+			case "END":
+				send(":irc.test 001 " + nick + " :Welcome")
+			}
+		case "AUTHENTICATE":
+			if parts[1] == "PLAIN" {
+				send("AUTHENTICATE +")
+				continue
+			}
+			expected := base64.StdEncoding.EncodeToString([]byte("meeseeks\x00meeseeks\x00" + s.saslPassword))
+			if parts[1] == expected {
+				send(":irc.test 903 " + nick + " :SASL authentication successful")
+			} else {
+				send(":irc.test 904 " + nick + " :SASL authentication failed")
+			}
+		case "NICK":
+			if parts[1] == "meeseeks" {
+				send(":irc.test 433 * meeseeks :Nickname is already in use")
+				continue
+			}
+			nick = parts[1]
+		case "USER":
+			if !negotiating {
+				send(":irc.test 001 " + nick + " :Welcome")
+			}
+		case "JOIN":
+			for _, m := range s.messages {
+				send(m)
+			}
+		case "PRIVMSG":
+			s.privmsgs <- parts[1]
+		}
+	}
+}
+
+func TestIRCClient(t *testing.T) {
+	server := newFakeServer(t, "password", true,
+		"PING :irc.test",
+		"@account=someaccount :someone!u@host PRIVMSG #meeseeks :not for the bot",
+		"@account=someaccount :someone!u@host PRIVMSG #meeseeks :\x01ACTION waves\x01",
+		":impostor!u@host PRIVMSG #meeseeks :meeseeks_: echo pretending",
+		"@account=someaccount :someone!u@host PRIVMSG #meeseeks :meeseeks_: echo hello",
+		"@time=2019-01-01T00:00:00.000Z;account=someaccount :someone!u@host PRIVMSG meeseeks_ :echo in private",
+	)
+	defer server.Close()
+
+	c, err := irc.Connect(irc.ConnectionOpts{
+		Server:       server.Addr(),
+		Nick:         "meeseeks",
+		Channels:     []string{"#meeseeks"},
+		SASLUser:     "meeseeks",
+		SASLPassword: "password",
+		FloodDelay:   time.Millisecond,
+	})
+	mocks.Must(t, "could not connect to fake irc server", err)
+
+	t.Run("listen", func(t *testing.T) {
+		formatter.Configure(formatter.FormatConfig{
+			Templates: map[string]string{
+				template.Failure: "{{ .userlink }} {{ .error }}",
+			},
+		})
+
+		ch := make(chan meeseeks.Request)
+		go c.Listen(ch)
+
+		select {
+		case l := <-server.privmsgs:
+			mocks.AssertEquals(t, "#meeseeks :impostor you have to be identified with the network services to run commands", l)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for the unidentified user to be rejected")
+		}
+
+		for _, expected := range []meeseeks.Request{
+			{
+				Command:     "echo",
+				Args:        []string{"hello"},
+				Username:    "someaccount",
+				UserID:      "someaccount",
+				UserLink:    "someone",
+				Channel:     "#meeseeks",
+				ChannelID:   "#meeseeks",
+				ChannelLink: "#meeseeks",
+				Origin:      irc.Origin,
+			},
+			{
+				Command:     "echo",
+				Args:        []string{"in", "private"},
+				Username:    "someaccount",
+				UserID:      "someaccount",
+				UserLink:    "someone",
+				Channel:     "IM",
+				ChannelID:   "someone",
+				ChannelLink: "IM",
+				IsIM:        true,
+				Origin:      irc.Origin,
+			},
+		} {
+			select {
+			case r := <-ch:
+				mocks.AssertEquals(t, expected, r)
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for a request")
+			}
+		}
+	})
+
+	t.Run("enricher", func(t *testing.T) {
+		channelID, err := c.ParseChannelLink("#meeseeks")
+		mocks.Must(t, "could not parse channel link", err)
+		mocks.AssertEquals(t, "#meeseeks", channelID)
+
+		_, err = c.ParseChannelLink("meeseeks")
+		mocks.AssertEquals(t, "invalid channel link: meeseeks", err.Error())
+
+		userID, err := c.ParseUserLink("@someone")
+		mocks.Must(t, "could not parse user link", err)
+		mocks.AssertEquals(t, "someone", userID)
+
+		mocks.AssertEquals(t, true, c.IsIM("someone"))
+		mocks.AssertEquals(t, false, c.IsIM("#meeseeks"))
+	})
+
+	t.Run("reply splits long lines", func(t *testing.T) {
+		formatter.Configure(formatter.FormatConfig{
+			Templates: map[string]string{
+				template.Success: "{{ .output }}",
+			},
+		})
+
+		long := strings.Repeat("0123456789 ", 100)
+		c.Reply(formatter.SuccessReply(meeseeks.Request{ChannelID: "#meeseeks"}).WithOutput("first line\n\n" + long))
+
+		lines := []string{}
+		timeout := time.After(2 * time.Second)
+		for len(lines) < 4 {
+			select {
+			case l := <-server.privmsgs:
+				mocks.AssertEquals(t, true, len(l) < 400)
+				lines = append(lines, l)
+			case <-timeout:
+				t.Fatalf("timed out waiting for the reply lines, got %#v", lines)
+			}
+		}
+		mocks.AssertEquals(t, "#meeseeks :first line", lines[0])
+		mocks.AssertEquals(t, long[:len(long)-1], strings.Join([]string{
+			strings.TrimPrefix(lines[1], "#meeseeks :"),
+			strings.TrimPrefix(lines[2], "#meeseeks :"),
+			strings.TrimPrefix(lines[3], "#meeseeks :"),
+		}, " "))
+	})
+}
+
+func TestIRCClientRequiresAccountsUnlessNicksAreTrusted(t *testing.T) {
+	server := newFakeServer(t, "", false)
+	defer server.Close()
+
+	_, err := irc.Connect(irc.ConnectionOpts{
+		Server:     server.Addr(),
+		Nick:       "meeseeks",
+		FloodDelay: time.Millisecond,
+	})
+	mocks.AssertEquals(t, "could not connect to irc: server does not support account-tag, so users can't be identified by their account;"+
+		" nicks have to be explicitly trusted to accept commands from anyone using them", err.Error())
+
+	server = newFakeServer(t, "", false,
+		":someone!u@host PRIVMSG meeseeks_ :echo trusted",
+	)
+	defer server.Close()
+
+	c, err := irc.Connect(irc.ConnectionOpts{
+		Server:     server.Addr(),
+		Nick:       "meeseeks",
+		Channels:   []string{"#meeseeks"},
+		TrustNicks: true,
+		FloodDelay: time.Millisecond,
+	})
+	mocks.Must(t, "could not connect trusting nicks", err)
+
+	ch := make(chan meeseeks.Request)
+	go c.Listen(ch)
+	select {
+	case r := <-ch:
+		mocks.AssertEquals(t, "someone", r.Username)
+		mocks.AssertEquals(t, []string{"trusted"}, r.Args)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a request")
+	}
+}
+
+func TestIRCClientFailsWithWrongSASLPassword(t *testing.T) {
+	server := newFakeServer(t, "password", true)
+	defer server.Close()
+
+	_, err := irc.Connect(irc.ConnectionOpts{
+		Server:       server.Addr(),
+		Nick:         "meeseeks",
+		SASLUser:     "meeseeks",
+		SASLPassword: "wrong",
+		FloodDelay:   time.Millisecond,
+	})
+	mocks.AssertEquals(t, "could not connect to irc: sasl authentication failed: SASL authentication failed", err.Error())
+}
@@ -0,0 +1,129 @@
+package irc
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+var tagValueUnescaper = strings.NewReplacer(`\:`, ";", `\s`, " ", `\\`, `\`, `\r`, "\r", `\n`, "\n")
+
+// message is a single parsed IRC protocol line
+type message struct {
+	Tags    map[string]string
+	Prefix  string
+	Command string
+	Params  []string
+}
+
+// parseMessage parses a raw IRC line in the form [@tags] [:prefix] command params [:trailing]
+func parseMessage(line string) message {
+	line = strings.TrimRight(line, "\r\n")
+
+	m := message{}
+	if strings.HasPrefix(line, "@") {
+		parts := strings.SplitN(line[1:], " ", 2)
+		m.Tags = parseTags(parts[0])
+		line = ""
+		if len(parts) > 1 {
+			line = strings.TrimLeft(parts[1], " ")
+		}
+	}
+	if strings.HasPrefix(line, ":") {
+		parts := strings.SplitN(line[1:], " ", 2)
+		m.Prefix = parts[0]
+		line = ""
+		if len(parts) > 1 {
+			line = parts[1]
+		}
+	}
+
+	for line != "" {
+		if strings.HasPrefix(line, ":") {
+			m.Params = append(m.Params, line[1:])
+			break
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if m.Command == "" {
+			m.Command = strings.ToUpper(parts[0])
+		} else if parts[0] != "" {
+			m.Params = append(m.Params, parts[0])
+		}
+		line = ""
+		if len(parts) > 1 {
+			line = parts[1]
+		}
+	}
+	return m
+}
+
+// parseTags parses IRCv3 message tags in the form key=value;key2=value2
+func parseTags(raw string) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(raw, ";") {
+		if tag == "" {
+			continue
+		}
+		kv := strings.SplitN(tag, "=", 2)
+		value := ""
+		if len(kv) > 1 {
+			value = tagValueUnescaper.Replace(kv[1])
+		}
+		tags[kv[0]] = value
+	}
+	return tags
+}
+
+// Tag returns the value of an IRCv3 message tag or an empty string if it isn't set
+func (m message) Tag(name string) string {
+	return m.Tags[name]
+}
+
+// Nick returns the nick part of the message prefix
+func (m message) Nick() string {
+	if i := strings.Index(m.Prefix, "!"); i >= 0 {
+		return m.Prefix[:i]
+	}
+	return m.Prefix
+}
+
+// Param returns the nth param or an empty string if it doesn't exist
+func (m message) Param(n int) string {
+	if n < len(m.Params) {
+		return m.Params[n]
+	}
+	return ""
+}
+
+func isChannel(target string) bool {
+	return strings.HasPrefix(target, "#") || strings.HasPrefix(target, "&")
+}
+
+// splitLines splits a multi-line text in lines no longer than max bytes, breaking on
+// spaces when possible and never in the middle of an utf8 rune
+func splitLines(text string, max int) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			continue
+		}
+		for len(line) > max {
+			cut := strings.LastIndex(line[:max+1], " ")
+			if cut <= 0 {
+				cut = max
+				for cut > 0 && !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+				if cut == 0 {
+					cut = max
+				}
+			}
+			lines = append(lines, line[:cut])
+			line = strings.TrimLeft(line[cut:], " ")
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
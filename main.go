@@ -3,19 +3,26 @@ package main
 import (
 	"flag"
 	"fmt"
+	nethttp "net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/api"
 	"gitlab.com/yakshaving.art/meeseeks-box/config"
+	"gitlab.com/yakshaving.art/meeseeks-box/console"
 	"gitlab.com/yakshaving.art/meeseeks-box/http"
+	"gitlab.com/yakshaving.art/meeseeks-box/irc"
+	"gitlab.com/yakshaving.art/meeseeks-box/matrix"
+	"gitlab.com/yakshaving.art/meeseeks-box/mattermost"
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks/executor"
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks/metrics"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
 	"gitlab.com/yakshaving.art/meeseeks-box/remote/agent"
 	"gitlab.com/yakshaving.art/meeseeks-box/remote/server"
+	"gitlab.com/yakshaving.art/meeseeks-box/scheduler"
 	"gitlab.com/yakshaving.art/meeseeks-box/slack"
 	"gitlab.com/yakshaving.art/meeseeks-box/version"
 
@@ -46,7 +53,30 @@ type args struct {
 	Address           string
 	APIPath           string
 	MetricsPath       string
+	ChatBackend       string
+	Console           bool
+	ConsoleUser       string
+	ConsoleChannel    string
+	MattermostURL     string
+	MattermostToken   string
+	MattermostTeam    string
+	IRCServer         string
+	IRCNick           string
+	IRCChannels       string
+	IRCTLS            bool
+	IRCSASLUser       string
+	IRCSASLPassword   string
+	IRCTrustNicks     bool
+	MatrixHomeserver  string
+	MatrixToken       string
 	SlackToken        string
+	SlackAppToken     string
+	SlackMode         string
+	SlackSigningKey   string
+	SlackEventsPath   string
+	SlackCommandsPath string
+	SlackInteractPath string
+	ConfirmTimeout    time.Duration
 	ExecutionMode     string
 	AgentOf           string
 	GRPCServerAddress string
@@ -64,8 +94,31 @@ func parseArgs() args {
 	address := flag.String("http-address", ":9696", "http endpoint in which to listen")
 	apiPath := flag.String("api-path", "/message", "api path in to listen for api calls")
 	metricsPath := flag.String("metrics-path", "/metrics", "path to in which to expose prometheus metrics")
+	chatBackend := flag.String("chat-backend", "slack", "comma separated chat backends to connect to: slack (default), mattermost, irc or matrix. The first one is used to resolve api tokens")
+	consoleMode := flag.Bool("console", false, "read commands from stdin and reply on stdout instead of connecting to a chat backend")
+	consoleUser := flag.String("console-user", os.Getenv("USER"), "username used for the commands typed in the console")
+	consoleChannel := flag.String("console-channel", "console", "channel used for the commands typed in the console, when empty commands are sent as IM")
+	mattermostURL := flag.String("mattermost-url", "", "mattermost server url, used with the mattermost chat backend")
+	mattermostToken := flag.String("mattermost-token", os.Getenv("MATTERMOST_TOKEN"), "mattermost bot token, by default loaded from the MATTERMOST_TOKEN environment variable")
+	mattermostTeam := flag.String("mattermost-team", "", "mattermost team name used to resolve channel links")
+	ircServer := flag.String("irc-server", "", "irc server address as host:port, used with the irc chat backend")
+	ircNick := flag.String("irc-nick", "meeseeks", "irc nick to register with")
+	ircChannels := flag.String("irc-channels", "", "comma separated list of irc channels to join")
+	ircTLS := flag.Bool("irc-tls", false, "connect to the irc server using tls")
+	ircSASLUser := flag.String("irc-sasl-user", "", "irc account used to authenticate with sasl")
+	ircSASLPassword := flag.String("irc-sasl-password", os.Getenv("IRC_SASL_PASSWORD"), "irc sasl password, by default loaded from the IRC_SASL_PASSWORD environment variable")
+	ircTrustNicks := flag.Bool("irc-trust-nicks", false, "accept irc commands from nicks that are not identified with an account, only safe where nicks can't be impersonated")
+	matrixHomeserver := flag.String("matrix-homeserver", "", "matrix homeserver url, used with the matrix chat backend")
+	matrixToken := flag.String("matrix-token", os.Getenv("MATRIX_TOKEN"), "matrix access token, by default loaded from the MATRIX_TOKEN environment variable")
 	slackStealth := flag.Bool("stealth", false, "Enable slack stealth mode")
 	slackToken := flag.String("slack-token", os.Getenv("SLACK_TOKEN"), "slack token, by default loaded from the SLACK_TOKEN environment variable")
+	slackAppToken := flag.String("slack-app-token", os.Getenv("SLACK_APP_TOKEN"), "slack app level token used in socket mode, by default loaded from the SLACK_APP_TOKEN environment variable")
+	slackMode := flag.String("slack-mode", slack.ModeRTM, "slack connection mode, rtm (default), socket or events")
+	slackSigningKey := flag.String("slack-signing-secret", os.Getenv("SLACK_SIGNING_SECRET"), "slack signing secret used to verify http requests, by default loaded from the SLACK_SIGNING_SECRET environment variable")
+	slackEventsPath := flag.String("slack-events-path", "/slack/events", "path in which to listen for slack events api calls when running in events mode")
+	slackCommandsPath := flag.String("slack-commands-path", "/slack/commands", "path in which to listen for slack slash commands, enabled when a signing secret is set")
+	slackInteractPath := flag.String("slack-interactive-path", "/slack/interactive", "path in which to listen for slack button clicks, enabled when a signing secret is set")
+	confirmTimeout := flag.Duration("confirmation-timeout", executor.DefaultConfirmationTimeout, "how long commands that require a confirmation wait for it before expiring")
 	agentOf := flag.String("agent-of", "", "remote server to connect to, enables agent mode")
 	grpcServerAddress := flag.String("grpc-address", ":9697", "grpc server endpoint, used to connect remote agents")
 	grpcServerEnabled := flag.Bool("with-grpc-server", false, "enable grpc remote server to connect to")
@@ -91,7 +144,30 @@ func parseArgs() args {
 		DebugMode:         *debugMode,
 		StealthMode:       *slackStealth,
 		DebugSlack:        *debugSlack,
+		ChatBackend:       *chatBackend,
+		Console:           *consoleMode,
+		ConsoleUser:       *consoleUser,
+		ConsoleChannel:    *consoleChannel,
+		MattermostURL:     *mattermostURL,
+		MattermostToken:   *mattermostToken,
+		MattermostTeam:    *mattermostTeam,
+		IRCServer:         *ircServer,
+		IRCNick:           *ircNick,
+		IRCChannels:       *ircChannels,
+		IRCTLS:            *ircTLS,
+		IRCSASLUser:       *ircSASLUser,
+		IRCSASLPassword:   *ircSASLPassword,
+		IRCTrustNicks:     *ircTrustNicks,
+		MatrixHomeserver:  *matrixHomeserver,
+		MatrixToken:       *matrixToken,
 		SlackToken:        *slackToken,
+		SlackAppToken:     *slackAppToken,
+		SlackMode:         *slackMode,
+		SlackSigningKey:   *slackSigningKey,
+		SlackEventsPath:   *slackEventsPath,
+		SlackCommandsPath: *slackCommandsPath,
+		SlackInteractPath: *slackInteractPath,
+		ConfirmTimeout:    *confirmTimeout,
 		Address:           *address,
 		APIPath:           *apiPath,
 		MetricsPath:       *metricsPath,
@@ -112,12 +188,18 @@ func launch(args args) (shutdownFunc func(), reloadFunc func(), err error) {
 	must("failed to load configuration file: %s", err)
 	must("could not load configuration: %s", config.LoadConfiguration(cnf))
 
+	// The executor is built once, so the pool can't change on reload
+	pool := cnf.Pool
+
 	reloadFunc = func() {
 		cnf, err := config.ReadFile(args.ConfigFile)
 		if err != nil {
 			logrus.Warnf("failed to read configuration file %s: %s", args.ConfigFile, err)
 			return
 		}
+		if cnf.Pool != pool {
+			logrus.Warnf("pool size changed from %d to %d, restart to apply it", pool, cnf.Pool)
+		}
 		if err = config.LoadConfiguration(cnf); err != nil {
 			logrus.Warnf("failed to reload configuration %s: %s", args.ConfigFile, err)
 		} else {
@@ -135,21 +217,41 @@ func launch(args args) (shutdownFunc func(), reloadFunc func(), err error) {
 		remoteServer, err := startRemoteServer(args)
 		must("could not start GRPC server: %s", err)
 
-		slackClient := connectToSlack(args)
-		apiService := startAPI(slackClient, args)
+		backends, err := connectToChats(args)
+		must("could not connect to chat: %s", err)
+
+		// The first backend resolves api tokens and replies to requests without origin
+		apiService := startAPI(backends[0].client, args)
 
 		exc := executor.New(executor.Args{
-			ConcurrentTaskCount: 20,
+			ConcurrentTaskCount: cnf.Pool,
 			WithBuiltinCommands: true,
-			ChatClient:          slackClient,
+			ChatClient:          backends[0].client,
+			ConfirmationTimeout: args.ConfirmTimeout,
 		})
 
-		exc.ListenTo(slackClient)
+		for _, b := range backends {
+			exc.RegisterChatClient(b.origin, b.client)
+			apiService.RegisterEnricher(b.origin, b.client)
+			for _, l := range b.listeners {
+				exc.ListenTo(l)
+			}
+			if b.origin == slack.Origin && args.SlackSigningKey != "" {
+				logrus.Debugf("Listening for slack interactions on %s", args.SlackInteractPath)
+				slack.NewInteractionHandler(nethttp.DefaultServeMux, args.SlackInteractPath, args.SlackSigningKey, exc.Confirm)
+			}
+		}
 		exc.ListenTo(apiService)
+		sched := scheduler.New(backends[0].client)
+		for _, b := range backends {
+			sched.RegisterEnricher(b.origin, b.client)
+		}
+		exc.ListenTo(sched)
 
 		go exc.Run()
 
 		return func() {
+			sched.Shutdown()
 			exc.Shutdown()
 			httpServer.Shutdown()
 			remoteServer.Shutdown()
@@ -197,13 +299,143 @@ func configureLogger(args args) {
 	}
 }
 
+// chatClient is what a chat backend provides to reply and enrich requests
+type chatClient interface {
+	executor.ChatClient
+	api.Enricher
+}
+
+// chatBackend is a connected chat client along with the listeners that receive its requests
+type chatBackend struct {
+	origin    string
+	client    chatClient
+	listeners []executor.Listener
+}
+
+func connectToChats(args args) ([]chatBackend, error) {
+	if args.Console {
+		consoleClient := startConsole(args)
+		return []chatBackend{{console.Origin, consoleClient, []executor.Listener{consoleClient}}}, nil
+	}
+
+	backends := make([]chatBackend, 0)
+	for _, name := range strings.Split(args.ChatBackend, ",") {
+		client, listeners, err := connectToChat(strings.TrimSpace(name), args)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, chatBackend{strings.TrimSpace(name), client, listeners})
+	}
+	return backends, nil
+}
+
+func connectToChat(backend string, args args) (chatClient, []executor.Listener, error) {
+	switch backend {
+	case slack.Origin:
+		slackClient := connectToSlack(args)
+		listeners := []executor.Listener{slackListener(slackClient, args)}
+
+		if args.SlackSigningKey != "" {
+			logrus.Debugf("Listening for slack slash commands on %s", args.SlackCommandsPath)
+			listeners = append(listeners,
+				slack.NewSlashCommandListener(nethttp.DefaultServeMux, slackClient, args.SlackCommandsPath, args.SlackSigningKey))
+		}
+		return slackClient, listeners, nil
+
+	case mattermost.Origin:
+		mattermostClient := connectToMattermost(args)
+		return mattermostClient, []executor.Listener{mattermostClient}, nil
+
+	case irc.Origin:
+		ircClient := connectToIRC(args)
+		return ircClient, []executor.Listener{ircClient}, nil
+
+	case matrix.Origin:
+		matrixClient := connectToMatrix(args)
+		return matrixClient, []executor.Listener{matrixClient}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("Invalid chat backend %s, Valid chat backends are slack (default), mattermost, irc, and matrix",
+			backend)
+	}
+}
+
+func startConsole(args args) *console.Client {
+	logrus.Infof("Starting console as %s, type commands and press enter, Ctrl-D to exit", args.ConsoleUser)
+	return console.New(os.Stdin, os.Stdout, console.Opts{
+		Username: args.ConsoleUser,
+		Channel:  args.ConsoleChannel,
+		OnClose: func() {
+			// Shut down gracefully as if we got an interrupt
+			syscall.Kill(os.Getpid(), syscall.SIGINT)
+		},
+	})
+}
+
+func connectToMattermost(args args) *mattermost.Client {
+	logrus.Debug("Connecting to mattermost")
+	mattermostClient, err := mattermost.Connect(
+		mattermost.ConnectionOpts{
+			URL:   args.MattermostURL,
+			Token: args.MattermostToken,
+			Team:  args.MattermostTeam,
+		})
+
+	must("Could not connect to mattermost: %s", err)
+	logrus.Info("Connected to mattermost")
+
+	return mattermostClient
+}
+
+func connectToIRC(args args) *irc.Client {
+	channels := make([]string, 0)
+	for _, channel := range strings.Split(args.IRCChannels, ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			channels = append(channels, channel)
+		}
+	}
+
+	logrus.Debug("Connecting to irc")
+	ircClient, err := irc.Connect(
+		irc.ConnectionOpts{
+			Server:       args.IRCServer,
+			Nick:         args.IRCNick,
+			Channels:     channels,
+			TLS:          args.IRCTLS,
+			SASLUser:     args.IRCSASLUser,
+			SASLPassword: args.IRCSASLPassword,
+			TrustNicks:   args.IRCTrustNicks,
+		})
+
+	must("Could not connect to irc: %s", err)
+	logrus.Info("Connected to irc")
+
+	return ircClient
+}
+
+func connectToMatrix(args args) *matrix.Client {
+	logrus.Debug("Connecting to matrix")
+	matrixClient, err := matrix.Connect(
+		matrix.ConnectionOpts{
+			Homeserver: args.MatrixHomeserver,
+			Token:      args.MatrixToken,
+		})
+
+	must("Could not connect to matrix: %s", err)
+	logrus.Info("Connected to matrix")
+
+	return matrixClient
+}
+
 func connectToSlack(args args) *slack.Client {
 	logrus.Debug("Connecting to slack")
 	slackClient, err := slack.Connect(
 		slack.ConnectionOpts{
-			Debug:   args.DebugSlack,
-			Token:   args.SlackToken,
-			Stealth: args.StealthMode,
+			Debug:    args.DebugSlack,
+			Token:    args.SlackToken,
+			AppToken: args.SlackAppToken,
+			Mode:     args.SlackMode,
+			Stealth:  args.StealthMode,
 		})
 
 	must("Could not connect to slack: %s", err)
@@ -212,6 +444,14 @@ func connectToSlack(args args) *slack.Client {
 	return slackClient
 }
 
+func slackListener(client *slack.Client, args args) executor.Listener {
+	if args.SlackMode != slack.ModeEvents {
+		return client
+	}
+	logrus.Debugf("Listening for slack events on %s", args.SlackEventsPath)
+	return slack.NewEventsListener(nethttp.DefaultServeMux, client, args.SlackEventsPath, args.SlackSigningKey)
+}
+
 func listenHTTP(args args) *http.Server {
 	httpServer := http.New(args.Address)
 	metrics.RegisterPath(args.MetricsPath)
@@ -225,7 +465,7 @@ func listenHTTP(args args) *http.Server {
 	return httpServer
 }
 
-func startAPI(client *slack.Client, args args) *api.Service {
+func startAPI(client api.Enricher, args args) *api.Service {
 	logrus.Debug("Starting api server")
 	return api.New(client, args.APIPath)
 }
@@ -0,0 +1,70 @@
+package matrix
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	inlineCode = regexp.MustCompile("`([^`]+)`")
+	bold       = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	slackBold  = regexp.MustCompile(`(^|\W)\*([^*\s](?:[^*]*[^*\s])?)\*(\W|$)`)
+	italic     = regexp.MustCompile(`(^|\W)_([^_]+)_(\W|$)`)
+	link       = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+)
+
+// renderHTML renders the small subset of markdown used in reply templates as html:
+// code blocks, inline code, bold (both **bold** and *bold*), italics and links
+func renderHTML(text string) string {
+	b := strings.Builder{}
+	inCodeBlock := false
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inCodeBlock {
+				b.WriteString("</code></pre>")
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		if inCodeBlock {
+			b.WriteString(html.EscapeString(line))
+			b.WriteString("\n")
+			continue
+		}
+
+		b.WriteString(renderInline(line))
+		if i < len(lines)-1 && !strings.HasPrefix(strings.TrimSpace(lines[i+1]), "```") {
+			b.WriteString("<br/>")
+		}
+	}
+	if inCodeBlock {
+		b.WriteString("</code></pre>")
+	}
+	return b.String()
+}
+
+func renderInline(line string) string {
+	parts := inlineCode.Split(line, -1)
+	codes := inlineCode.FindAllStringSubmatch(line, -1)
+
+	b := strings.Builder{}
+	for i, part := range parts {
+		part = html.EscapeString(part)
+		part = link.ReplaceAllString(part, `<a href="$2">$1</a>`)
+		part = bold.ReplaceAllString(part, "<strong>$1</strong>")
+		part = slackBold.ReplaceAllString(part, "$1<strong>$2</strong>$3")
+		part = italic.ReplaceAllString(part, "$1<em>$2</em>$3")
+		b.WriteString(part)
+
+		if i < len(codes) {
+			b.WriteString("<code>" + html.EscapeString(codes[i][1]) + "</code>")
+		}
+	}
+	return b.String()
+}
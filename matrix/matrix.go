@@ -0,0 +1,470 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/parser"
+
+	"github.com/jpillora/backoff"
+	"github.com/sirupsen/logrus"
+)
+
+var errIgnoredMessage = fmt.Errorf("ignore this message")
+var errNoCommandToRun = fmt.Errorf("no command to run")
+
+const (
+	textStyle     = "text"
+	nullStyle     = "null"
+	nilStyle      = "nil"
+	disabledStyle = "disabled"
+)
+
+// Origin identifies the requests that come from matrix
+const Origin = "matrix"
+
+const (
+	apiPrefix   = "/_matrix/client/r0"
+	syncTimeout = 30 * time.Second
+)
+
+// Matrix does not understand slack named colors, so they are mapped to hex codes
+var namedColors = map[string]string{
+	formatter.DefaultSuccessColorMessage: "#36a64f",
+	formatter.DefaultWarningColorMessage: "#daa038",
+	formatter.DefaultErrColorMessage:     "#d00000",
+}
+
+// ConnectionOpts groups all the connection options in a single struct
+type ConnectionOpts struct {
+	Homeserver string
+	Token      string
+}
+
+// Client is a matrix chat client
+type Client struct {
+	apiURL     string
+	token      string
+	userID     string
+	httpClient *http.Client
+
+	txnID uint64
+
+	lock        sync.RWMutex
+	directRooms map[string]bool
+	roomAliases map[string]string
+
+	matcher messageMatcher
+}
+
+// Connect builds a new chat client
+func Connect(opts ConnectionOpts) (*Client, error) {
+	if opts.Homeserver == "" {
+		return nil, fmt.Errorf("could not connect to matrix: no homeserver url")
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("could not connect to matrix: MATRIX_TOKEN env var is empty")
+	}
+
+	c := &Client{
+		apiURL:      strings.TrimSuffix(opts.Homeserver, "/") + apiPrefix,
+		token:       opts.Token,
+		httpClient:  &http.Client{Timeout: syncTimeout + 30*time.Second},
+		directRooms: make(map[string]bool),
+		roomAliases: make(map[string]string),
+	}
+
+	whoami := struct {
+		UserID string `json:"user_id"`
+	}{}
+	if err := c.get("/account/whoami", &whoami); err != nil {
+		return nil, fmt.Errorf("could not connect to matrix: %s", err)
+	}
+	c.userID = whoami.UserID
+
+	direct := directContent{}
+	if err := c.get(fmt.Sprintf("/user/%s/account_data/m.direct", url.PathEscape(c.userID)), &direct); err != nil {
+		logrus.Debugf("could not load matrix direct rooms, assuming there are none: %s", err)
+	}
+	c.setDirectRooms(direct)
+
+	c.matcher = newMessageMatcher(c.userID)
+	return c, nil
+}
+
+// directContent is the content of the m.direct account data event: rooms by user ID
+type directContent map[string][]string
+
+func (c *Client) setDirectRooms(direct directContent) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.directRooms = make(map[string]bool)
+	for _, rooms := range direct {
+		for _, room := range rooms {
+			c.directRooms[room] = true
+		}
+	}
+}
+
+// ParseChannelLink implements the api.Enricher interface
+func (c *Client) ParseChannelLink(channelLink string) (string, error) {
+	switch {
+	case strings.HasPrefix(channelLink, "!"):
+		return channelLink, nil
+	case strings.HasPrefix(channelLink, "#"):
+		room := struct {
+			RoomID string `json:"room_id"`
+		}{}
+		if err := c.get("/directory/room/"+url.PathEscape(channelLink), &room); err != nil {
+			return "", fmt.Errorf("could not find room %s: %s", channelLink, err)
+		}
+		return room.RoomID, nil
+	}
+	return "", fmt.Errorf("invalid channel link: %s", channelLink)
+}
+
+// ParseUserLink implements the api.Enricher interface
+func (c *Client) ParseUserLink(userLink string) (string, error) {
+	if !strings.HasPrefix(userLink, "@") || !strings.Contains(userLink, ":") {
+		return "", fmt.Errorf("invalid user link: %s", userLink)
+	}
+	return userLink, nil
+}
+
+// GetUsername implements the api.Enricher interface
+//
+// The full user ID is used as the username because the localpart alone is not
+// unique across homeservers
+func (c *Client) GetUsername(userID string) string {
+	return userID
+}
+
+// GetUserLink implements the api.Enricher interface
+func (c *Client) GetUserLink(userID string) string {
+	return userID
+}
+
+// GetChannel implements the api.Enricher interface
+func (c *Client) GetChannel(channelID string) string {
+	if c.IsIM(channelID) {
+		return "IM"
+	}
+	return c.roomAlias(channelID)
+}
+
+// GetChannelLink implements the api.Enricher interface
+func (c *Client) GetChannelLink(channelID string) string {
+	return c.roomAlias(channelID)
+}
+
+// IsIM implements the api.Enricher interface
+func (c *Client) IsIM(channelID string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.directRooms[channelID]
+}
+
+// roomAlias returns the canonical alias of a room, or the room ID if it has none
+func (c *Client) roomAlias(roomID string) string {
+	c.lock.RLock()
+	alias, ok := c.roomAliases[roomID]
+	c.lock.RUnlock()
+	if ok {
+		return alias
+	}
+
+	content := struct {
+		Alias string `json:"alias"`
+	}{}
+	if err := c.get(fmt.Sprintf("/rooms/%s/state/m.room.canonical_alias", url.PathEscape(roomID)), &content); err != nil {
+		logrus.Debugf("could not find canonical alias for room %s: %s", roomID, err)
+	}
+	alias = content.Alias
+	if alias == "" {
+		alias = roomID
+	}
+
+	c.lock.Lock()
+	c.roomAliases[roomID] = alias
+	c.lock.Unlock()
+
+	return alias
+}
+
+type syncResponse struct {
+	NextBatch   string `json:"next_batch"`
+	AccountData struct {
+		Events []event `json:"events"`
+	} `json:"account_data"`
+	Rooms struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+type event struct {
+	Type    string          `json:"type"`
+	Sender  string          `json:"sender"`
+	EventID string          `json:"event_id"`
+	Content json.RawMessage `json:"content"`
+}
+
+type messageContent struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+// Listen runs the sync loop and sends the matching messages through the channel as requests
+func (c *Client) Listen(ch chan<- meeseeks.Request) {
+	b := &backoff.Backoff{
+		Min:    100 * time.Millisecond,
+		Max:    10 * time.Second,
+		Factor: 2,
+		Jitter: true,
+	}
+
+	logrus.Infof("Listening Matrix Messages")
+	since := ""
+	for {
+		resp, err := c.sync(since)
+		if err != nil {
+			if b.Attempt() > 10 {
+				logrus.Errorf("failed to sync with matrix homeserver: %s. Quitting", err)
+				break
+			}
+			logrus.Warnf("failed to sync with matrix homeserver: %s. Retrying", err)
+			time.Sleep(b.Duration())
+			continue
+		}
+		b.Reset()
+
+		// The first sync returns the latest history, which has already been processed
+		c.handleSync(resp, since != "", ch)
+		since = resp.NextBatch
+	}
+	logrus.Infof("Stopped listening to messages")
+}
+
+func (c *Client) sync(since string) (syncResponse, error) {
+	params := url.Values{}
+	params.Set("timeout", fmt.Sprintf("%d", syncTimeout/time.Millisecond))
+	if since != "" {
+		params.Set("since", since)
+	}
+
+	resp := syncResponse{}
+	err := c.get("/sync?"+params.Encode(), &resp)
+	return resp, err
+}
+
+func (c *Client) handleSync(resp syncResponse, handleMessages bool, ch chan<- meeseeks.Request) {
+	for _, e := range resp.AccountData.Events {
+		if e.Type != "m.direct" {
+			continue
+		}
+		direct := directContent{}
+		if err := json.Unmarshal(e.Content, &direct); err != nil {
+			logrus.Errorf("could not parse m.direct account data: %s", err)
+			continue
+		}
+		c.setDirectRooms(direct)
+	}
+
+	for roomID := range resp.Rooms.Invite {
+		logrus.Infof("Joining matrix room %s after being invited", roomID)
+		if err := c.do(http.MethodPost, fmt.Sprintf("/rooms/%s/join", url.PathEscape(roomID)), struct{}{}, nil); err != nil {
+			logrus.Errorf("could not join matrix room %s: %s", roomID, err)
+		}
+	}
+
+	if !handleMessages {
+		return
+	}
+
+	for roomID, room := range resp.Rooms.Join {
+		for _, e := range room.Timeline.Events {
+			if e.Type != "m.room.message" {
+				continue
+			}
+			content := messageContent{}
+			if err := json.Unmarshal(e.Content, &content); err != nil {
+				logrus.Errorf("could not parse message %s: %s", e.Content, err)
+				continue
+			}
+			if content.MsgType != "m.text" {
+				continue
+			}
+
+			isIM := c.IsIM(roomID)
+			text, err := c.matcher.Matches(e.Sender, content.Body, isIM)
+			if err != nil {
+				continue
+			}
+
+			r, err := c.requestFromMessage(text, e.Sender, roomID, isIM)
+			if err != nil {
+				logrus.Debugf("Failed to parse message '%s' as a command: %s", text, err)
+				c.Reply(formatter.FailureReply(meeseeks.Request{ChannelID: roomID}, err))
+				continue
+			}
+
+			logrus.Debugf("Sending Matrix event %s to messages channel", e.EventID)
+			ch <- r
+		}
+	}
+}
+
+func (c *Client) requestFromMessage(text, userID, roomID string, isIM bool) (meeseeks.Request, error) {
+	args, err := parser.Parse(text)
+	logrus.Debugf("Command '%s' parsed as %#v", text, args)
+
+	if err != nil {
+		return meeseeks.Request{}, err
+	}
+
+	if len(args) == 0 {
+		return meeseeks.Request{}, errNoCommandToRun
+	}
+
+	return meeseeks.Request{
+		Command:     args[0],
+		Args:        args[1:],
+		Username:    c.GetUsername(userID),
+		UserID:      userID,
+		UserLink:    c.GetUserLink(userID),
+		Channel:     c.GetChannel(roomID),
+		ChannelID:   roomID,
+		ChannelLink: c.GetChannelLink(roomID),
+		IsIM:        isIM,
+		Origin:      Origin,
+	}, nil
+}
+
+// Reply replies to the user sending an html formatted notice
+func (c *Client) Reply(r formatter.Reply) {
+	style := r.ReplyStyle()
+	switch style {
+	case nullStyle, disabledStyle, nilStyle:
+		logrus.Debugf("Ignoring reply %#v, the null formatter is like this", r)
+		return
+	}
+
+	content, err := r.Render()
+	if err != nil {
+		logrus.Errorf("failed to render reply %#v: %s", r, err)
+		return
+	}
+
+	formatted := renderHTML(content)
+	if style != textStyle && r.Color() != "" {
+		formatted = fmt.Sprintf(`<font color="%s">%s</font>`, mapColor(r.Color()), formatted)
+	}
+
+	path := fmt.Sprintf("/rooms/%s/send/m.room.message/%s", url.PathEscape(r.ChannelID()), c.nextTxnID())
+	logrus.Debugf("Replying in Matrix room %s with %s", r.ChannelID(), formatted)
+	if err := c.do(http.MethodPut, path, messageContent{
+		MsgType:       "m.notice",
+		Body:          content,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted,
+	}, nil); err != nil {
+		logrus.Errorf("failed post message %s on %s: %s", content, r.ChannelID(), err)
+	}
+}
+
+func (c *Client) nextTxnID() string {
+	return fmt.Sprintf("meeseeks-%d-%d", time.Now().UnixNano(), atomic.AddUint64(&c.txnID, 1))
+}
+
+func mapColor(color string) string {
+	if hex, ok := namedColors[color]; ok {
+		return hex
+	}
+	return color
+}
+
+func (c *Client) get(path string, out interface{}) error {
+	return c.do(http.MethodGet, path, nil, out)
+}
+
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("could not marshal request payload: %s", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.apiURL+path, body)
+	if err != nil {
+		return fmt.Errorf("could not create request: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request %s %s failed: %s", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("request %s %s failed with status %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type messageMatcher struct {
+	botID         string
+	prefixMatches []string
+}
+
+func newMessageMatcher(botID string) messageMatcher {
+	localpart := strings.SplitN(strings.TrimPrefix(botID, "@"), ":", 2)[0]
+	return messageMatcher{
+		botID:         botID,
+		prefixMatches: []string{botID + ":", botID + " ", localpart + ":"},
+	}
+}
+
+// Matches returns the text of the message that is a command for this bot
+func (m messageMatcher) Matches(sender, body string, isIM bool) (string, error) {
+	if sender == m.botID {
+		logrus.Debug("It's myself, ignoring message")
+		return "", errIgnoredMessage
+	}
+	if isIM {
+		logrus.Debugf("Message from %s is in a direct room, responding...", sender)
+		return body, nil
+	}
+	for _, match := range m.prefixMatches {
+		if strings.HasPrefix(body, match) {
+			logrus.Debugf("Message '%s' matches prefix, responding...", body)
+			return strings.TrimSpace(body[len(match):]), nil
+		}
+	}
+	return "", errIgnoredMessage
+}
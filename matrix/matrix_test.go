@@ -0,0 +1,211 @@
+package matrix_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/matrix"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/template"
+)
+
+func textMessage(sender, body string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "m.room.message",
+		"sender":   sender,
+		"event_id": "$" + body,
+		"content": map[string]string{
+			"msgtype": "m.text",
+			"body":    body,
+		},
+	}
+}
+
+func syncResponse(nextBatch string, rooms map[string][]interface{}, invites ...string) map[string]interface{} {
+	join := map[string]interface{}{}
+	for room, events := range rooms {
+		join[room] = map[string]interface{}{
+			"timeline": map[string]interface{}{"events": events},
+		}
+	}
+	invite := map[string]interface{}{}
+	for _, room := range invites {
+		invite[room] = map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"next_batch": nextBatch,
+		"rooms": map[string]interface{}{
+			"join":   join,
+			"invite": invite,
+		},
+	}
+}
+
+type fakeHomeserver struct {
+	*httptest.Server
+	notices chan map[string]interface{}
+	joined  chan string
+}
+
+func newFakeHomeserver(t *testing.T) fakeHomeserver {
+	hs := fakeHomeserver{
+		notices: make(chan map[string]interface{}, 10),
+		joined:  make(chan string, 10),
+	}
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		mocks.Must(t, "could not encode response", json.NewEncoder(w).Encode(v))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_matrix/client/r0/account/whoami", func(w http.ResponseWriter, r *http.Request) {
+		mocks.AssertEquals(t, "Bearer matrix-token", r.Header.Get("Authorization"))
+		writeJSON(w, map[string]string{"user_id": "@meeseeks:test"})
+	})
+	mux.HandleFunc("/_matrix/client/r0/user/@meeseeks:test/account_data/m.direct", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string][]string{"@someone:test": {"!dm:test"}})
+	})
+	mux.HandleFunc("/_matrix/client/r0/directory/room/#general:test", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"room_id": "!room:test"})
+	})
+	mux.HandleFunc("/_matrix/client/r0/rooms/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/_matrix/client/r0/rooms/!room:test/state/m.room.canonical_alias":
+			writeJSON(w, map[string]string{"alias": "#general:test"})
+		case strings.HasSuffix(r.URL.Path, "/join"):
+			hs.joined <- strings.Split(r.URL.Path, "/")[5]
+			writeJSON(w, map[string]string{})
+		case strings.Contains(r.URL.Path, "/send/m.room.message/"):
+			mocks.AssertEquals(t, http.MethodPut, r.Method)
+			notice := map[string]interface{}{}
+			mocks.Must(t, "could not decode notice", json.NewDecoder(r.Body).Decode(&notice))
+			notice["room_id"] = strings.Split(r.URL.Path, "/")[5]
+			hs.notices <- notice
+			writeJSON(w, map[string]string{"event_id": "$reply"})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	mux.HandleFunc("/_matrix/client/r0/sync", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("since") {
+		case "":
+			writeJSON(w, syncResponse("s1", map[string][]interface{}{
+				"!room:test": {textMessage("@someone:test", "meeseeks: echo old history")},
+			}, "!new:test"))
+		case "s1":
+			writeJSON(w, syncResponse("s2", map[string][]interface{}{
+				"!room:test": {
+					textMessage("@meeseeks:test", "meeseeks: talking to myself"),
+					textMessage("@someone:test", "not for the bot"),
+					textMessage("@someone:test", "meeseeks: echo hello"),
+				},
+			}))
+		case "s2":
+			writeJSON(w, syncResponse("s3", map[string][]interface{}{
+				"!dm:test": {textMessage("@someone:test", "echo in private")},
+			}))
+		default:
+			time.Sleep(100 * time.Millisecond)
+			writeJSON(w, syncResponse(r.URL.Query().Get("since"), nil))
+		}
+	})
+
+	hs.Server = httptest.NewServer(mux)
+	return hs
+}
+
+func TestMatrixClient(t *testing.T) {
+	hs := newFakeHomeserver(t)
+	defer hs.Close()
+
+	c, err := matrix.Connect(matrix.ConnectionOpts{
+		Homeserver: hs.URL,
+		Token:      "matrix-token",
+	})
+	mocks.Must(t, "could not connect to fake homeserver", err)
+
+	t.Run("listen", func(t *testing.T) {
+		ch := make(chan meeseeks.Request)
+		go c.Listen(ch)
+
+		for _, expected := range []meeseeks.Request{
+			{
+				Command:     "echo",
+				Args:        []string{"hello"},
+				Username:    "@someone:test",
+				UserID:      "@someone:test",
+				UserLink:    "@someone:test",
+				Channel:     "#general:test",
+				ChannelID:   "!room:test",
+				ChannelLink: "#general:test",
+				Origin:      matrix.Origin,
+			},
+			{
+				Command:     "echo",
+				Args:        []string{"in", "private"},
+				Username:    "@someone:test",
+				UserID:      "@someone:test",
+				UserLink:    "@someone:test",
+				Channel:     "IM",
+				ChannelID:   "!dm:test",
+				ChannelLink: "!dm:test",
+				IsIM:        true,
+				Origin:      matrix.Origin,
+			},
+		} {
+			select {
+			case r := <-ch:
+				mocks.AssertEquals(t, expected, r)
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for a request")
+			}
+		}
+		mocks.AssertEquals(t, "!new:test", <-hs.joined)
+	})
+
+	t.Run("enricher", func(t *testing.T) {
+		roomID, err := c.ParseChannelLink("#general:test")
+		mocks.Must(t, "could not parse channel link", err)
+		mocks.AssertEquals(t, "!room:test", roomID)
+
+		userID, err := c.ParseUserLink("@someone:test")
+		mocks.Must(t, "could not parse user link", err)
+		mocks.AssertEquals(t, "@someone:test", userID)
+
+		_, err = c.ParseUserLink("someone")
+		mocks.AssertEquals(t, "invalid user link: someone", err.Error())
+	})
+
+	t.Run("reply", func(t *testing.T) {
+		formatter.Configure(formatter.FormatConfig{
+			Templates: map[string]string{
+				template.Success: "**{{ .command }}** done\n```\n{{ .output }}```",
+				template.Failure: "*{{ .command }}* failed: {{ .error }}",
+			},
+			ReplyStyle: map[string]string{
+				template.Success: "text",
+			},
+			Colors: formatter.MessageColors{
+				Error: formatter.DefaultErrColorMessage,
+			},
+		})
+
+		c.Reply(formatter.SuccessReply(meeseeks.Request{Command: "echo", ChannelID: "!room:test"}).WithOutput("<hello>\n"))
+		mocks.AssertEquals(t, map[string]interface{}{
+			"room_id":        "!room:test",
+			"msgtype":        "m.notice",
+			"body":           "**echo** done\n```\n<hello>\n```",
+			"format":         "org.matrix.custom.html",
+			"formatted_body": "<strong>echo</strong> done<pre><code>&lt;hello&gt;\n</code></pre>",
+		}, <-hs.notices)
+
+		c.Reply(formatter.FailureReply(meeseeks.Request{Command: "echo", ChannelID: "!room:test"}, fmt.Errorf("2 * 3 is not 7")))
+		mocks.AssertEquals(t, `<font color="#d00000"><strong>echo</strong> failed: 2 * 3 is not 7</font>`, (<-hs.notices)["formatted_body"])
+	})
+}
@@ -0,0 +1,448 @@
+package mattermost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/parser"
+
+	"github.com/gorilla/websocket"
+	"github.com/jpillora/backoff"
+	"github.com/sirupsen/logrus"
+)
+
+var errIgnoredMessage = fmt.Errorf("ignore this message")
+var errNoCommandToRun = fmt.Errorf("no command to run")
+
+const (
+	textStyle     = "text"
+	nullStyle     = "null"
+	nilStyle      = "nil"
+	disabledStyle = "disabled"
+)
+
+// Origin identifies the requests that come from mattermost
+const Origin = "mattermost"
+
+// Mattermost channel types
+const (
+	directChannel = "D"
+)
+
+// Mattermost does not understand slack named colors, so they are mapped to hex codes
+var namedColors = map[string]string{
+	formatter.DefaultSuccessColorMessage: "#36a64f",
+	formatter.DefaultWarningColorMessage: "#daa038",
+	formatter.DefaultErrColorMessage:     "#d00000",
+}
+
+// ConnectionOpts groups all the connection options in a single struct
+type ConnectionOpts struct {
+	URL   string
+	Token string
+	Team  string
+}
+
+// Client is a mattermost chat client
+type Client struct {
+	apiURL     string
+	token      string
+	teamID     string
+	httpClient *http.Client
+	dialer     *websocket.Dialer
+
+	matcher messageMatcher
+}
+
+// Connect builds a new chat client
+func Connect(opts ConnectionOpts) (*Client, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("could not connect to mattermost: no server url")
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("could not connect to mattermost: MATTERMOST_TOKEN env var is empty")
+	}
+
+	c := &Client{
+		apiURL:     strings.TrimSuffix(opts.URL, "/") + "/api/v4",
+		token:      opts.Token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		dialer:     websocket.DefaultDialer,
+	}
+
+	me := user{}
+	if err := c.get("/users/me", &me); err != nil {
+		return nil, fmt.Errorf("could not connect to mattermost: %s", err)
+	}
+
+	if opts.Team != "" {
+		t := team{}
+		if err := c.get("/teams/name/"+url.PathEscape(opts.Team), &t); err != nil {
+			return nil, fmt.Errorf("could not find mattermost team %s: %s", opts.Team, err)
+		}
+		c.teamID = t.ID
+	}
+
+	c.matcher = newMessageMatcher(me.ID, me.Username)
+	return c, nil
+}
+
+type user struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type team struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type channel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ParseChannelLink implements the api.Enricher interface
+func (c *Client) ParseChannelLink(channelLink string) (string, error) {
+	if !strings.HasPrefix(channelLink, "~") {
+		return "", fmt.Errorf("invalid channel link: %s", channelLink)
+	}
+	if c.teamID == "" {
+		return "", fmt.Errorf("can't parse channel link %s without a configured team", channelLink)
+	}
+	ch := channel{}
+	if err := c.get(fmt.Sprintf("/teams/%s/channels/name/%s", c.teamID,
+		url.PathEscape(channelLink[1:])), &ch); err != nil {
+		return "", fmt.Errorf("could not find channel %s: %s", channelLink, err)
+	}
+	return ch.ID, nil
+}
+
+// ParseUserLink implements the api.Enricher interface
+func (c *Client) ParseUserLink(userLink string) (string, error) {
+	if !strings.HasPrefix(userLink, "@") {
+		return "", fmt.Errorf("invalid user link: %s", userLink)
+	}
+	u := user{}
+	if err := c.get("/users/username/"+url.PathEscape(userLink[1:]), &u); err != nil {
+		return "", fmt.Errorf("could not find user %s: %s", userLink, err)
+	}
+	return u.ID, nil
+}
+
+// GetUsername implements the api.Enricher interface
+func (c *Client) GetUsername(userID string) string {
+	u := user{}
+	if err := c.get("/users/"+url.PathEscape(userID), &u); err != nil {
+		logrus.Errorf("could not find user with id %s: %s", userID, err)
+		return "unknown-user"
+	}
+	return u.Username
+}
+
+// GetUserLink implements the api.Enricher interface
+func (c *Client) GetUserLink(userID string) string {
+	return "@" + c.GetUsername(userID)
+}
+
+// GetChannel implements the api.Enricher interface
+func (c *Client) GetChannel(channelID string) string {
+	ch, err := c.getChannel(channelID)
+	if err != nil {
+		logrus.Errorf("could not find channel with id %s: %s", channelID, err)
+		return "unknown-channel"
+	}
+	if ch.Type == directChannel {
+		return "IM"
+	}
+	return ch.Name
+}
+
+// GetChannelLink implements the api.Enricher interface
+func (c *Client) GetChannelLink(channelID string) string {
+	return "~" + c.GetChannel(channelID)
+}
+
+// IsIM implements the api.Enricher interface
+func (c *Client) IsIM(channelID string) bool {
+	ch, err := c.getChannel(channelID)
+	if err != nil {
+		logrus.Errorf("could not find channel with id %s: %s", channelID, err)
+		return false
+	}
+	return ch.Type == directChannel
+}
+
+func (c *Client) getChannel(channelID string) (channel, error) {
+	ch := channel{}
+	err := c.get("/channels/"+url.PathEscape(channelID), &ch)
+	return ch, err
+}
+
+// Listen listens to mattermost posts and sends the matching ones through the channel as requests
+func (c *Client) Listen(ch chan<- meeseeks.Request) {
+	b := &backoff.Backoff{
+		Min:    100 * time.Millisecond,
+		Max:    10 * time.Second,
+		Factor: 2,
+		Jitter: true,
+	}
+
+	for {
+		conn, err := c.connectWebsocket()
+		if err != nil {
+			if b.Attempt() > 10 {
+				logrus.Errorf("failed to connect to mattermost websocket: %s. Quitting", err)
+				break
+			}
+			logrus.Warnf("failed to connect to mattermost websocket: %s. Retrying", err)
+			time.Sleep(b.Duration())
+			continue
+		}
+		b.Reset()
+
+		logrus.Infof("Listening Mattermost Messages")
+		if err := c.serve(conn, ch); err != nil {
+			logrus.Warnf("mattermost websocket connection dropped: %s. Reconnecting", err)
+		}
+		conn.Close()
+	}
+	logrus.Infof("Stopped listening to messages")
+}
+
+func (c *Client) connectWebsocket() (*websocket.Conn, error) {
+	u, err := url.Parse(c.apiURL + "/websocket")
+	if err != nil {
+		return nil, fmt.Errorf("invalid websocket url: %s", err)
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+
+	conn, _, err := c.dialer.Dial(u.String(), http.Header{
+		"Authorization": []string{"Bearer " + c.token},
+	})
+	return conn, err
+}
+
+type event struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+type postedData struct {
+	ChannelType string `json:"channel_type"`
+	Post        string `json:"post"`
+}
+
+type post struct {
+	ID        string                 `json:"id,omitempty"`
+	UserID    string                 `json:"user_id,omitempty"`
+	ChannelID string                 `json:"channel_id"`
+	Message   string                 `json:"message"`
+	Props     map[string]interface{} `json:"props,omitempty"`
+}
+
+type attachment struct {
+	Text  string `json:"text"`
+	Color string `json:"color,omitempty"`
+}
+
+func (c *Client) serve(conn *websocket.Conn, ch chan<- meeseeks.Request) error {
+	for {
+		e := event{}
+		if err := conn.ReadJSON(&e); err != nil {
+			return fmt.Errorf("could not read event: %s", err)
+		}
+		if e.Event != "posted" {
+			logrus.Debugf("Ignored Mattermost Event %s", e.Event)
+			continue
+		}
+
+		data := postedData{}
+		if err := json.Unmarshal(e.Data, &data); err != nil {
+			logrus.Errorf("could not parse posted event %s: %s", e.Data, err)
+			continue
+		}
+		p := post{}
+		if err := json.Unmarshal([]byte(data.Post), &p); err != nil {
+			logrus.Errorf("could not parse post %s: %s", data.Post, err)
+			continue
+		}
+
+		text, err := c.matcher.Matches(p, data.ChannelType == directChannel)
+		if err != nil {
+			continue
+		}
+
+		r, err := c.requestFromPost(text, p, data.ChannelType == directChannel)
+		if err != nil {
+			logrus.Debugf("Failed to parse message '%s' as a command: %s", text, err)
+			c.Reply(formatter.FailureReply(meeseeks.Request{ChannelID: p.ChannelID}, err))
+			continue
+		}
+
+		logrus.Debugf("Sending Mattermost post %#v to messages channel", p)
+		ch <- r
+	}
+}
+
+func (c *Client) requestFromPost(text string, p post, isIM bool) (meeseeks.Request, error) {
+	args, err := parser.Parse(text)
+	logrus.Debugf("Command '%s' parsed as %#v", text, args)
+
+	if err != nil {
+		return meeseeks.Request{}, err
+	}
+
+	if len(args) == 0 {
+		return meeseeks.Request{}, errNoCommandToRun
+	}
+
+	username := c.GetUsername(p.UserID)
+	channel := "IM"
+	if !isIM {
+		channel = c.GetChannel(p.ChannelID)
+	}
+
+	return meeseeks.Request{
+		Command:     args[0],
+		Args:        args[1:],
+		Username:    username,
+		UserID:      p.UserID,
+		UserLink:    "@" + username,
+		Channel:     channel,
+		ChannelID:   p.ChannelID,
+		ChannelLink: "~" + channel,
+		IsIM:        isIM,
+		Origin:      Origin,
+	}, nil
+}
+
+// Reply replies to the user building a regular message
+func (c *Client) Reply(r formatter.Reply) {
+	style := r.ReplyStyle()
+	switch style {
+	case nullStyle, disabledStyle, nilStyle:
+		logrus.Debugf("Ignoring reply %#v, the null formatter is like this", r)
+		return
+	}
+
+	content, err := r.Render()
+	if err != nil {
+		logrus.Errorf("failed to render reply %#v: %s", r, err)
+		return
+	}
+
+	p := post{ChannelID: r.ChannelID()}
+	switch style {
+	case textStyle:
+		p.Message = content
+	default:
+		p.Props = map[string]interface{}{
+			"attachments": []attachment{
+				{
+					Text:  content,
+					Color: mapColor(r.Color()),
+				},
+			},
+		}
+	}
+
+	logrus.Debugf("Replying in Mattermost %s with %#v", r.ChannelID(), p)
+	if err := c.do(http.MethodPost, "/posts", p, nil); err != nil {
+		logrus.Errorf("failed post message %s on %s: %s", content, r.ChannelID(), err)
+	}
+}
+
+func mapColor(color string) string {
+	if hex, ok := namedColors[color]; ok {
+		return hex
+	}
+	return color
+}
+
+func (c *Client) get(path string, out interface{}) error {
+	return c.do(http.MethodGet, path, nil, out)
+}
+
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("could not marshal request payload: %s", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.apiURL+path, body)
+	if err != nil {
+		return fmt.Errorf("could not create request: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request %s %s failed: %s", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("request %s %s failed with status %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type messageMatcher struct {
+	botID         string
+	mention       string
+	prefixMatches []string
+}
+
+func newMessageMatcher(botID, botName string) messageMatcher {
+	mention := "@" + botName
+	return messageMatcher{
+		botID:         botID,
+		mention:       mention,
+		prefixMatches: []string{mention + ":", mention + ",", mention + " "},
+	}
+}
+
+// Matches returns the text of the post that is a command for this bot
+func (m messageMatcher) Matches(p post, isIM bool) (string, error) {
+	if p.UserID == m.botID {
+		logrus.Debug("It's myself, ignoring message")
+		return "", errIgnoredMessage
+	}
+	if isIM {
+		logrus.Debugf("Channel %s is IM channel, responding...", p.ChannelID)
+		return p.Message, nil
+	}
+	if p.Message == m.mention {
+		return "", nil
+	}
+	for _, match := range m.prefixMatches {
+		if strings.HasPrefix(p.Message, match) {
+			logrus.Debugf("Message '%s' matches prefix, responding...", p.Message)
+			return strings.TrimSpace(p.Message[len(match):]), nil
+		}
+	}
+	return "", errIgnoredMessage
+}
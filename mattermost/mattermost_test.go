@@ -0,0 +1,187 @@
+package mattermost_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/mattermost"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/template"
+
+	"github.com/gorilla/websocket"
+)
+
+func postedEvent(userID, channelID, channelType, message string) map[string]interface{} {
+	p, _ := json.Marshal(map[string]string{
+		"id":         "post-id",
+		"user_id":    userID,
+		"channel_id": channelID,
+		"message":    message,
+	})
+	return map[string]interface{}{
+		"event": "posted",
+		"data": map[string]string{
+			"channel_type": channelType,
+			"post":         string(p),
+		},
+	}
+}
+
+func newFakeServer(t *testing.T, posts chan map[string]interface{}) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/users/me", func(w http.ResponseWriter, r *http.Request) {
+		mocks.AssertEquals(t, "Bearer mm-token", r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"id": "bot", "username": "meeseeks"}`)
+	})
+	mux.HandleFunc("/api/v4/teams/name/yakshavers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "T1", "name": "yakshavers"}`)
+	})
+	mux.HandleFunc("/api/v4/users/U1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "U1", "username": "someone"}`)
+	})
+	mux.HandleFunc("/api/v4/users/username/someone", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "U1", "username": "someone"}`)
+	})
+	mux.HandleFunc("/api/v4/channels/C1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "C1", "name": "general", "type": "O"}`)
+	})
+	mux.HandleFunc("/api/v4/channels/D1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "D1", "name": "bot__U1", "type": "D"}`)
+	})
+	mux.HandleFunc("/api/v4/teams/T1/channels/name/general", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "C1", "name": "general", "type": "O"}`)
+	})
+	mux.HandleFunc("/api/v4/posts", func(w http.ResponseWriter, r *http.Request) {
+		p := map[string]interface{}{}
+		mocks.Must(t, "could not decode post", json.NewDecoder(r.Body).Decode(&p))
+		posts <- p
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/api/v4/websocket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		mocks.Must(t, "could not upgrade connection", err)
+		defer conn.Close()
+
+		for _, e := range []interface{}{
+			map[string]interface{}{"event": "hello", "data": map[string]string{"server_version": "5.0"}},
+			postedEvent("bot", "C1", "O", "@meeseeks I'm talking to myself"),
+			postedEvent("U1", "C1", "O", "not for the bot"),
+			postedEvent("U1", "C1", "O", "@meeseeks-admin echo for another bot"),
+			postedEvent("U1", "C1", "O", "@meeseeks: echo hello"),
+			postedEvent("U1", "D1", "D", "echo in private"),
+		} {
+			mocks.Must(t, "could not send event", conn.WriteJSON(e))
+		}
+		// Wait for the client to go away
+		conn.ReadMessage()
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestMattermostClient(t *testing.T) {
+	posts := make(chan map[string]interface{}, 1)
+	server := newFakeServer(t, posts)
+	defer server.Close()
+
+	c, err := mattermost.Connect(mattermost.ConnectionOpts{
+		URL:   server.URL,
+		Token: "mm-token",
+		Team:  "yakshavers",
+	})
+	mocks.Must(t, "could not connect to fake mattermost", err)
+
+	t.Run("listen", func(t *testing.T) {
+		ch := make(chan meeseeks.Request)
+		go c.Listen(ch)
+
+		for _, expected := range []meeseeks.Request{
+			{
+				Command:     "echo",
+				Args:        []string{"hello"},
+				Username:    "someone",
+				UserID:      "U1",
+				UserLink:    "@someone",
+				Channel:     "general",
+				ChannelID:   "C1",
+				ChannelLink: "~general",
+				Origin:      mattermost.Origin,
+			},
+			{
+				Command:     "echo",
+				Args:        []string{"in", "private"},
+				Username:    "someone",
+				UserID:      "U1",
+				UserLink:    "@someone",
+				Channel:     "IM",
+				ChannelID:   "D1",
+				ChannelLink: "~IM",
+				IsIM:        true,
+				Origin:      mattermost.Origin,
+			},
+		} {
+			select {
+			case r := <-ch:
+				mocks.AssertEquals(t, expected, r)
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for a request")
+			}
+		}
+	})
+
+	t.Run("enricher", func(t *testing.T) {
+		userID, err := c.ParseUserLink("@someone")
+		mocks.Must(t, "could not parse user link", err)
+		mocks.AssertEquals(t, "U1", userID)
+
+		channelID, err := c.ParseChannelLink("~general")
+		mocks.Must(t, "could not parse channel link", err)
+		mocks.AssertEquals(t, "C1", channelID)
+
+		mocks.AssertEquals(t, "someone", c.GetUsername("U1"))
+		mocks.AssertEquals(t, "@someone", c.GetUserLink("U1"))
+		mocks.AssertEquals(t, "general", c.GetChannel("C1"))
+		mocks.AssertEquals(t, "~general", c.GetChannelLink("C1"))
+		mocks.AssertEquals(t, false, c.IsIM("C1"))
+		mocks.AssertEquals(t, true, c.IsIM("D1"))
+	})
+
+	t.Run("reply", func(t *testing.T) {
+		formatter.Configure(formatter.FormatConfig{
+			Templates: map[string]string{
+				template.Success: "{{ .command }} done",
+				template.Failure: "{{ .command }} failed",
+			},
+			ReplyStyle: map[string]string{
+				template.Success: "text",
+			},
+			Colors: formatter.MessageColors{
+				Error: formatter.DefaultErrColorMessage,
+			},
+		})
+
+		c.Reply(formatter.SuccessReply(meeseeks.Request{Command: "echo", ChannelID: "C1"}))
+		p := <-posts
+		mocks.AssertEquals(t, "C1", p["channel_id"])
+		mocks.AssertEquals(t, "echo done", p["message"])
+
+		c.Reply(formatter.FailureReply(meeseeks.Request{Command: "echo", ChannelID: "C1"}, fmt.Errorf("boom")))
+		p = <-posts
+		mocks.AssertEquals(t, map[string]interface{}{
+			"attachments": []interface{}{
+				map[string]interface{}{
+					"text":  "echo failed",
+					"color": "#d00000",
+				},
+			},
+		}, p["props"])
+	})
+}
@@ -0,0 +1,104 @@
+package executor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/auth"
+	"gitlab.com/yakshaving.art/meeseeks-box/commands"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+)
+
+// requiresApproval returns whether the jobs of a command have to be approved before running
+func requiresApproval(cmd meeseeks.Command) bool {
+	a, ok := cmd.(meeseeks.ApprovableCommand)
+	return ok && a.GetRequiredApprovals() > 0
+}
+
+func (m *Executor) requestApproval(req meeseeks.Request) {
+	job, err := persistence.Jobs().RequestApproval(req)
+	if err != nil {
+		m.client.Reply(formatter.FailureReply(req, fmt.Errorf("could not create job: %s", err)))
+		return
+	}
+	logrus.Infof("Command '%s' from user '%s' is waiting for approval as job %d", req.Command, req.Username, job.ID)
+
+	m.client.Reply(formatter.PendingApprovalReply(req).WithJobID(job.ID))
+}
+
+// Approve records the approval of a job pending approval by the user that sent
+// the request, starting the job when it has enough approvals
+func (m *Executor) Approve(jobID uint64, approver meeseeks.Request) (meeseeks.Job, error) {
+	return m.decide(jobID, approver, true)
+}
+
+// Deny records the denial of a job pending approval by the user that sent the
+// request, which finishes the job without running it
+func (m *Executor) Deny(jobID uint64, approver meeseeks.Request) (meeseeks.Job, error) {
+	return m.decide(jobID, approver, false)
+}
+
+func (m *Executor) decide(jobID uint64, approver meeseeks.Request, approved bool) (meeseeks.Job, error) {
+	job, err := persistence.Jobs().Get(jobID)
+	if err != nil {
+		return job, err
+	}
+	if job.Status != meeseeks.JobPendingApprovalStatus {
+		return job, meeseeks.ErrJobNotPendingApproval
+	}
+
+	req := job.Request
+	cmd, ok := commands.Find(&req)
+	if !ok || !requiresApproval(cmd) {
+		return job, fmt.Errorf("command %s does not require approval anymore", req.Command)
+	}
+	a := cmd.(meeseeks.ApprovableCommand)
+
+	if approver.Username == req.Username {
+		return job, fmt.Errorf("jobs can't be approved or denied by the user that requested them")
+	}
+	if err := checkApprover(approver.Username, a.GetApprovers()); err != nil {
+		return job, err
+	}
+
+	job, err = persistence.Jobs().AddApproval(jobID, meeseeks.Approval{
+		Username: approver.Username,
+		UserID:   approver.UserID,
+		Approved: approved,
+		Time:     time.Now().UTC(),
+	}, a.GetRequiredApprovals())
+	if err != nil {
+		return job, err
+	}
+	logrus.Infof("Job %d got a decision from user '%s', approved: %t", jobID, approver.Username, approved)
+
+	switch job.Status {
+	case meeseeks.JobDeniedStatus:
+		m.client.Reply(formatter.FailureReply(job.Request,
+			fmt.Errorf("job %d was denied by %s", job.ID, approver.Username)).WithJobID(job.ID))
+
+	case meeseeks.JobRunningStatus:
+		m.runTask(task{job: job, cmd: cmd})
+	}
+	return job, nil
+}
+
+// checkApprover returns nil if the user belongs to any of the approvers
+// groups, which are the admins when none is configured
+func checkApprover(username string, approvers []string) error {
+	if len(approvers) == 0 {
+		approvers = []string{auth.AdminGroup}
+	}
+	err := auth.Check(meeseeks.Request{Username: username}, meeseeks.CommandOpts{
+		AuthStrategy:  auth.AuthStrategyAllowedGroup,
+		AllowedGroups: approvers,
+	})
+	if err != nil {
+		return fmt.Errorf("%s is not allowed to approve this job", username)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package executor
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+)
+
+// DefaultConfirmationTimeout is how long a request waits to be confirmed before it expires
+const DefaultConfirmationTimeout = 5 * time.Minute
+
+// Confirmation errors
+var (
+	ErrUnknownConfirmation = errors.New("this request expired or was already answered")
+	ErrNotTheRequester     = errors.New("only the user that sent the request can answer it")
+)
+
+type pendingConfirmation struct {
+	req   meeseeks.Request
+	cmd   meeseeks.Command
+	timer *time.Timer
+}
+
+// pendingConfirmations keeps the requests that are waiting for the user to
+// confirm them, dropping them when they expire
+type pendingConfirmations struct {
+	timeout  time.Duration
+	requests map[string]pendingConfirmation
+	m        sync.Mutex
+}
+
+func newPendingConfirmations(timeout time.Duration) *pendingConfirmations {
+	if timeout <= 0 {
+		timeout = DefaultConfirmationTimeout
+	}
+	return &pendingConfirmations{
+		timeout:  timeout,
+		requests: make(map[string]pendingConfirmation),
+	}
+}
+
+// Add stores a request and returns the ID used to confirm it, onExpire is
+// called if nobody answers it in time
+func (p *pendingConfirmations) Add(req meeseeks.Request, cmd meeseeks.Command, onExpire func(meeseeks.Request)) string {
+	defer p.m.Unlock()
+	p.m.Lock()
+
+	id := p.newID()
+	p.requests[id] = pendingConfirmation{
+		req: req,
+		cmd: cmd,
+		timer: time.AfterFunc(p.timeout, func() {
+			if pending, ok := p.remove(id); ok {
+				logrus.Infof("Confirmation %s for command '%s' from user '%s' expired", id, req.Command, req.Username)
+				onExpire(pending.req)
+			}
+		}),
+	}
+	return id
+}
+
+// newID returns an unused ID short enough to be typed in the chat, it must be
+// called with the lock held
+func (p *pendingConfirmations) newID() string {
+	for {
+		id := strings.SplitN(uuid.New().String(), "-", 2)[0]
+		if _, ok := p.requests[id]; !ok {
+			return id
+		}
+	}
+}
+
+// Answer removes the pending request if it was sent by the user
+func (p *pendingConfirmations) Answer(id, userID string) (pendingConfirmation, error) {
+	defer p.m.Unlock()
+	p.m.Lock()
+
+	pending, ok := p.requests[id]
+	if !ok {
+		return pendingConfirmation{}, ErrUnknownConfirmation
+	}
+	if pending.req.UserID != userID {
+		return pendingConfirmation{}, ErrNotTheRequester
+	}
+
+	delete(p.requests, id)
+	pending.timer.Stop()
+	return pending, nil
+}
+
+func (p *pendingConfirmations) remove(id string) (pendingConfirmation, bool) {
+	defer p.m.Unlock()
+	p.m.Lock()
+
+	pending, ok := p.requests[id]
+	delete(p.requests, id)
+	return pending, ok
+}
@@ -2,17 +2,21 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/auth"
 	"gitlab.com/yakshaving.art/meeseeks-box/commands"
 	"gitlab.com/yakshaving.art/meeseeks-box/commands/builtins"
+	"gitlab.com/yakshaving.art/meeseeks-box/commands/params"
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks/metrics"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
+	"gitlab.com/yakshaving.art/meeseeks-box/ratelimit"
 	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
 )
 
@@ -27,6 +31,46 @@ type NullChatClient struct{}
 // Reply implements ChatClient.Reply
 func (NullChatClient) Reply(_ formatter.Reply) {}
 
+// chatClients routes each reply to the chat client registered for the origin
+// of the request, using the fallback client for unknown origins
+type chatClients struct {
+	fallback ChatClient
+	clients  map[string]ChatClient
+	m        sync.RWMutex
+}
+
+func newChatClients(fallback ChatClient) *chatClients {
+	if fallback == nil {
+		fallback = NullChatClient{}
+	}
+	return &chatClients{
+		fallback: fallback,
+		clients:  make(map[string]ChatClient),
+	}
+}
+
+func (c *chatClients) Register(origin string, client ChatClient) {
+	defer c.m.Unlock()
+	c.m.Lock()
+
+	c.clients[origin] = client
+}
+
+// Reply implements ChatClient.Reply
+func (c *chatClients) Reply(r formatter.Reply) {
+	c.m.RLock()
+	client, ok := c.clients[r.Origin()]
+	c.m.RUnlock()
+
+	if !ok {
+		if r.Origin() != "" {
+			logrus.Warnf("no chat client registered for origin %s, replying through the default one", r.Origin())
+		}
+		client = c.fallback
+	}
+	client.Reply(r)
+}
+
 // Listener provides the necessary interface to start listening requests from a channel.
 type Listener interface {
 	Listen(chan<- meeseeks.Request)
@@ -34,16 +78,28 @@ type Listener interface {
 
 // Executor is the command execution engine
 type Executor struct {
-	client    ChatClient
+	client    *chatClients
 	listeners []Listener
 
 	requestsCh chan meeseeks.Request
 
 	tasksCh        chan task
 	wg             sync.WaitGroup
+	queue          *taskQueue
 	activeCommands *activeCommands
+	confirmations  *pendingConfirmations
+
+	// shuttingDown stops new tasks from being sent to the tasks channel once it is about to be closed
+	shuttingDown bool
+	shutdownM    sync.Mutex
 }
 
+// ErrShuttingDown is returned when a task is started while the executor is shutting down
+var ErrShuttingDown = errors.New("meeseeks is shutting down")
+
+// ErrCancelledWhileQueued is the failure of a job that was cancelled before it started running
+var ErrCancelledWhileQueued = errors.New("job was cancelled while queued")
+
 type task struct {
 	job meeseeks.Job
 	cmd meeseeks.Command
@@ -51,33 +107,54 @@ type task struct {
 
 // Args is handy to set multiple arguments
 type Args struct {
+	// ConcurrentTaskCount is how many jobs can run at the same time, jobs over
+	// it are queued until others finish. No limit when 0
 	ConcurrentTaskCount int
 	WithBuiltinCommands bool
-	ChatClient          ChatClient
+
+	// ChatClient is used to reply to requests that have no registered origin
+	ChatClient ChatClient
+
+	// ConfirmationTimeout is how long requests wait to be confirmed, DefaultConfirmationTimeout when 0
+	ConfirmationTimeout time.Duration
 }
 
 // New creates a new Meeseeks service
 func New(args Args) *Executor {
 	ac := newActiveCommands()
-	if args.WithBuiltinCommands {
-		builtins.LoadBuiltins(
-			builtins.NewCancelJobCommand(ac.Cancel),
-			builtins.NewKillJobCommand(ac.Cancel),
-		)
-	}
 
-	e := Executor{
-		client:     args.ChatClient,
+	e := &Executor{
+		client:     newChatClients(args.ChatClient),
 		tasksCh:    make(chan task, args.ConcurrentTaskCount),
 		requestsCh: make(chan meeseeks.Request),
 
 		wg:             sync.WaitGroup{},
+		queue:          newTaskQueue(args.ConcurrentTaskCount),
 		activeCommands: ac,
+		confirmations:  newPendingConfirmations(args.ConfirmationTimeout),
+	}
+
+	if args.WithBuiltinCommands {
+		builtins.LoadBuiltins(
+			builtins.NewCancelJobCommand(e.cancelJob),
+			builtins.NewKillJobCommand(e.killJob),
+			builtins.NewApproveJobCommand(e.Approve),
+			builtins.NewDenyJobCommand(e.Deny),
+			builtins.NewConfirmCommand(e.Confirm),
+			builtins.NewDismissCommand(e.Confirm),
+			builtins.NewLocksCommand(e.queue.Locks),
+		)
 	}
 
 	go e.processTasks()
 
-	return &e
+	return e
+}
+
+// RegisterChatClient registers the client used to reply to requests with the given origin
+func (m *Executor) RegisterChatClient(origin string, client ChatClient) {
+	logrus.Debugf("Executor: registering chat client for origin %s", origin)
+	m.client.Register(origin, client)
 }
 
 // ListenTo appends a listener to the list and starts listening to it
@@ -105,21 +182,123 @@ func (m *Executor) Run() {
 			continue
 		}
 
+		args, err := parseArgs(req, cmd)
+		if err != nil {
+			logrus.Infof("Rejected command '%s' from user '%s' on channel '%s': %s",
+				req.Command, req.Username, req.Channel, err)
+			m.client.Reply(formatter.FailureReply(req, err))
+			continue
+		}
+		req.Args = args
+
+		if err := ratelimit.Check(req, cmd); err != nil {
+			logrus.Infof("Rejected command '%s' from user '%s' on channel '%s': %s",
+				req.Command, req.Username, req.Channel, err)
+			m.client.Reply(formatter.RateLimitedReply(req, err))
+			metrics.RateLimitedCommandsCount.WithLabelValues(req.Command, rateLimitLabel(err)).Inc()
+			continue
+		}
+
 		logrus.Infof("Accepted command '%s' from user '%s' on channel '%s' with args: %s",
 			req.Command, req.Username, req.Channel, req.Args)
 		metrics.AcceptedCommandsCount.WithLabelValues(req.Command).Inc()
 
-		t, err := m.createTask(req, cmd)
-		if err != nil {
-			m.client.Reply(formatter.FailureReply(req, fmt.Errorf("could not create task: %s", err)))
+		if c, ok := cmd.(meeseeks.ConfirmableCommand); ok && c.MustConfirm() {
+			m.askForConfirmation(req, cmd)
 			continue
 		}
 
-		m.wg.Add(1)
-		m.tasksCh <- t
+		m.startTask(req, cmd)
 	}
 }
 
+// parseArgs validates the arguments of the request when the command declares
+// the ones it accepts, returning them with their defaults filled in
+func parseArgs(req meeseeks.Request, cmd meeseeks.Command) ([]string, error) {
+	c, ok := cmd.(meeseeks.ParameterizedCommand)
+	if !ok || len(c.GetParams()) == 0 {
+		return req.Args, nil
+	}
+	args, err := params.Parse(c.GetParams(), req.Args)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments: %s", err)
+	}
+	return args, nil
+}
+
+func rateLimitLabel(err error) string {
+	if e, ok := err.(ratelimit.Error); ok {
+		return e.Limit
+	}
+	return "unknown"
+}
+
+func (m *Executor) startTask(req meeseeks.Request, cmd meeseeks.Command) {
+	if requiresApproval(cmd) {
+		m.requestApproval(req)
+		return
+	}
+
+	t, err := m.createTask(req, cmd)
+	if err != nil {
+		m.client.Reply(formatter.FailureReply(req, fmt.Errorf("could not create task: %s", err)))
+		return
+	}
+
+	m.runTask(t)
+}
+
+// runTask hands the task to the workers, failing its job when meeseeks is shutting down
+func (m *Executor) runTask(t task) {
+	if err := m.pushTask(t); err != nil {
+		logrus.Errorf("Could not run job %d: %s", t.job.ID, err)
+		m.client.Reply(formatter.FailureReply(t.job.Request, err).WithJobID(t.job.ID))
+		if t.cmd.MustRecord() {
+			persistence.Jobs().Fail(t.job.ID)
+		}
+	}
+}
+
+func (m *Executor) pushTask(t task) error {
+	m.shutdownM.Lock()
+	defer m.shutdownM.Unlock()
+
+	if m.shuttingDown {
+		return ErrShuttingDown
+	}
+	m.wg.Add(1)
+	m.tasksCh <- t
+	return nil
+}
+
+func (m *Executor) askForConfirmation(req meeseeks.Request, cmd meeseeks.Command) {
+	id := m.confirmations.Add(req, cmd, func(req meeseeks.Request) {
+		m.client.Reply(formatter.ConfirmationExpiredReply(req))
+	})
+	logrus.Infof("Command '%s' from user '%s' is waiting for confirmation %s", req.Command, req.Username, id)
+
+	m.client.Reply(formatter.ConfirmationReply(req, id))
+}
+
+// Confirm answers a pending confirmation on behalf of the user, starting the
+// job when approved. It returns the request that was answered.
+func (m *Executor) Confirm(confirmationID, userID string, approved bool) (meeseeks.Request, error) {
+	pending, err := m.confirmations.Answer(confirmationID, userID)
+	if err != nil {
+		return meeseeks.Request{}, err
+	}
+
+	if !approved {
+		logrus.Infof("Command '%s' from user '%s' was cancelled", pending.req.Command, pending.req.Username)
+		return pending.req, nil
+	}
+
+	logrus.Infof("Command '%s' from user '%s' was confirmed", pending.req.Command, pending.req.Username)
+	m.startTask(pending.req, pending.cmd)
+
+	return pending.req, nil
+}
+
 func (m *Executor) createTask(req meeseeks.Request, cmd meeseeks.Command) (task, error) {
 	if !cmd.MustRecord() {
 		return task{job: persistence.Jobs().Null(req), cmd: cmd}, nil
@@ -134,11 +313,58 @@ func (m *Executor) createTask(req meeseeks.Request, cmd meeseeks.Command) (task,
 func (m *Executor) Shutdown() {
 	defer m.closeTasksChannel()
 
+	m.shutdownM.Lock()
+	m.shuttingDown = true
+	m.shutdownM.Unlock()
+
 	logrus.Info("Waiting for jobs to finish")
 	m.wg.Wait()
 	logrus.Info("Done waiting, exiting")
 }
 
+// cancelJob asks a job to finish on behalf of the user that sent the request
+func (m *Executor) cancelJob(jobID uint64, requester meeseeks.Request) {
+	m.stopJob(jobID, meeseeks.Stop{Kind: meeseeks.StopCancel, Username: requester.Username})
+}
+
+// killJob finishes a job right away on behalf of the user that sent the request
+func (m *Executor) killJob(jobID uint64, requester meeseeks.Request) {
+	m.stopJob(jobID, meeseeks.Stop{Kind: meeseeks.StopKill, Username: requester.Username})
+}
+
+// stopJob drops the job from the queue when it did not start yet, or stops it
+// if it's running
+func (m *Executor) stopJob(jobID uint64, stop meeseeks.Stop) {
+	t, ok := m.queue.Remove(jobID)
+	if !ok {
+		m.activeCommands.Stop(jobID, stop)
+		return
+	}
+	defer m.wg.Done()
+
+	logrus.Infof("Job %d for command '%s' was stopped by %s while queued", jobID, t.job.Request.Command, stop.Username)
+	if err := finishStopped(jobID, stop); err != nil {
+		logrus.Errorf("Could not flag job %d as stopped: %s", jobID, err)
+	}
+	m.client.Reply(formatter.FailureReply(t.job.Request, ErrCancelledWhileQueued).WithJobID(jobID))
+}
+
+// finishStopped flags the job as cancelled or killed by the user that stopped it
+func finishStopped(jobID uint64, stop meeseeks.Stop) error {
+	if stop.Kind == meeseeks.StopKill {
+		return persistence.Jobs().Kill(jobID, stop.Username)
+	}
+	return persistence.Jobs().Cancel(jobID, stop.Username)
+}
+
+// stopError is the failure of a job that was stopped while running
+func stopError(stop meeseeks.Stop) error {
+	if stop.Kind == meeseeks.StopKill {
+		return fmt.Errorf("job was killed by %s", stop.Username)
+	}
+	return fmt.Errorf("job was cancelled by %s", stop.Username)
+}
+
 func (m *Executor) closeTasksChannel() {
 	logrus.Infof("Closing meeseeks tasks channel")
 	close(m.tasksCh)
@@ -146,48 +372,141 @@ func (m *Executor) closeTasksChannel() {
 
 func (m *Executor) processTasks() {
 	for t := range m.tasksCh {
-		go func(t task) {
-			job := t.job
-			req := job.Request
-			cmd := t.cmd
+		start, ahead, err := m.queue.Push(t)
+		if err != nil {
+			m.rejectTask(t, err)
+			continue
+		}
+		if !start {
+			m.queueTask(t, ahead)
+			continue
+		}
+		go m.execute(t)
+	}
+}
+
+// queueTask lets the user know the job is waiting for other jobs to finish
+func (m *Executor) queueTask(t task, ahead int) {
+	logrus.Infof("Job %d for command '%s' from user '%s' is queued behind %d jobs",
+		t.job.ID, t.job.Request.Command, t.job.Request.Username, ahead)
+
+	if err := persistence.Jobs().Queue(t.job.ID); err != nil {
+		logrus.Errorf("Could not flag job %d as queued: %s", t.job.ID, err)
+	}
+	m.client.Reply(formatter.QueuedReply(t.job.Request, ahead).WithJobID(t.job.ID))
+}
+
+// rejectTask fails a job that can't wait for the lock it needs
+func (m *Executor) rejectTask(t task, err error) {
+	defer m.wg.Done()
 
-			if cmd.HasHandshake() {
-				m.client.Reply(formatter.HandshakeReply(req))
-			}
+	logrus.Infof("Job %d for command '%s' from user '%s' was rejected: %s",
+		t.job.ID, t.job.Request.Command, t.job.Request.Username, err)
+
+	if err := persistence.Jobs().Fail(t.job.ID); err != nil {
+		logrus.Errorf("Could not flag job %d as failed: %s", t.job.ID, err)
+	}
+	m.client.Reply(formatter.FailureReply(t.job.Request, err).WithJobID(t.job.ID))
+}
+
+func (m *Executor) execute(t task) {
+	job := t.job
+	req := job.Request
+	cmd := t.cmd
+
+	if cmd.HasHandshake() {
+		m.client.Reply(formatter.HandshakeReply(req))
+	}
 
-			ctx := m.activeCommands.Add(t)
-			defer m.activeCommands.Cancel(job.ID)
+	ctx := m.activeCommands.Add(t)
 
-			out, err := t.cmd.Execute(ctx, t.job)
-			if err != nil {
-				logrus.Errorf("Command '%s' from user '%s' failed execution with error: %s",
-					req.Command, req.Username, err)
+	out, result, err := m.run(ctx, t)
+	stop, stopped := meeseeks.StopOf(ctx)
+	m.activeCommands.Cancel(job.ID)
 
-				m.client.Reply(formatter.FailureReply(req, err).WithOutput(out))
+	if err == nil && isWarning(cmd, result) {
+		logrus.Infof("Command '%s' from user '%s' finished with warnings", req.Command,
+			req.Username)
 
-				persistence.Jobs().Fail(job.ID)
+		m.client.Reply(formatter.WarningReply(req).WithJobID(job.ID).WithOutput(out))
 
-			} else {
-				logrus.Infof("Command '%s' from user '%s' succeeded execution", req.Command,
-					req.Username)
+		persistence.Jobs().Warn(job.ID)
 
-				m.client.Reply(formatter.SuccessReply(req).WithOutput(out))
+	} else if err != nil && stopped {
+		logrus.Infof("Command '%s' from user '%s' was stopped by %s: %s",
+			req.Command, req.Username, stop.Username, err)
 
-				persistence.Jobs().Succeed(job.ID)
-			}
-			m.wg.Done()
-		}(t)
+		m.client.Reply(formatter.FailureReply(req, stopError(stop)).WithJobID(job.ID).WithOutput(out))
+
+		finishStopped(job.ID, stop)
+
+	} else if err != nil {
+		logrus.Errorf("Command '%s' from user '%s' failed execution with error: %s",
+			req.Command, req.Username, err)
+
+		m.client.Reply(formatter.FailureReply(req, err).WithJobID(job.ID).WithOutput(out))
+
+		persistence.Jobs().Fail(job.ID)
+
+	} else {
+		logrus.Infof("Command '%s' from user '%s' succeeded execution", req.Command,
+			req.Username)
+
+		m.client.Reply(formatter.SuccessReply(req).WithJobID(job.ID).WithOutput(out))
+
+		persistence.Jobs().Succeed(job.ID)
 	}
+
+	for _, next := range m.queue.Done(t) {
+		if err := persistence.Jobs().Start(next.job.ID); err != nil {
+			logrus.Errorf("Could not flag job %d as running: %s", next.job.ID, err)
+		}
+		go m.execute(next)
+	}
+	m.wg.Done()
+}
+
+// run executes the command of the task, recording how it ran when the command
+// reports it
+func (m *Executor) run(ctx context.Context, t task) (string, *meeseeks.JobResult, error) {
+	c, ok := t.cmd.(meeseeks.ReportingCommand)
+	if !ok {
+		out, err := t.cmd.Execute(ctx, t.job)
+		return out, nil, err
+	}
+
+	out, result, err := c.ExecuteWithResult(ctx, t.job)
+	if t.cmd.MustRecord() {
+		if e := persistence.Jobs().SetResult(t.job.ID, result); e != nil {
+			logrus.Errorf("Could not record the result of job %d: %s", t.job.ID, e)
+		}
+	}
+	return out, &result, err
+}
+
+// isWarning returns whether the process of a job exited with one of the exit
+// codes its command flags as a warning
+func isWarning(cmd meeseeks.Command, result *meeseeks.JobResult) bool {
+	c, ok := cmd.(meeseeks.ExitCodesCommand)
+	if !ok || result == nil {
+		return false
+	}
+	for _, code := range c.GetWarningExitCodes() {
+		if code == result.ExitCode {
+			return true
+		}
+	}
+	return false
 }
 
 type activeCommands struct {
-	ctx map[uint64]context.CancelFunc
+	ctx map[uint64]func(meeseeks.Stop)
 	m   sync.Mutex
 }
 
 func newActiveCommands() *activeCommands {
 	return &activeCommands{
-		ctx: make(map[uint64]context.CancelFunc),
+		ctx: make(map[uint64]func(meeseeks.Stop)),
 	}
 }
 
@@ -195,24 +514,30 @@ func (a *activeCommands) Add(t task) context.Context {
 	defer a.m.Unlock()
 	a.m.Lock()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	a.ctx[t.job.ID] = cancel
+	ctx, stop := meeseeks.WithStop(context.Background())
+	a.ctx[t.job.ID] = stop
 	return ctx
 }
 
+// Cancel releases the context of a job that is done
 func (a *activeCommands) Cancel(jobID uint64) {
+	a.Stop(jobID, meeseeks.Stop{})
+}
+
+// Stop cancels the context of a running job recording how it was stopped
+func (a *activeCommands) Stop(jobID uint64, stop meeseeks.Stop) {
 	defer a.m.Unlock()
 	a.m.Lock()
 
-	cancel, ok := a.ctx[jobID]
+	stopFunc, ok := a.ctx[jobID]
 	if !ok {
-		logrus.Debugf("could not cancel job %d because it is not in the active jobs list", jobID)
+		logrus.Debugf("could not stop job %d because it is not in the active jobs list", jobID)
 		return
 	}
 
-	// Delete the cancel command from the map
+	// Delete the stop function from the map
 	delete(a.ctx, jobID)
 
-	// Invoke the cancel function
-	cancel()
+	// Invoke the stop function
+	stopFunc(stop)
 }
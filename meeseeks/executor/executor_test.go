@@ -3,12 +3,15 @@ package executor_test
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks/executor"
 	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
 	"gitlab.com/yakshaving.art/meeseeks-box/text/template"
 	"github.com/renstrom/dedent"
 	"github.com/sirupsen/logrus"
@@ -197,3 +200,587 @@ func Test_MeeseeksInteractions(t *testing.T) {
 	})
 
 }
+
+func Test_RepliesAreRoutedToTheRequestOrigin(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().WithEchoCommand().WithDBPath(dbpath).Load()
+		other := mocks.NewClientStub()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			ConcurrentTaskCount: 1,
+		})
+		e.RegisterChatClient("other", other)
+		e.ListenTo(client)
+
+		go e.Run()
+
+		client.RequestsCh <- meeseeks.Request{
+			Command:   "unknown-command",
+			UserLink:  "<@myuser>",
+			ChannelID: "otherID",
+			Origin:    "other",
+		}
+		mocks.AssertEquals(t, "otherID", (<-other.MessagesSent).Channel)
+
+		client.RequestsCh <- meeseeks.Request{
+			Command:   "unknown-command",
+			UserLink:  "<@myuser>",
+			ChannelID: "generalID",
+		}
+		mocks.AssertEquals(t, "generalID", (<-client.MessagesSent).Channel)
+
+		e.Shutdown()
+	})
+}
+
+func Test_CommandsThatRequireConfirmation(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().
+			WithConfig(dedent.Dedent(`
+			---
+			commands:
+			  dangerous:
+			    command: echo
+			    auth_strategy: any
+			    no_handshake: true
+			    confirm: true
+			`)).WithDBPath(dbpath).Load()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			ConcurrentTaskCount: 1,
+			ConfirmationTimeout: 100 * time.Millisecond,
+		})
+		e.ListenTo(client)
+
+		go e.Run()
+
+		req := meeseeks.Request{
+			Command:   "dangerous",
+			Args:      []string{"yes"},
+			UserID:    "myuser",
+			UserLink:  "<@myuser>",
+			ChannelID: "generalID",
+		}
+
+		client.RequestsCh <- req
+		confirmation := <-client.MessagesSent
+		mocks.AssertEquals(t, fmt.Sprintf("<@myuser> Uuuh, are you sure you want me to run dangerous yes? "+
+			"Answer with `confirm %s` or `dismiss %s`", confirmation.ConfirmationID, confirmation.ConfirmationID),
+			confirmation.Text)
+
+		_, err := e.Confirm(confirmation.ConfirmationID, "otheruser", true)
+		mocks.AssertEquals(t, executor.ErrNotTheRequester, err)
+
+		confirmed, err := e.Confirm(confirmation.ConfirmationID, "myuser", true)
+		mocks.Must(t, "could not confirm the request", err)
+		mocks.AssertEquals(t, req, confirmed)
+		mocks.AssertMatches(t, "^<@myuser> .*\n```\nyes\n```$", (<-client.MessagesSent).Text)
+
+		_, err = e.Confirm(confirmation.ConfirmationID, "myuser", true)
+		mocks.AssertEquals(t, executor.ErrUnknownConfirmation, err)
+
+		client.RequestsCh <- req
+		<-client.MessagesSent
+		mocks.AssertEquals(t, "<@myuser> Uuuh, nobody confirmed, so I'm not running dangerous yes",
+			(<-client.MessagesSent).Text)
+
+		e.Shutdown()
+	})
+}
+
+func Test_ConfirmingWhileShuttingDown(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().
+			WithConfig(dedent.Dedent(`
+			---
+			commands:
+			  dangerous:
+			    command: echo
+			    auth_strategy: any
+			    no_handshake: true
+			    confirm: true
+			`)).WithDBPath(dbpath).Load()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			ConcurrentTaskCount: 1,
+		})
+		e.ListenTo(client)
+
+		go e.Run()
+
+		client.RequestsCh <- meeseeks.Request{
+			Command:   "dangerous",
+			Args:      []string{"yes"},
+			UserID:    "myuser",
+			UserLink:  "<@myuser>",
+			ChannelID: "generalID",
+		}
+		confirmation := <-client.MessagesSent
+
+		e.Shutdown()
+
+		go e.Confirm(confirmation.ConfirmationID, "myuser", true)
+		mocks.AssertMatches(t, "^<@myuser> .* meeseeks is shutting down$", (<-client.MessagesSent).Text)
+	})
+}
+
+func Test_CommandsThatRequireApproval(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().
+			WithConfig(dedent.Dedent(`
+			---
+			groups:
+			  sre: ["approver1", "approver2"]
+			commands:
+			  deploy:
+			    command: echo
+			    auth_strategy: any
+			    no_handshake: true
+			    approval:
+			      approvers: ["sre"]
+			      required_approvals: 2
+			`)).WithDBPath(dbpath).Load()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			WithBuiltinCommands: true,
+			ConcurrentTaskCount: 1,
+		})
+		e.ListenTo(client)
+
+		go e.Run()
+
+		client.RequestsCh <- meeseeks.Request{
+			Command:   "deploy",
+			Args:      []string{"prod"},
+			Username:  "requester",
+			UserLink:  "<@requester>",
+			ChannelID: "generalID",
+		}
+		mocks.AssertEquals(t, "<@requester> Uuuh, somebody has to approve deploy prod, "+
+			"job 1 will run once it's approved with `approve 1`", (<-client.MessagesSent).Text)
+
+		_, err := e.Approve(1, meeseeks.Request{Username: "requester"})
+		mocks.AssertEquals(t, "jobs can't be approved or denied by the user that requested them", err.Error())
+
+		_, err = e.Approve(1, meeseeks.Request{Username: "outsider"})
+		mocks.AssertEquals(t, "outsider is not allowed to approve this job", err.Error())
+
+		job, err := e.Approve(1, meeseeks.Request{Username: "approver1"})
+		mocks.Must(t, "could not approve job", err)
+		mocks.AssertEquals(t, meeseeks.JobPendingApprovalStatus, job.Status)
+
+		_, err = e.Deny(1, meeseeks.Request{Username: "approver1"})
+		mocks.AssertEquals(t, meeseeks.ErrAlreadyDecided, err)
+
+		job, err = e.Approve(1, meeseeks.Request{Username: "approver2"})
+		mocks.Must(t, "could not approve job", err)
+		mocks.AssertEquals(t, meeseeks.JobRunningStatus, job.Status)
+		mocks.AssertMatches(t, "^<@requester> .*\n```\nprod\n```$", (<-client.MessagesSent).Text)
+
+		client.RequestsCh <- meeseeks.Request{
+			Command:   "deploy",
+			Username:  "requester",
+			UserLink:  "<@requester>",
+			ChannelID: "generalID",
+		}
+		<-client.MessagesSent
+
+		denied := make(chan meeseeks.Job)
+		go func() {
+			job, err := e.Deny(2, meeseeks.Request{Username: "approver2"})
+			mocks.Must(t, "could not deny job", err)
+			denied <- job
+		}()
+		mocks.AssertEquals(t, "<@requester> Uuuh!, no, it failed :disappointed: job 2 was denied by approver2",
+			(<-client.MessagesSent).Text)
+		mocks.AssertEquals(t, meeseeks.JobDeniedStatus, (<-denied).Status)
+
+		e.Shutdown()
+	})
+}
+
+func Test_JobsOverTheConcurrencyLimitAreQueued(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().
+			WithConfig(dedent.Dedent(`
+			---
+			commands:
+			  sleepy:
+			    command: sleep
+			    auth_strategy: any
+			    no_handshake: true
+			    max_concurrency: 1
+			`)).WithDBPath(dbpath).Load()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			WithBuiltinCommands: true,
+			ConcurrentTaskCount: 20,
+		})
+		e.ListenTo(client)
+
+		go e.Run()
+
+		for i := 0; i < 3; i++ {
+			client.RequestsCh <- meeseeks.Request{
+				Command:   "sleepy",
+				Args:      []string{"0.2"},
+				Username:  "myuser",
+				UserLink:  "<@myuser>",
+				ChannelID: "generalID",
+			}
+		}
+		mocks.AssertEquals(t, "<@myuser> Uuuh, I'm busy, I'll run sleepy 0.2, job 2 is queued behind 1 jobs",
+			(<-client.MessagesSent).Text)
+		mocks.AssertEquals(t, "<@myuser> Uuuh, I'm busy, I'll run sleepy 0.2, job 3 is queued behind 2 jobs",
+			(<-client.MessagesSent).Text)
+
+		job, err := persistence.Jobs().Get(2)
+		mocks.Must(t, "could not get queued job", err)
+		mocks.AssertEquals(t, meeseeks.JobQueuedStatus, job.Status)
+
+		client.RequestsCh <- meeseeks.Request{
+			Command:   "cancel",
+			Args:      []string{"3"},
+			Username:  "myuser",
+			UserLink:  "<@myuser>",
+			ChannelID: "generalID",
+		}
+
+		replies := make([]string, 0)
+		for i := 0; i < 4; i++ {
+			replies = append(replies, (<-client.MessagesSent).Text)
+		}
+		e.Shutdown()
+
+		joined := strings.Join(replies, "\n")
+		mocks.AssertEquals(t, 1, strings.Count(joined, "Issued command cancellation to job 3"))
+		mocks.AssertEquals(t, 1, strings.Count(joined, ":disappointed: job was cancelled while queued"))
+
+		for id, status := range map[uint64]string{
+			1: meeseeks.JobSuccessStatus,
+			2: meeseeks.JobSuccessStatus,
+			3: meeseeks.JobCancelledStatus,
+		} {
+			job, err := persistence.Jobs().Get(id)
+			mocks.Must(t, "could not get job", err)
+			mocks.AssertEquals(t, status, job.Status)
+		}
+
+		job, err = persistence.Jobs().Get(3)
+		mocks.Must(t, "could not get cancelled job", err)
+		mocks.AssertEquals(t, "myuser", job.StoppedBy)
+	})
+}
+
+func Test_JobsHoldingTheSameLockDoNotRunTogether(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().
+			WithConfig(dedent.Dedent(`
+			---
+			commands:
+			  deploy:
+			    command: sleep
+			    auth_strategy: any
+			    no_handshake: true
+			    lock: deploy-prod
+			  migrate:
+			    command: sleep
+			    auth_strategy: any
+			    no_handshake: true
+			    lock: deploy-prod
+			  rollback:
+			    command: sleep
+			    auth_strategy: any
+			    no_handshake: true
+			    lock: deploy-prod
+			    lock_policy: reject
+			`)).WithDBPath(dbpath).Load()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			WithBuiltinCommands: true,
+			ConcurrentTaskCount: 20,
+		})
+		e.ListenTo(client)
+
+		go e.Run()
+
+		for _, command := range []string{"deploy", "rollback", "migrate"} {
+			client.RequestsCh <- meeseeks.Request{
+				Command:   command,
+				Args:      []string{"0.5"},
+				Username:  "myuser",
+				UserLink:  "<@myuser>",
+				ChannelID: "generalID",
+			}
+		}
+		rejected := (<-client.MessagesSent).Text
+		if !strings.HasSuffix(rejected, ":disappointed: lock deploy-prod is held by job 1") {
+			t.Fatalf("rollback was not rejected naming the lock holder: %s", rejected)
+		}
+		mocks.AssertEquals(t, "<@myuser> Uuuh, I'm busy, I'll run migrate 0.5, job 3 is queued behind 1 jobs",
+			(<-client.MessagesSent).Text)
+
+		client.RequestsCh <- meeseeks.Request{
+			Command:   "locks",
+			Username:  "myuser",
+			UserLink:  "<@myuser>",
+			ChannelID: "generalID",
+		}
+
+		replies := make([]string, 0)
+		for i := 0; i < 3; i++ {
+			replies = append(replies, (<-client.MessagesSent).Text)
+		}
+		e.Shutdown()
+
+		joined := strings.Join(replies, "\n")
+		mocks.AssertEquals(t, 1, strings.Count(joined, "- *deploy-prod* - held by job *1* `deploy 0.5` by *myuser* since now, 1 waiting"))
+
+		for id, status := range map[uint64]string{
+			1: meeseeks.JobSuccessStatus,
+			2: meeseeks.JobFailedStatus,
+			3: meeseeks.JobSuccessStatus,
+		} {
+			job, err := persistence.Jobs().Get(id)
+			mocks.Must(t, "could not get job", err)
+			mocks.AssertEquals(t, status, job.Status)
+		}
+
+		first, err := persistence.Jobs().Get(1)
+		mocks.Must(t, "could not get job", err)
+		third, err := persistence.Jobs().Get(3)
+		mocks.Must(t, "could not get job", err)
+		if third.StartTime.Before(first.EndTime) {
+			t.Fatal("migrate started before deploy released the lock")
+		}
+	})
+}
+
+func Test_RequestsOverTheRateLimitAreRejected(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().
+			WithConfig(dedent.Dedent(`
+			---
+			commands:
+			  deploy:
+			    command: echo
+			    auth_strategy: any
+			    no_handshake: true
+			    cooldown: 60
+			`)).WithDBPath(dbpath).Load()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			ConcurrentTaskCount: 20,
+		})
+		e.ListenTo(client)
+
+		go e.Run()
+
+		for i := 0; i < 2; i++ {
+			client.RequestsCh <- meeseeks.Request{
+				Command:   "deploy",
+				Args:      []string{"prod"},
+				Username:  "myuser",
+				UserLink:  "<@myuser>",
+				ChannelID: "generalID",
+			}
+		}
+		replies := []string{(<-client.MessagesSent).Text, (<-client.MessagesSent).Text}
+		e.Shutdown()
+
+		joined := strings.Join(replies, "\n")
+		mocks.AssertEquals(t, 1, strings.Count(joined,
+			"<@myuser> Uuuh, slow down! I can't run deploy: deploy ran in this channel 0s ago, try again in 1m0s"))
+
+		_, err := persistence.Jobs().Get(2)
+		mocks.AssertEquals(t, meeseeks.ErrNoJobWithID, err)
+	})
+}
+
+func Test_ArgumentsAreValidatedBeforeCreatingAJob(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().
+			WithConfig(dedent.Dedent(`
+			---
+			commands:
+			  deploy:
+			    command: echo
+			    auth_strategy: any
+			    no_handshake: true
+			    params:
+			    - name: env
+			      type: enum
+			      values: [staging, prod]
+			      required: true
+			    - name: replicas
+			      type: int
+			      flag: true
+			      default: "2"
+			`)).WithDBPath(dbpath).Load()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			ConcurrentTaskCount: 20,
+		})
+		e.ListenTo(client)
+
+		go e.Run()
+
+		for _, args := range [][]string{{"prod; rm -rf /"}, {"prod"}} {
+			client.RequestsCh <- meeseeks.Request{
+				Command:   "deploy",
+				Args:      args,
+				Username:  "myuser",
+				UserLink:  "<@myuser>",
+				ChannelID: "generalID",
+			}
+		}
+		rejected := (<-client.MessagesSent).Text
+		if !strings.HasSuffix(rejected, ":disappointed: invalid arguments: invalid value prod; rm -rf / for env: expected one of staging, prod") {
+			t.Fatalf("invalid arguments were not rejected: %s", rejected)
+		}
+		succeeded := (<-client.MessagesSent).Text
+		if !strings.HasSuffix(succeeded, "```\nprod --replicas 2\n```") {
+			t.Fatalf("defaults were not passed to the command: %s", succeeded)
+		}
+		e.Shutdown()
+
+		job, err := persistence.Jobs().Get(1)
+		mocks.Must(t, "could not get job", err)
+		mocks.AssertEquals(t, []string{"prod", "--replicas", "2"}, job.Request.Args)
+
+		_, err = persistence.Jobs().Get(2)
+		mocks.AssertEquals(t, meeseeks.ErrNoJobWithID, err)
+	})
+}
+
+func Test_ExitCodesSetTheJobStatus(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().
+			WithConfig(dedent.Dedent(`
+			---
+			commands:
+			  check:
+			    command: sh
+			    args: ["-c", "echo checked; exit $0"]
+			    auth_strategy: any
+			    no_handshake: true
+			    exit_codes:
+			      success: [0, 1]
+			      warning: [2]
+			`)).WithDBPath(dbpath).Load()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			ConcurrentTaskCount: 1,
+		})
+		e.ListenTo(client)
+
+		go e.Run()
+
+		expected := []string{
+			meeseeks.JobSuccessStatus,
+			meeseeks.JobSuccessStatus,
+			meeseeks.JobWarningStatus,
+			meeseeks.JobFailedStatus,
+		}
+		for code := range expected {
+			client.RequestsCh <- meeseeks.Request{
+				Command:   "check",
+				Args:      []string{strconv.Itoa(code)},
+				Username:  "myuser",
+				UserLink:  "<@myuser>",
+				ChannelID: "generalID",
+			}
+			reply := <-client.MessagesSent
+			if code == 2 && !strings.Contains(reply.Text, "Uuuh, done, but with warnings :warning:") {
+				t.Fatalf("exit code 2 was not replied as a warning: %s", reply.Text)
+			}
+		}
+		e.Shutdown()
+
+		for i, status := range expected {
+			job, err := persistence.Jobs().Get(uint64(i + 1))
+			mocks.Must(t, "could not get job", err)
+			mocks.AssertEquals(t, status, job.Status)
+			mocks.AssertEquals(t, i, job.Result.ExitCode)
+		}
+	})
+}
+
+func Test_RunningJobsCanBeCancelledOrKilled(t *testing.T) {
+	mocks.WithTmpDB(func(dbpath string) {
+		client := mocks.NewHarness().
+			WithConfig(dedent.Dedent(`
+			---
+			groups:
+			  admin: ["admin_user"]
+			commands:
+			  sleepy:
+			    command: sh
+			    args: ["-c", "echo started; sleep 10"]
+			    auth_strategy: any
+			    no_handshake: true
+			`)).WithDBPath(dbpath).Load()
+
+		e := executor.New(executor.Args{
+			ChatClient:          client,
+			WithBuiltinCommands: true,
+			ConcurrentTaskCount: 20,
+		})
+		e.ListenTo(client)
+
+		go e.Run()
+
+		tt := []struct {
+			builtin  string
+			username string
+			expected string
+			status   string
+		}{
+			{builtin: "cancel", username: "myuser", expected: "job was cancelled by myuser", status: meeseeks.JobCancelledStatus},
+			{builtin: "kill", username: "admin_user", expected: "job was killed by admin_user", status: meeseeks.JobKilledStatus},
+		}
+		for i, tc := range tt {
+			jobID := uint64(i + 1)
+			client.RequestsCh <- meeseeks.Request{
+				Command:   "sleepy",
+				Username:  "myuser",
+				UserLink:  "<@myuser>",
+				ChannelID: "generalID",
+			}
+			for {
+				logs, err := persistence.LogReader().Get(jobID, meeseeks.StreamStdout)
+				if err == nil && logs.Output == "started" {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			client.RequestsCh <- meeseeks.Request{
+				Command:   tc.builtin,
+				Args:      []string{strconv.FormatUint(jobID, 10)},
+				Username:  tc.username,
+				UserLink:  "<@" + tc.username + ">",
+				ChannelID: "generalID",
+			}
+			replies := (<-client.MessagesSent).Text + "\n" + (<-client.MessagesSent).Text
+			mocks.AssertEquals(t, 1, strings.Count(replies, "<@myuser> Uuuh!, no, it failed :disappointed: "+tc.expected))
+		}
+		e.Shutdown()
+
+		for i, tc := range tt {
+			job, err := persistence.Jobs().Get(uint64(i + 1))
+			mocks.Must(t, "could not get job", err)
+			mocks.AssertEquals(t, tc.status, job.Status)
+			mocks.AssertEquals(t, tc.username, job.StoppedBy)
+		}
+	})
+}
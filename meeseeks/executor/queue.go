@@ -0,0 +1,187 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+)
+
+// taskQueue limits how many tasks run at the same time, both overall and per
+// command, and keeps tasks holding the same lock from running together. Tasks
+// over the limits wait in arrival order. Tasks that are not recorded, like
+// builtin commands, are never limited so jobs can still be listed and
+// cancelled when all the workers are busy
+type taskQueue struct {
+	workers      int
+	running      int
+	runningByCmd map[string]int
+	locks        map[string]task
+	waiting      []task
+	m            sync.Mutex
+}
+
+func newTaskQueue(workers int) *taskQueue {
+	return &taskQueue{
+		workers:      workers,
+		runningByCmd: make(map[string]int),
+		locks:        make(map[string]task),
+		waiting:      make([]task, 0),
+	}
+}
+
+// Push starts the task when it fits in the limits, returning true. When it
+// doesn't, the task is queued and Push returns false along with how many jobs
+// it is waiting behind. It fails when the lock of the task is held and its
+// command rejects jobs instead of queueing them
+func (q *taskQueue) Push(t task) (bool, int, error) {
+	if !t.cmd.MustRecord() {
+		return true, 0, nil
+	}
+
+	defer q.m.Unlock()
+	q.m.Lock()
+
+	if holder, ok := q.lockHolder(t); ok && lockPolicy(t) == meeseeks.LockPolicyReject {
+		return false, 0, fmt.Errorf("lock %s is held by job %d", lockName(t), holder.job.ID)
+	}
+
+	if q.globalFull() {
+		ahead := q.running + len(q.waiting)
+		q.waiting = append(q.waiting, t)
+		return false, ahead, nil
+	}
+	if q.commandFull(t) {
+		ahead := q.runningByCmd[t.job.Request.Command]
+		for _, w := range q.waiting {
+			if w.job.Request.Command == t.job.Request.Command {
+				ahead++
+			}
+		}
+		q.waiting = append(q.waiting, t)
+		return false, ahead, nil
+	}
+	if _, ok := q.lockHolder(t); ok {
+		ahead := 1 + q.waitingFor(lockName(t))
+		q.waiting = append(q.waiting, t)
+		return false, ahead, nil
+	}
+
+	q.start(t)
+	return true, 0, nil
+}
+
+// Done frees the slot of a finished task and returns the queued tasks that
+// can start now
+func (q *taskQueue) Done(t task) []task {
+	if !t.cmd.MustRecord() {
+		return nil
+	}
+
+	defer q.m.Unlock()
+	q.m.Lock()
+
+	q.running--
+	q.runningByCmd[t.job.Request.Command]--
+	if lock := lockName(t); lock != "" {
+		delete(q.locks, lock)
+	}
+
+	started := make([]task, 0)
+	waiting := make([]task, 0, len(q.waiting))
+	for _, w := range q.waiting {
+		if _, locked := q.lockHolder(w); locked || q.globalFull() || q.commandFull(w) {
+			waiting = append(waiting, w)
+			continue
+		}
+		q.start(w)
+		started = append(started, w)
+	}
+	q.waiting = waiting
+	return started
+}
+
+// Remove drops a queued task, returning false if there is no such task waiting
+func (q *taskQueue) Remove(jobID uint64) (task, bool) {
+	defer q.m.Unlock()
+	q.m.Lock()
+
+	for i, w := range q.waiting {
+		if w.job.ID == jobID {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return w, true
+		}
+	}
+	return task{}, false
+}
+
+// Locks returns the locks held by running jobs sorted by name
+func (q *taskQueue) Locks() []meeseeks.Lock {
+	defer q.m.Unlock()
+	q.m.Lock()
+
+	locks := make([]meeseeks.Lock, 0, len(q.locks))
+	for name, holder := range q.locks {
+		locks = append(locks, meeseeks.Lock{
+			Name:    name,
+			Job:     holder.job,
+			Waiting: q.waitingFor(name),
+		})
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].Name < locks[j].Name })
+	return locks
+}
+
+func (q *taskQueue) start(t task) {
+	q.running++
+	q.runningByCmd[t.job.Request.Command]++
+	if lock := lockName(t); lock != "" {
+		q.locks[lock] = t
+	}
+}
+
+func (q *taskQueue) lockHolder(t task) (task, bool) {
+	lock := lockName(t)
+	if lock == "" {
+		return task{}, false
+	}
+	holder, ok := q.locks[lock]
+	return holder, ok
+}
+
+func (q *taskQueue) waitingFor(lock string) int {
+	waiting := 0
+	for _, w := range q.waiting {
+		if lockName(w) == lock {
+			waiting++
+		}
+	}
+	return waiting
+}
+
+func (q *taskQueue) globalFull() bool {
+	return q.workers > 0 && q.running >= q.workers
+}
+
+func (q *taskQueue) commandFull(t task) bool {
+	c, ok := t.cmd.(meeseeks.LimitedCommand)
+	if !ok || c.GetMaxConcurrency() <= 0 {
+		return false
+	}
+	return q.runningByCmd[t.job.Request.Command] >= c.GetMaxConcurrency()
+}
+
+func lockName(t task) string {
+	if c, ok := t.cmd.(meeseeks.LockedCommand); ok {
+		return c.GetLock()
+	}
+	return ""
+}
+
+func lockPolicy(t task) string {
+	if c, ok := t.cmd.(meeseeks.LockedCommand); ok {
+		return c.GetLockPolicy()
+	}
+	return meeseeks.LockPolicyQueue
+}
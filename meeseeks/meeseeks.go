@@ -3,12 +3,14 @@ package meeseeks
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 )
 
 // Defaults for commands
 const (
 	DefaultCommandTimeout = 60 * time.Second
+	DefaultGracePeriod    = 10 * time.Second
 )
 
 // Message interface to interact with an abstract message
@@ -29,6 +31,8 @@ type Message interface {
 	GetUserLink() string
 	// IsIM
 	IsIM() bool
+	// The thread the message belongs to, used to reply in the same thread
+	GetThreadID() string
 }
 
 // LoggerProvider wraps the specific logger implementation
@@ -39,21 +43,35 @@ type LoggerProvider interface {
 
 // LogWriter is an interface to write logs to a given job
 type LogWriter interface {
-	Append(jobID uint64, content string) error
+	Append(jobID uint64, line LogLine) error
 	SetError(jobID uint64, jobErr error) error
 }
 
 // ErrNoLogsForJob is returned when we try to extract the logs of a non existing job
 var ErrNoLogsForJob = errors.New("No logs for job")
 
-// LogReader is an interface to read logs from a given job
+// LogReader is an interface to read logs from a given job, the lines can be
+// filtered by stream, an empty stream returns all of them
 type LogReader interface {
 	// Returns the whole log output of a given job
-	Get(jobID uint64) (JobLog, error)
+	Get(jobID uint64, stream string) (JobLog, error)
 	// Head returns the top <limit> log lines
-	Head(jobID uint64, limit int) (JobLog, error)
+	Head(jobID uint64, stream string, limit int) (JobLog, error)
 	// Tail returns the bottm <limit> log lines
-	Tail(jobID uint64, limit int) (JobLog, error)
+	Tail(jobID uint64, stream string, limit int) (JobLog, error)
+}
+
+// Streams a command writes its output to
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// LogLine is a line of the output of a job
+type LogLine struct {
+	Line   string    `json:"Line"`
+	Stream string    `json:"Stream"`
+	Time   time.Time `json:"Time"`
 }
 
 // Request is a structure that holds a command execution request
@@ -67,6 +85,15 @@ type Request struct {
 	ChannelID   string   `json:"CannelID"`
 	ChannelLink string   `json:"CannelLink"`
 	IsIM        bool     `json:"IsIM"`
+
+	// Origin is the chat backend the request came from, replies are routed back through it
+	Origin string `json:"Origin,omitempty"`
+
+	// ThreadID identifies the thread of the message that triggered the request
+	ThreadID string `json:"ThreadID,omitempty"`
+
+	// ResponseURL is an optional url through which replies should be delivered
+	ResponseURL string `json:"-"`
 }
 
 // Job represents a request that matched a command and can be executed
@@ -76,6 +103,46 @@ type Job struct {
 	StartTime time.Time `json:"StartTime"`
 	EndTime   time.Time `json:"EndTime"`
 	Status    string    `json:"Status"`
+
+	// Approvals are the decisions taken on a job that required approval
+	Approvals []Approval `json:"Approvals,omitempty"`
+
+	// Result is how the job ran, nil when the command doesn't report it
+	Result *JobResult `json:"Result,omitempty"`
+
+	// StoppedBy is the user that cancelled or killed the job
+	StoppedBy string `json:"StoppedBy,omitempty"`
+}
+
+// Duration returns how long the job ran, 0 while it is still running
+func (j Job) Duration() time.Duration {
+	if j.EndTime.IsZero() {
+		return 0
+	}
+	return j.EndTime.Sub(j.StartTime).Round(time.Millisecond)
+}
+
+// JobResult is how the process of a job ran and where
+type JobResult struct {
+	// ExitCode is -1 when the process didn't exit on its own
+	ExitCode int    `json:"ExitCode"`
+	Signal   string `json:"Signal,omitempty"`
+
+	// AgentID is empty when the job ran in the meeseeks itself
+	AgentID  string `json:"AgentID,omitempty"`
+	Hostname string `json:"Hostname"`
+
+	OutputBytes uint64 `json:"OutputBytes"`
+	// Truncated is set when part of the output could not be recorded
+	Truncated bool `json:"Truncated,omitempty"`
+}
+
+// Approval is the decision of a user on a job that is pending approval
+type Approval struct {
+	Username string    `json:"Username"`
+	UserID   string    `json:"UserID"`
+	Approved bool      `json:"Approved"`
+	Time     time.Time `json:"Time"`
 }
 
 // JobLog represents all the logging information of a given Job
@@ -99,6 +166,9 @@ type APIToken struct {
 	ChannelLink string    `json:"channelLink"`
 	Text        string    `json:"text"`
 	CreatedOn   time.Time `json:"created_on"`
+
+	// Origin is the chat backend the user and channel links belong to
+	Origin string `json:"origin,omitempty"`
 }
 
 // Command is the base interface for any command
@@ -117,6 +187,98 @@ type Command interface {
 	MustRecord() bool
 }
 
+// ConfirmableCommand is implemented by the commands that can ask the user to
+// confirm a request before running it
+type ConfirmableCommand interface {
+	MustConfirm() bool
+}
+
+// ApprovableCommand is implemented by the commands that can require other users
+// to approve a job before running it
+type ApprovableCommand interface {
+	GetApprovers() []string
+	GetRequiredApprovals() int
+}
+
+// LimitedCommand is implemented by the commands that limit how many of their
+// jobs can run at the same time
+type LimitedCommand interface {
+	GetMaxConcurrency() int
+}
+
+// LockedCommand is implemented by the commands whose jobs hold a named lock
+// while they run, so no other job holding the same lock runs at the same time
+type LockedCommand interface {
+	GetLock() string
+	GetLockPolicy() string
+}
+
+// CooldownCommand is implemented by the commands that can't run again in the
+// same channel until some time has passed
+type CooldownCommand interface {
+	GetCooldown() time.Duration
+}
+
+// ReportingCommand is implemented by the commands that report how their
+// jobs ran along with the output
+type ReportingCommand interface {
+	ExecuteWithResult(context.Context, Job) (string, JobResult, error)
+}
+
+// ExitCodesCommand is implemented by the commands that decide the status of
+// their jobs from the exit code of the process
+type ExitCodesCommand interface {
+	GetSuccessExitCodes() []int
+	GetWarningExitCodes() []int
+}
+
+// GracefulCommand is implemented by the commands that are given some time to
+// finish when their jobs are cancelled
+type GracefulCommand interface {
+	GetGracePeriod() time.Duration
+}
+
+// ParameterizedCommand is implemented by the commands that declare the
+// arguments they accept, so requests are validated before they run
+type ParameterizedCommand interface {
+	GetParams() []Param
+}
+
+// Param types
+const (
+	ParamTypeString   = "string"
+	ParamTypeInt      = "int"
+	ParamTypeBool     = "bool"
+	ParamTypeEnum     = "enum"
+	ParamTypeDuration = "duration"
+)
+
+// Param is an argument a command accepts, either positional, in the order
+// they are declared, or as a --name flag
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+	Regex       string
+	Values      []string
+	Required    bool
+	Default     string
+	Flag        bool
+}
+
+// Lock policies, they define what happens to a job when its lock is held
+const (
+	LockPolicyQueue  = "queue"
+	LockPolicyReject = "reject"
+)
+
+// Lock is a named lock held by a running job
+type Lock struct {
+	Name    string
+	Job     Job
+	Waiting int
+}
+
 // Help is the base interface for any command help
 type Help interface {
 	GetSummary() string
@@ -138,10 +300,16 @@ type JobFilter struct {
 
 // Jobs status
 const (
-	JobRunningStatus = "Running"
-	JobFailedStatus  = "Failed"
-	JobKilledStatus  = "Killed"
-	JobSuccessStatus = "Successful"
+	JobRunningStatus   = "Running"
+	JobQueuedStatus    = "Queued"
+	JobFailedStatus    = "Failed"
+	JobKilledStatus    = "Killed"
+	JobCancelledStatus = "Cancelled"
+	JobSuccessStatus   = "Successful"
+	JobWarningStatus   = "Warning"
+
+	JobPendingApprovalStatus = "PendingApproval"
+	JobDeniedStatus          = "Denied"
 )
 
 // Jobs provides an interface to handle persistent access to recorded jobs
@@ -161,6 +329,24 @@ type Jobs interface {
 	// Succeed accounds for the job ending and sets the status.
 	Succeed(jobID uint64) error
 
+	// Warn accounts for the job ending with warnings and sets the status.
+	Warn(jobID uint64) error
+
+	// Cancel accounts for the job being cancelled by the user and sets the status.
+	Cancel(jobID uint64, username string) error
+
+	// Kill accounts for the job being killed by the user and sets the status.
+	Kill(jobID uint64, username string) error
+
+	// Queue flags a running job as queued while it waits for a free worker
+	Queue(jobID uint64) error
+
+	// Start flags a queued job as running again, resetting its start time
+	Start(jobID uint64) error
+
+	// SetResult records how the process of a job ran
+	SetResult(jobID uint64, result JobResult) error
+
 	// Find will walk through the values on the jobs bucket and will apply the Match function
 	// to determine if the job matches a search criteria.
 	//
@@ -169,15 +355,28 @@ type Jobs interface {
 
 	// FailRunningJobs flags as failed any jobs that is still in running state
 	FailRunningJobs() error
+
+	// RequestApproval records a request in the DB as a job pending approval
+	RequestApproval(r Request) (Job, error)
+
+	// AddApproval records a decision on a job pending approval. A denial
+	// finishes the job, while reaching the required approvals sets it running.
+	AddApproval(jobID uint64, approval Approval, requiredApprovals int) (Job, error)
 }
 
 // ErrNoJobWithID is returned when we can't find a job with the proposed id
 var ErrNoJobWithID = errors.New("no job could be found")
 
+// ErrJobNotPendingApproval is returned when deciding on a job that is not waiting for approval
+var ErrJobNotPendingApproval = errors.New("job is not pending approval")
+
+// ErrAlreadyDecided is returned when a user tries to decide twice on the same job
+var ErrAlreadyDecided = errors.New("user already decided on this job")
+
 // APITokens provides an interface to handle persisted api tokens
 type APITokens interface {
 	// Create creates a new token persistence record and returns the created token.
-	Create(userLink, channelLink, text string) (string, error)
+	Create(userLink, channelLink, text, origin string) (string, error)
 
 	// Get returns the token given an ID, it may return ErrTokenNotFound when there is no such token
 	Get(tokenID string) (APIToken, error)
@@ -210,6 +409,49 @@ type Aliases interface {
 	Remove(userID, alias string) error
 }
 
+// Schedule is a request a user scheduled to run later, either once or periodically
+type Schedule struct {
+	ID      uint64  `json:"ID"`
+	Request Request `json:"Request"`
+
+	// Spec is the schedule as the user wrote it
+	Spec string `json:"Spec"`
+	// Cron is the cron expression of a recurring schedule, empty when it runs once
+	Cron string `json:"Cron,omitempty"`
+	// RunAt is when a schedule that runs once is due
+	RunAt time.Time `json:"RunAt,omitempty"`
+
+	CreatedOn time.Time `json:"CreatedOn"`
+}
+
+// IsRecurring returns whether the schedule runs periodically
+func (s Schedule) IsRecurring() bool {
+	return s.Cron != ""
+}
+
+// ScheduleFilter is used to filter the schedules to be returned from a Find query
+type ScheduleFilter struct {
+	Match func(Schedule) bool
+}
+
+// Schedules provides an interface to handle persisted schedules
+type Schedules interface {
+	// Create records a new schedule and returns it with its ID set
+	Create(s Schedule) (Schedule, error)
+
+	// Get returns a schedule by ID, it may return ErrNoScheduleWithID
+	Get(id uint64) (Schedule, error)
+
+	// Remove deletes a schedule by ID
+	Remove(id uint64) error
+
+	// Find returns all the schedules that match the filter in creation order
+	Find(filter ScheduleFilter) ([]Schedule, error)
+}
+
+// ErrNoScheduleWithID is returned when we can't find a schedule with the proposed id
+var ErrNoScheduleWithID = errors.New("no schedule could be found")
+
 // CommandOpts are the options used to build a new shell command
 type CommandOpts struct {
 	Cmd             string
@@ -219,8 +461,59 @@ type CommandOpts struct {
 	AllowedChannels []string
 	ChannelStrategy string
 	Handshake       bool
+	Confirm         bool
 	Timeout         time.Duration
 	Help            Help
+
+	Approvers         []string
+	RequiredApprovals int
+
+	MaxConcurrency int
+
+	Lock       string
+	LockPolicy string
+
+	Cooldown time.Duration
+
+	Params []Param
+
+	// Env is added to the environment of the command
+	Env map[string]string
+	// CleanEnv keeps the command from inheriting the environment of the meeseeks
+	CleanEnv bool
+
+	// WorkDir is the directory the command runs in, the one of the meeseeks when empty
+	WorkDir string
+	// User and Group the command runs as, the ones of the meeseeks when empty
+	User  string
+	Group string
+	// Limits are the resource limits of the command process
+	Limits ResourceLimits
+	// GracePeriod is how long a cancelled command has to exit before it is killed
+	GracePeriod time.Duration
+
+	// SuccessExitCodes and WarningExitCodes map the exit codes of the process
+	// to job statuses, any other exit code fails the job
+	SuccessExitCodes []int
+	WarningExitCodes []int
+}
+
+// ResourceLimits are the resource limits applied to a command process before
+// it starts, 0 leaves a limit as the meeseeks has it
+type ResourceLimits struct {
+	// CPU is the CPU time in seconds
+	CPU uint64
+	// AddressSpace is the size of the virtual memory in bytes
+	AddressSpace uint64
+	// OpenFiles is the number of open file descriptors
+	OpenFiles uint64
+	// Processes is the number of processes of the user the command runs as
+	Processes uint64
+}
+
+// IsZero returns whether no limit is set
+func (l ResourceLimits) IsZero() bool {
+	return l == ResourceLimits{}
 }
 
 // HasHandshake indicates if this command should show the handshake message or not
@@ -291,6 +584,110 @@ func (o CommandOpts) MustRecord() bool {
 	return true
 }
 
+// MustConfirm returns whether the user has to confirm the request before running it
+func (o CommandOpts) MustConfirm() bool {
+	return o.Confirm
+}
+
+// GetApprovers returns the groups whose users can approve jobs of this command
+func (o CommandOpts) GetApprovers() []string {
+	if o.Approvers == nil {
+		return []string{}
+	}
+	return o.Approvers
+}
+
+// GetRequiredApprovals returns how many approvals a job needs before running,
+// 1 when there are approvers but no count and 0 when no approval is required
+func (o CommandOpts) GetRequiredApprovals() int {
+	if o.RequiredApprovals == 0 && len(o.Approvers) > 0 {
+		return 1
+	}
+	return o.RequiredApprovals
+}
+
+// GetMaxConcurrency returns how many jobs of this command can run at the same time, 0 means no limit
+func (o CommandOpts) GetMaxConcurrency() int {
+	return o.MaxConcurrency
+}
+
+// GetLock returns the name of the lock held by the jobs of this command, empty when there is none
+func (o CommandOpts) GetLock() string {
+	return o.Lock
+}
+
+// GetLockPolicy returns what happens to a job when its lock is held, queue by default
+func (o CommandOpts) GetLockPolicy() string {
+	if o.LockPolicy == "" {
+		return LockPolicyQueue
+	}
+	return o.LockPolicy
+}
+
+// GetCooldown returns how long the command can't run again in the same channel, 0 means it can
+func (o CommandOpts) GetCooldown() time.Duration {
+	return o.Cooldown
+}
+
+// GetParams returns the arguments this command accepts, none means they are not checked
+func (o CommandOpts) GetParams() []Param {
+	return o.Params
+}
+
+// GetEnv returns the variables added to the environment of the command
+func (o CommandOpts) GetEnv() map[string]string {
+	if o.Env == nil {
+		return map[string]string{}
+	}
+	return o.Env
+}
+
+// InheritsEnv returns whether the command runs with the environment of the meeseeks
+func (o CommandOpts) InheritsEnv() bool {
+	return !o.CleanEnv
+}
+
+// GetWorkDir returns the directory the command runs in
+func (o CommandOpts) GetWorkDir() string {
+	return o.WorkDir
+}
+
+// GetGracePeriod returns how long a cancelled command has to exit before it is killed
+func (o CommandOpts) GetGracePeriod() time.Duration {
+	if o.GracePeriod == 0 {
+		return DefaultGracePeriod
+	}
+	return o.GracePeriod
+}
+
+// GetUser returns the user the command runs as
+func (o CommandOpts) GetUser() string {
+	return o.User
+}
+
+// GetGroup returns the group the command runs as
+func (o CommandOpts) GetGroup() string {
+	return o.Group
+}
+
+// GetLimits returns the resource limits of the command process
+func (o CommandOpts) GetLimits() ResourceLimits {
+	return o.Limits
+}
+
+// GetSuccessExitCodes returns the exit codes that succeed a job, 0 by default
+func (o CommandOpts) GetSuccessExitCodes() []int {
+	if len(o.SuccessExitCodes) == 0 {
+		return []int{0}
+	}
+	return o.SuccessExitCodes
+}
+
+// GetWarningExitCodes returns the exit codes that finish a job with warnings
+func (o CommandOpts) GetWarningExitCodes() []int {
+	return o.WarningExitCodes
+}
+
 // CommandHelp represents the help of a given command
 type CommandHelp struct {
 	summary string
@@ -314,3 +711,56 @@ func NewHelp(summary string, args ...string) Help {
 		append([]string{}, args...),
 	}
 }
+
+// Ways of stopping a running job
+const (
+	// StopCancel asks the job to finish, giving it a grace period to do so
+	StopCancel = "cancel"
+	// StopKill finishes the job right away
+	StopKill = "kill"
+)
+
+// Stop is how and by whom a running job is stopped
+type Stop struct {
+	Kind     string
+	Username string
+}
+
+type stopKey struct{}
+
+type stopper struct {
+	stop *Stop
+	m    sync.Mutex
+}
+
+// WithStop returns a copy of the parent context that is cancelled by calling
+// the returned function, which first records how the job is being stopped.
+// Calling it with a zero Stop only cancels the context
+func WithStop(parent context.Context) (context.Context, func(Stop)) {
+	s := &stopper{}
+	ctx, cancel := context.WithCancel(context.WithValue(parent, stopKey{}, s))
+	return ctx, func(stop Stop) {
+		s.m.Lock()
+		if s.stop == nil && stop != (Stop{}) {
+			s.stop = &stop
+		}
+		s.m.Unlock()
+		cancel()
+	}
+}
+
+// StopOf returns how the job running with the context was stopped, false
+// when it wasn't
+func StopOf(ctx context.Context) (Stop, bool) {
+	s, ok := ctx.Value(stopKey{}).(*stopper)
+	if !ok {
+		return Stop{}, false
+	}
+	defer s.m.Unlock()
+	s.m.Lock()
+
+	if s.stop == nil {
+		return Stop{}, false
+	}
+	return *s.stop, true
+}
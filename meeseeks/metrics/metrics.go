@@ -39,6 +39,13 @@ var RejectedCommandsCount = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Help:      "Commands that have been rejected due to an auth failure",
 }, []string{"command"})
 
+// RateLimitedCommandsCount is the count of commands that have been rejected by a rate limit
+var RateLimitedCommandsCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "rate_limited_commands_count",
+	Help:      "Commands that have been rejected due to a rate limit or a cooldown",
+}, []string{"command", "limit"})
+
 // AcceptedCommandsCount is the count of commands that have been accepted
 var AcceptedCommandsCount = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Namespace: namespace,
@@ -87,6 +94,7 @@ func RegisterServerMetrics() {
 	prometheus.MustRegister(AliasedCommandsCount)
 	prometheus.MustRegister(UnknownCommandsCount)
 	prometheus.MustRegister(RejectedCommandsCount)
+	prometheus.MustRegister(RateLimitedCommandsCount)
 	prometheus.MustRegister(AcceptedCommandsCount)
 	prometheus.MustRegister(TaskDurations)
 	prometheus.MustRegister(LogLinesCount)
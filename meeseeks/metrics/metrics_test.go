@@ -17,6 +17,7 @@ func TestRegisteringServerMetrics(t *testing.T) {
 	mocks.AssertEquals(t, true, prometheus.Unregister(metrics.LogLinesCount))
 	mocks.AssertEquals(t, true, prometheus.Unregister(metrics.ReceivedCommandsCount))
 	mocks.AssertEquals(t, true, prometheus.Unregister(metrics.RejectedCommandsCount))
+	mocks.AssertEquals(t, true, prometheus.Unregister(metrics.RateLimitedCommandsCount))
 	mocks.AssertEquals(t, true, prometheus.Unregister(metrics.TaskDurations))
 	mocks.AssertEquals(t, true, prometheus.Unregister(metrics.UnknownCommandsCount))
 }
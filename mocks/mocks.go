@@ -25,6 +25,8 @@ type SentMessage struct {
 	Channel string
 	Color   string
 	IsIM    bool
+
+	ConfirmationID string
 }
 
 // Harness is a builder that helps out testing meeseeks
@@ -89,7 +91,7 @@ func (h Harness) Load() ClientStub {
 		fmt.Printf("Failed to load configuration: %s", err)
 		return ClientStub{}
 	}
-	return newClientStub()
+	return NewClientStub()
 }
 
 // ClientStub is an extremely simple implementation of a client that only captures messages
@@ -102,7 +104,7 @@ type ClientStub struct {
 }
 
 // NewClientStub returns a new empty but intialized Client stub
-func newClientStub() ClientStub {
+func NewClientStub() ClientStub {
 	return ClientStub{
 		MessagesSent: make(chan SentMessage),
 		RequestsCh:   make(chan meeseeks.Request),
@@ -116,7 +118,7 @@ func (c ClientStub) Reply(r formatter.Reply) {
 	if err != nil {
 		logrus.Error(err)
 	}
-	c.MessagesSent <- SentMessage{Text: text, Channel: r.ChannelID()}
+	c.MessagesSent <- SentMessage{Text: text, Channel: r.ChannelID(), ConfirmationID: r.ConfirmationID()}
 }
 
 // Listen listens for requests and then passes them to the passed in channel
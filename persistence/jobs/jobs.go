@@ -35,12 +35,42 @@ func (Jobs) Create(r meeseeks.Request) (meeseeks.Job, error) {
 
 // Fail accounds for the job ending and sets the status.
 func (Jobs) Fail(jobID uint64) error {
-	return finish(jobID, meeseeks.JobFailedStatus)
+	return finish(jobID, meeseeks.JobFailedStatus, "")
 }
 
 // Succeed accounds for the job ending and sets the status.
 func (Jobs) Succeed(jobID uint64) error {
-	return finish(jobID, meeseeks.JobSuccessStatus)
+	return finish(jobID, meeseeks.JobSuccessStatus, "")
+}
+
+// Warn accounts for the job ending with warnings and sets the status.
+func (Jobs) Warn(jobID uint64) error {
+	return finish(jobID, meeseeks.JobWarningStatus, "")
+}
+
+// Cancel accounts for the job being cancelled by the user and sets the status.
+func (Jobs) Cancel(jobID uint64, username string) error {
+	return finish(jobID, meeseeks.JobCancelledStatus, username)
+}
+
+// Kill accounts for the job being killed by the user and sets the status.
+func (Jobs) Kill(jobID uint64, username string) error {
+	return finish(jobID, meeseeks.JobKilledStatus, username)
+}
+
+// Queue flags a running job as queued while it waits for a free worker
+func (Jobs) Queue(jobID uint64) error {
+	return setStatus(jobID, meeseeks.JobRunningStatus, meeseeks.JobQueuedStatus)
+}
+
+// Start flags a queued job as running again, resetting its start time
+func (Jobs) Start(jobID uint64) error {
+	return setStatus(jobID, meeseeks.JobQueuedStatus, meeseeks.JobRunningStatus)
+}
+
+// SetResult records how the process of a job ran
+func (Jobs) SetResult(jobID uint64, result meeseeks.JobResult) error {
+	return setResult(jobID, result)
 }
 
 // FailRunningJobs flags as failed any jobs that is still in running state
@@ -56,6 +86,17 @@ func (Jobs) Find(filter meeseeks.JobFilter) ([]meeseeks.Job, error) {
 	return find(filter)
 }
 
+// RequestApproval records a request in the DB as a job pending approval
+func (Jobs) RequestApproval(r meeseeks.Request) (meeseeks.Job, error) {
+	return requestApproval(r)
+}
+
+// AddApproval records a decision on a job pending approval. A denial
+// finishes the job, while reaching the required approvals sets it running.
+func (Jobs) AddApproval(jobID uint64, approval meeseeks.Approval, requiredApprovals int) (meeseeks.Job, error) {
+	return addApproval(jobID, approval, requiredApprovals)
+}
+
 func null(req meeseeks.Request) meeseeks.Job {
 	return meeseeks.Job{
 		ID:        0,
@@ -97,6 +138,85 @@ func create(req meeseeks.Request) (meeseeks.Job, error) {
 	return *job, nil
 }
 
+func requestApproval(req meeseeks.Request) (meeseeks.Job, error) {
+	var job *meeseeks.Job
+	err := db.Update(func(tx *bolt.Tx) error {
+		jobID, bucket, err := db.NextSequenceFor(jobsBucketKey, tx)
+		if err != nil {
+			return fmt.Errorf("could not get next sequence for %s: %s", string(jobsBucketKey), err)
+		}
+
+		job = &meeseeks.Job{
+			ID:        jobID,
+			Request:   req,
+			StartTime: time.Now().UTC(),
+			Status:    meeseeks.JobPendingApprovalStatus,
+		}
+		logrus.Debugf("Creating job pending approval %#v", job)
+
+		return save(*job, bucket)
+	})
+	if err != nil {
+		return meeseeks.Job{}, fmt.Errorf("failed to create a job %s", err)
+	}
+	return *job, nil
+}
+
+func addApproval(jobID uint64, approval meeseeks.Approval, requiredApprovals int) (meeseeks.Job, error) {
+	var job meeseeks.Job
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(jobsBucketKey)
+		if bucket == nil {
+			return meeseeks.ErrNoJobWithID
+		}
+		payload := bucket.Get(db.IDToBytes(jobID))
+		if payload == nil {
+			return meeseeks.ErrNoJobWithID
+		}
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("could not read job %d from bucket: %s", jobID, err)
+		}
+
+		if job.Status != meeseeks.JobPendingApprovalStatus {
+			return meeseeks.ErrJobNotPendingApproval
+		}
+		approvals := 0
+		for _, a := range job.Approvals {
+			if a.Username == approval.Username {
+				return meeseeks.ErrAlreadyDecided
+			}
+			if a.Approved {
+				approvals++
+			}
+		}
+		job.Approvals = append(job.Approvals, approval)
+
+		switch {
+		case !approval.Approved:
+			job.Status = meeseeks.JobDeniedStatus
+			job.EndTime = time.Now().UTC()
+
+		case approvals+1 >= requiredApprovals:
+			job.Status = meeseeks.JobRunningStatus
+			job.StartTime = time.Now().UTC()
+
+			runningJobsBucket, err := tx.CreateBucketIfNotExists(runningJobsBucketKey)
+			if err != nil {
+				return fmt.Errorf("could not create running jobs bucket: %s", err)
+			}
+			if err = runningJobsBucket.Put(db.IDToBytes(job.ID), []byte(meeseeks.JobRunningStatus)); err != nil {
+				return fmt.Errorf("could not save running job ID %d: %s", jobID, err)
+			}
+		}
+
+		return save(job, bucket)
+	})
+	if err != nil {
+		return meeseeks.Job{}, err
+	}
+	return job, nil
+}
+
 func get(id uint64) (meeseeks.Job, error) {
 	job := &meeseeks.Job{}
 	err := db.View(func(tx *bolt.Tx) error {
@@ -114,11 +234,45 @@ func get(id uint64) (meeseeks.Job, error) {
 	return *job, err
 }
 
+// setStatus moves a job that is still in the running jobs list from one status to another
+func setStatus(jobID uint64, from, to string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		job, err := get(jobID)
+		if err != nil {
+			return fmt.Errorf("could not get job with id %d: %s", jobID, err)
+		}
+		if job.Status != from {
+			return fmt.Errorf("job is not in %s status but %s", from, job.Status)
+		}
+
+		job.Status = to
+		if to == meeseeks.JobRunningStatus {
+			job.StartTime = time.Now().UTC()
+		}
+		return save(job, tx.Bucket(jobsBucketKey))
+	})
+}
+
+func setResult(jobID uint64, result meeseeks.JobResult) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		job, err := get(jobID)
+		if err != nil {
+			return fmt.Errorf("could not get job with id %d: %s", jobID, err)
+		}
+
+		job.Result = &result
+		return save(job, tx.Bucket(jobsBucketKey))
+	})
+}
+
 // Finish sets the status of a job to whatever end state if it's current status is running
 //
-// It also sets the end time of the job
-func finish(jobID uint64, status string) error {
-	if !(status == meeseeks.JobSuccessStatus || status == meeseeks.JobFailedStatus) {
+// It also sets the end time of the job, and the user that stopped it if any
+func finish(jobID uint64, status, stoppedBy string) error {
+	switch status {
+	case meeseeks.JobSuccessStatus, meeseeks.JobWarningStatus, meeseeks.JobFailedStatus,
+		meeseeks.JobCancelledStatus, meeseeks.JobKilledStatus:
+	default:
 		return fmt.Errorf("invalid status %s", status)
 	}
 	return db.Update(func(tx *bolt.Tx) error {
@@ -127,7 +281,7 @@ func finish(jobID uint64, status string) error {
 		if err != nil {
 			return fmt.Errorf("could not get job with id %d: %s", jobID, err)
 		}
-		if job.Status != meeseeks.JobRunningStatus {
+		if job.Status != meeseeks.JobRunningStatus && job.Status != meeseeks.JobQueuedStatus {
 			return fmt.Errorf("job is not in running status but %s", job.Status)
 		}
 		runningJobsBucket := tx.Bucket(runningJobsBucketKey)
@@ -137,6 +291,7 @@ func finish(jobID uint64, status string) error {
 
 		job.EndTime = time.Now().UTC()
 		job.Status = status
+		job.StoppedBy = stoppedBy
 
 		difference := job.EndTime.Sub(job.StartTime)
 		metrics.TaskDurations.WithLabelValues(job.Request.Command, status).Observe(difference.Seconds())
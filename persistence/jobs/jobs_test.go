@@ -149,3 +149,72 @@ func TestNullWorks(t *testing.T) {
 	mocks.AssertEquals(t, uint64(0), n.ID)
 	mocks.AssertEquals(t, meeseeks.JobRunningStatus, n.Status)
 }
+
+func Test_ApprovingAJob(t *testing.T) {
+	mocks.Must(t, "failed to run tests", mocks.WithTmpDB(func(_ string) {
+		job, err := persistence.Jobs().RequestApproval(req)
+		mocks.Must(t, "Could not store a job: ", err)
+		mocks.AssertEquals(t, meeseeks.JobPendingApprovalStatus, job.Status)
+
+		first := meeseeks.Approval{Username: "first", Approved: true}
+		job, err = persistence.Jobs().AddApproval(job.ID, first, 2)
+		mocks.Must(t, "Could not approve a job: ", err)
+		mocks.AssertEquals(t, meeseeks.JobPendingApprovalStatus, job.Status)
+
+		_, err = persistence.Jobs().AddApproval(job.ID, first, 2)
+		mocks.AssertEquals(t, meeseeks.ErrAlreadyDecided, err)
+
+		second := meeseeks.Approval{Username: "second", Approved: true}
+		job, err = persistence.Jobs().AddApproval(job.ID, second, 2)
+		mocks.Must(t, "Could not approve a job: ", err)
+		mocks.AssertEquals(t, meeseeks.JobRunningStatus, job.Status)
+
+		actual, err := persistence.Jobs().Get(job.ID)
+		mocks.Must(t, "Could not retrieve a job: ", err)
+		mocks.AssertEquals(t, []meeseeks.Approval{first, second}, actual.Approvals)
+
+		_, err = persistence.Jobs().AddApproval(job.ID, meeseeks.Approval{Username: "third"}, 2)
+		mocks.AssertEquals(t, meeseeks.ErrJobNotPendingApproval, err)
+
+		mocks.Must(t, "could not set as successful", persistence.Jobs().Succeed(job.ID))
+	}))
+}
+
+func Test_DenyingAJob(t *testing.T) {
+	mocks.Must(t, "failed to run tests", mocks.WithTmpDB(func(_ string) {
+		job, err := persistence.Jobs().RequestApproval(req)
+		mocks.Must(t, "Could not store a job: ", err)
+
+		job, err = persistence.Jobs().AddApproval(job.ID, meeseeks.Approval{Username: "approver"}, 1)
+		mocks.Must(t, "Could not deny a job: ", err)
+		mocks.AssertEquals(t, meeseeks.JobDeniedStatus, job.Status)
+		if job.EndTime.IsZero() {
+			t.Fatal("End time should be set on denied jobs")
+		}
+	}))
+}
+
+func Test_QueueingAJob(t *testing.T) {
+	mocks.Must(t, "failed to run tests", mocks.WithTmpDB(func(_ string) {
+		job, err := persistence.Jobs().Create(req)
+		mocks.Must(t, "Could not store a job: ", err)
+
+		mocks.Must(t, "could not queue the job", persistence.Jobs().Queue(job.ID))
+		queued, err := persistence.Jobs().Get(job.ID)
+		mocks.Must(t, "Could not retrieve a job: ", err)
+		mocks.AssertEquals(t, meeseeks.JobQueuedStatus, queued.Status)
+
+		mocks.AssertEquals(t, "job is not in Running status but Queued",
+			persistence.Jobs().Queue(job.ID).Error())
+
+		mocks.Must(t, "could not start the job", persistence.Jobs().Start(job.ID))
+		started, err := persistence.Jobs().Get(job.ID)
+		mocks.Must(t, "Could not retrieve a job: ", err)
+		mocks.AssertEquals(t, meeseeks.JobRunningStatus, started.Status)
+		if started.StartTime.Before(queued.StartTime) {
+			t.Fatal("Start time should be reset when a queued job starts")
+		}
+
+		mocks.Must(t, "could not set as successful", persistence.Jobs().Succeed(job.ID))
+	}))
+}
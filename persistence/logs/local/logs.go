@@ -1,6 +1,7 @@
 package local
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -24,10 +25,17 @@ func NewWriter() meeseeks.LogWriter {
 type localWriter struct{}
 
 // Implements LogWriter.Append
-func (l localWriter) Append(jobID uint64, content string) error {
-	if content == "" {
+func (l localWriter) Append(jobID uint64, line meeseeks.LogLine) error {
+	if line.Line == "" {
 		return nil
 	}
+	if line.Stream == "" {
+		line.Stream = meeseeks.StreamStdout
+	}
+	payload, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf("could not marshal log line for job %d: %s", jobID, err)
+	}
 	return db.Update(func(tx *bolt.Tx) error {
 		jobBucket, err := getJobBucket(jobID, tx)
 		if err != nil {
@@ -41,7 +49,7 @@ func (l localWriter) Append(jobID uint64, content string) error {
 
 		metrics.LogLinesCount.Inc()
 
-		return jobBucket.Put(db.IDToBytes(sequence), []byte(content))
+		return jobBucket.Put(db.IDToBytes(sequence), payload)
 	})
 }
 
@@ -67,64 +75,46 @@ func (l localWriter) SetError(jobID uint64, jobErr error) error {
 type localReader struct{}
 
 // Get implements LogReader.Get
-func (l localReader) Get(jobID uint64) (meeseeks.JobLog, error) {
-	job := &meeseeks.JobLog{}
-	err := readLogBucket(jobID, func(j *bolt.Bucket) error {
-		c := j.Cursor()
-		_, line := c.First()
-		lines := make([]string, 0)
-		for {
-			if line == nil {
-				break
-			}
-			lines = append(lines, string(line))
-			_, line = c.Next()
-		}
-		job.Output = strings.Join(lines, "\n")
-
-		errorBucket := j.Bucket(errorKey)
-		if errorBucket != nil {
-			job.Error = string(errorBucket.Get(errorKey))
-		}
-		return nil
-	})
-	return *job, err
+func (l localReader) Get(jobID uint64, stream string) (meeseeks.JobLog, error) {
+	return readLines(jobID, stream, -1, false)
 }
 
 // Head implements LogReader.Head
-func (l localReader) Head(jobID uint64, limit int) (meeseeks.JobLog, error) {
-	job := &meeseeks.JobLog{}
-	err := readLogBucket(jobID, func(j *bolt.Bucket) error {
-		c := j.Cursor()
-
-		lines := make([]string, 0)
-		_, line := c.First()
-		for i := 0; i < limit && line != nil; i++ {
-			lines = append(lines, string(line))
-			_, line = c.Next()
-		}
-		job.Output = strings.Join(lines, "\n")
-
-		errorBucket := j.Bucket(errorKey)
-		if errorBucket != nil {
-			job.Error = string(errorBucket.Get(errorKey))
-		}
-		return nil
-	})
-	return *job, err
+func (l localReader) Head(jobID uint64, stream string, limit int) (meeseeks.JobLog, error) {
+	return readLines(jobID, stream, limit, false)
 }
 
 // Tail implements LogReader.Tail
-func (l localReader) Tail(jobID uint64, limit int) (meeseeks.JobLog, error) {
+func (l localReader) Tail(jobID uint64, stream string, limit int) (meeseeks.JobLog, error) {
+	return readLines(jobID, stream, limit, true)
+}
+
+// readLines reads up to limit lines of the stream, all of them when limit is
+// negative, starting from the last one when backwards is set
+func readLines(jobID uint64, stream string, limit int, backwards bool) (meeseeks.JobLog, error) {
 	job := &meeseeks.JobLog{}
 	err := readLogBucket(jobID, func(j *bolt.Bucket) error {
 		c := j.Cursor()
-		lines := make([]string, 0)
+		first, next := c.First, c.Next
+		if backwards {
+			first, next = c.Last, c.Prev
+		}
 
-		_, line := c.Last()
-		for i := 0; i < limit && line != nil; i++ {
-			lines = append([]string{string(line)}, lines...)
-			_, line = c.Prev()
+		lines := make([]string, 0)
+		for key, payload := first(); key != nil && limit != len(lines); key, payload = next() {
+			// The error is a nested bucket, which has no value
+			if payload == nil {
+				continue
+			}
+			line := decodeLine(payload)
+			if stream != "" && line.Stream != stream {
+				continue
+			}
+			if backwards {
+				lines = append([]string{line.Line}, lines...)
+			} else {
+				lines = append(lines, line.Line)
+			}
 		}
 		job.Output = strings.Join(lines, "\n")
 
@@ -137,6 +127,16 @@ func (l localReader) Tail(jobID uint64, limit int) (meeseeks.JobLog, error) {
 	return *job, err
 }
 
+// decodeLine reads a stored line, lines stored before streams were recorded
+// are plain text and are read as stdout
+func decodeLine(payload []byte) meeseeks.LogLine {
+	var line meeseeks.LogLine
+	if err := json.Unmarshal(payload, &line); err != nil || line.Stream == "" {
+		return meeseeks.LogLine{Line: string(payload), Stream: meeseeks.StreamStdout}
+	}
+	return line
+}
+
 var logsBucketKey = []byte("logs")
 var errorKey = []byte("error")
 
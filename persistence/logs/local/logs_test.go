@@ -3,6 +3,7 @@ package local_test
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
@@ -24,7 +25,7 @@ func Test_Logs(t *testing.T) {
 			logs:  []string{"something"},
 			err:   nil,
 			getter: func(jobID uint64) (meeseeks.JobLog, error) {
-				return persistence.LogReader().Get(jobID)
+				return persistence.LogReader().Get(jobID, "")
 			},
 			expected: meeseeks.JobLog{
 				Output: "something",
@@ -36,7 +37,7 @@ func Test_Logs(t *testing.T) {
 			logs:  []string{"something", "something else"},
 			err:   nil,
 			getter: func(jobID uint64) (meeseeks.JobLog, error) {
-				return persistence.LogReader().Get(jobID)
+				return persistence.LogReader().Get(jobID, "")
 			},
 			expected: meeseeks.JobLog{
 				Output: "something\nsomething else",
@@ -48,7 +49,7 @@ func Test_Logs(t *testing.T) {
 			logs:  []string{"bla"},
 			err:   errors.New("something bad happened"),
 			getter: func(jobID uint64) (meeseeks.JobLog, error) {
-				return persistence.LogReader().Get(jobID)
+				return persistence.LogReader().Get(jobID, "")
 			},
 			expected: meeseeks.JobLog{
 				Output: "bla",
@@ -61,7 +62,7 @@ func Test_Logs(t *testing.T) {
 			logs:  []string{"something", "something else"},
 			err:   nil,
 			getter: func(jobID uint64) (meeseeks.JobLog, error) {
-				return persistence.LogReader().Head(jobID, 1)
+				return persistence.LogReader().Head(jobID, "", 1)
 			},
 			expected: meeseeks.JobLog{
 				Output: "something",
@@ -73,7 +74,7 @@ func Test_Logs(t *testing.T) {
 			logs:  []string{"something", "something else"},
 			err:   nil,
 			getter: func(jobID uint64) (meeseeks.JobLog, error) {
-				return persistence.LogReader().Tail(jobID, 1)
+				return persistence.LogReader().Tail(jobID, "", 1)
 			},
 			expected: meeseeks.JobLog{
 				Output: "something else",
@@ -85,7 +86,7 @@ func Test_Logs(t *testing.T) {
 			t.Run(tc.name, func(t *testing.T) {
 				lw := persistence.LogWriter()
 				for _, line := range tc.logs {
-					lw.Append(tc.jobID, line)
+					lw.Append(tc.jobID, meeseeks.LogLine{Line: line})
 				}
 				if tc.err != nil {
 					lw.SetError(tc.jobID, tc.err)
@@ -101,7 +102,7 @@ func Test_Logs(t *testing.T) {
 
 func Test_GetLoglessJob(t *testing.T) {
 	mocks.WithTmpDB(func(_ string) {
-		_, err := persistence.LogReader().Get(1)
+		_, err := persistence.LogReader().Get(1, "")
 
 		mocks.AssertEquals(t, meeseeks.ErrNoLogsForJob, err)
 	})
@@ -111,13 +112,13 @@ func Test_AppendEmptyStringToLogDoesntCreateALog(t *testing.T) {
 	mocks.WithTmpDB(func(_ string) {
 		lw := persistence.LogWriter()
 
-		err := lw.Append(1, "")
+		err := lw.Append(1, meeseeks.LogLine{})
 		mocks.Must(t, "should be able to write an empty string to a log", err)
 
 		err = lw.SetError(1, nil)
 		mocks.Must(t, "should be able to get set a nil error in a log", err)
 
-		_, err = persistence.LogReader().Get(1)
+		_, err = persistence.LogReader().Get(1, "")
 		mocks.AssertEquals(t, meeseeks.ErrNoLogsForJob, err)
 	})
 }
@@ -126,9 +127,36 @@ func Test_ErredOutJobHasError(t *testing.T) {
 	mocks.WithTmpDB(func(_ string) {
 		persistence.LogWriter().SetError(1, errors.New("nasty error"))
 
-		l, err := persistence.LogReader().Get(1)
+		l, err := persistence.LogReader().Get(1, "")
 
 		mocks.Must(t, "should be able to get a job with only an error", err)
 		mocks.AssertEquals(t, meeseeks.JobLog{Error: "nasty error"}, l)
 	})
 }
+
+func Test_LogsCanBeFilteredByStream(t *testing.T) {
+	mocks.WithTmpDB(func(_ string) {
+		lw := persistence.LogWriter()
+		lw.Append(1, meeseeks.LogLine{Line: "out 1", Stream: meeseeks.StreamStdout, Time: time.Now()})
+		lw.Append(1, meeseeks.LogLine{Line: "err 1", Stream: meeseeks.StreamStderr, Time: time.Now()})
+		lw.Append(1, meeseeks.LogLine{Line: "out 2", Stream: meeseeks.StreamStdout, Time: time.Now()})
+		lw.Append(1, meeseeks.LogLine{Line: "err 2", Stream: meeseeks.StreamStderr, Time: time.Now()})
+		lw.SetError(1, errors.New("exit status 1"))
+
+		all, err := persistence.LogReader().Get(1, "")
+		mocks.Must(t, "could not get all the logs", err)
+		mocks.AssertEquals(t, "out 1\nerr 1\nout 2\nerr 2", all.Output)
+
+		stderr, err := persistence.LogReader().Get(1, meeseeks.StreamStderr)
+		mocks.Must(t, "could not get the stderr logs", err)
+		mocks.AssertEquals(t, meeseeks.JobLog{Output: "err 1\nerr 2", Error: "exit status 1"}, stderr)
+
+		head, err := persistence.LogReader().Head(1, meeseeks.StreamStderr, 1)
+		mocks.Must(t, "could not get the stderr head", err)
+		mocks.AssertEquals(t, "err 1", head.Output)
+
+		tail, err := persistence.LogReader().Tail(1, meeseeks.StreamStdout, 1)
+		mocks.Must(t, "could not get the stdout tail", err)
+		mocks.AssertEquals(t, "out 2", tail.Output)
+	})
+}
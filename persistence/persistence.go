@@ -5,6 +5,7 @@ import (
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence/aliases"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence/jobs"
 	logs "gitlab.com/yakshaving.art/meeseeks-box/persistence/logs/local"
+	"gitlab.com/yakshaving.art/meeseeks-box/persistence/schedules"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence/tokens"
 )
 
@@ -15,6 +16,7 @@ func init() {
 		Aliases:   aliases.Aliases{},
 		Jobs:      jobs.Jobs{},
 		APITokens: tokens.Tokens{},
+		Schedules: schedules.Schedules{},
 		LogReader: logs.NewReader(),
 		LogWriter: logs.NewWriter(),
 	}
@@ -25,6 +27,7 @@ type Providers struct {
 	Aliases   meeseeks.Aliases
 	Jobs      meeseeks.Jobs
 	APITokens meeseeks.APITokens
+	Schedules meeseeks.Schedules
 	LogReader meeseeks.LogReader
 	LogWriter meeseeks.LogWriter
 }
@@ -44,6 +47,11 @@ func APITokens() meeseeks.APITokens {
 	return providers.APITokens
 }
 
+// Schedules returns an actual instance of the schedules service
+func Schedules() meeseeks.Schedules {
+	return providers.Schedules
+}
+
 // LogReader returns an actual instance of the log reader service
 func LogReader() meeseeks.LogReader {
 	return providers.LogReader
@@ -0,0 +1,106 @@
+package schedules
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/persistence/db"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/sirupsen/logrus"
+)
+
+var schedulesBucketKey = []byte("schedules")
+
+// Schedules implements the Schedules interface with locally stored schedules
+type Schedules struct{}
+
+// Create records a new schedule and returns it with its ID set
+func (Schedules) Create(s meeseeks.Schedule) (meeseeks.Schedule, error) {
+	return create(s)
+}
+
+// Get returns a schedule by ID, it may return ErrNoScheduleWithID
+func (Schedules) Get(id uint64) (meeseeks.Schedule, error) {
+	return get(id)
+}
+
+// Remove deletes a schedule by ID
+func (Schedules) Remove(id uint64) error {
+	return remove(id)
+}
+
+// Find returns all the schedules that match the filter in creation order
+func (Schedules) Find(filter meeseeks.ScheduleFilter) ([]meeseeks.Schedule, error) {
+	return find(filter)
+}
+
+func create(s meeseeks.Schedule) (meeseeks.Schedule, error) {
+	err := db.Create(schedulesBucketKey, func(id uint64, bucket *bolt.Bucket) error {
+		s.ID = id
+		s.CreatedOn = time.Now().UTC()
+
+		payload, err := json.Marshal(s)
+		if err != nil {
+			return fmt.Errorf("could not marshal schedule: %s", err)
+		}
+		logrus.Debugf("Creating schedule %#v", s)
+		return bucket.Put(db.IDToBytes(id), payload)
+	})
+	return s, err
+}
+
+func get(id uint64) (meeseeks.Schedule, error) {
+	s := meeseeks.Schedule{}
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(schedulesBucketKey)
+		if bucket == nil {
+			return meeseeks.ErrNoScheduleWithID
+		}
+		payload := bucket.Get(db.IDToBytes(id))
+		if payload == nil {
+			return meeseeks.ErrNoScheduleWithID
+		}
+		return json.Unmarshal(payload, &s)
+	})
+	return s, err
+}
+
+func remove(id uint64) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(schedulesBucketKey)
+		if bucket == nil || bucket.Get(db.IDToBytes(id)) == nil {
+			return meeseeks.ErrNoScheduleWithID
+		}
+		return bucket.Delete(db.IDToBytes(id))
+	})
+}
+
+func find(filter meeseeks.ScheduleFilter) ([]meeseeks.Schedule, error) {
+	if filter.Match == nil {
+		filter.Match = func(_ meeseeks.Schedule) bool { return true }
+	}
+
+	schedules := make([]meeseeks.Schedule, 0)
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(schedulesBucketKey)
+		if bucket == nil {
+			return nil // an empty list is not an error
+		}
+
+		c := bucket.Cursor()
+		for _, payload := c.First(); payload != nil; _, payload = c.Next() {
+			s := meeseeks.Schedule{}
+			if err := json.Unmarshal(payload, &s); err != nil {
+				return fmt.Errorf("failed to load schedule payload: %s", err)
+			}
+			if filter.Match(s) {
+				schedules = append(schedules, s)
+			}
+		}
+		return nil
+	})
+	return schedules, err
+}
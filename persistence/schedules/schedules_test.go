@@ -0,0 +1,63 @@
+package schedules_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
+)
+
+func TestGetNonExistingSchedule(t *testing.T) {
+	mocks.Must(t, "failed to run tests", mocks.WithTmpDB(func(_ string) {
+		_, err := persistence.Schedules().Get(1)
+		mocks.AssertEquals(t, meeseeks.ErrNoScheduleWithID, err)
+
+		err = persistence.Schedules().Remove(1)
+		mocks.AssertEquals(t, meeseeks.ErrNoScheduleWithID, err)
+	}))
+}
+
+func TestScheduleLifecycle(t *testing.T) {
+	mocks.Must(t, "failed to run tests", mocks.WithTmpDB(func(_ string) {
+		daily, err := persistence.Schedules().Create(meeseeks.Schedule{
+			Request: meeseeks.Request{Command: "report", Args: []string{"daily"}, UserID: "userid"},
+			Spec:    "every weekday 09:00",
+			Cron:    "0 9 * * 1-5",
+		})
+		mocks.Must(t, "could not create recurring schedule", err)
+		mocks.AssertEquals(t, uint64(1), daily.ID)
+		mocks.AssertEquals(t, true, daily.IsRecurring())
+
+		runAt := time.Date(2030, 1, 1, 18, 0, 0, 0, time.UTC)
+		once, err := persistence.Schedules().Create(meeseeks.Schedule{
+			Request: meeseeks.Request{Command: "deploy", Args: []string{"app"}, UserID: "otherid"},
+			Spec:    "18:00",
+			RunAt:   runAt,
+		})
+		mocks.Must(t, "could not create one-shot schedule", err)
+		mocks.AssertEquals(t, uint64(2), once.ID)
+		mocks.AssertEquals(t, false, once.IsRecurring())
+
+		s, err := persistence.Schedules().Get(once.ID)
+		mocks.Must(t, "could not get schedule back", err)
+		mocks.AssertEquals(t, "deploy", s.Request.Command)
+		mocks.AssertEquals(t, true, runAt.Equal(s.RunAt))
+
+		all, err := persistence.Schedules().Find(meeseeks.ScheduleFilter{})
+		mocks.Must(t, "could not list schedules", err)
+		mocks.AssertEquals(t, 2, len(all))
+
+		mine, err := persistence.Schedules().Find(meeseeks.ScheduleFilter{
+			Match: func(s meeseeks.Schedule) bool { return s.Request.UserID == "userid" },
+		})
+		mocks.Must(t, "could not filter schedules", err)
+		mocks.AssertEquals(t, 1, len(mine))
+		mocks.AssertEquals(t, daily.ID, mine[0].ID)
+
+		mocks.Must(t, "could not remove schedule", persistence.Schedules().Remove(daily.ID))
+		_, err = persistence.Schedules().Get(daily.ID)
+		mocks.AssertEquals(t, meeseeks.ErrNoScheduleWithID, err)
+	}))
+}
@@ -22,8 +22,8 @@ var ErrTokenNotFound = fmt.Errorf("no token found")
 type Tokens struct{}
 
 // Create gets a new token request and creates a token persistence record. It returns the created token.
-func (Tokens) Create(userLink, channelLink, text string) (string, error) {
-	return create(userLink, channelLink, text)
+func (Tokens) Create(userLink, channelLink, text, origin string) (string, error) {
+	return create(userLink, channelLink, text, origin)
 }
 
 // Get returns the token given an ID, it may return ErrTokenNotFound when there is no such token
@@ -41,7 +41,7 @@ func (Tokens) Find(filter meeseeks.APITokenFilter) ([]meeseeks.APIToken, error)
 	return find(filter)
 }
 
-func create(userLink, channelLink, text string) (string, error) {
+func create(userLink, channelLink, text, origin string) (string, error) {
 	token := uuid.New().String()
 
 	err := db.Update(func(tx *bolt.Tx) error {
@@ -56,6 +56,7 @@ func create(userLink, channelLink, text string) (string, error) {
 			ChannelLink: channelLink,
 			Text:        text,
 			CreatedOn:   time.Now(),
+			Origin:      origin,
 		}
 		tb, err := json.Marshal(t)
 		if err != nil {
@@ -28,6 +28,7 @@ func Test_TokenLifecycle(t *testing.T) {
 			"myuser",
 			"mychannel",
 			"echo hello",
+			"slack",
 		)
 		mocks.Must(t, "could not create token", err)
 		if id == "" {
@@ -41,6 +42,7 @@ func Test_TokenLifecycle(t *testing.T) {
 		mocks.AssertEquals(t, "myuser", tk.UserLink)
 		mocks.AssertEquals(t, "mychannel", tk.ChannelLink)
 		mocks.AssertEquals(t, "echo hello", tk.Text)
+		mocks.AssertEquals(t, "slack", tk.Origin)
 
 		mocks.Must(t, "could not revoke token", persistence.APITokens().Revoke(id))
 	})
@@ -52,6 +54,7 @@ func Test_TokenListing(t *testing.T) {
 			"echo something",
 			"myuser",
 			"mychannel",
+			"",
 		)
 		mocks.Must(t, "could not create token", err)
 
@@ -62,6 +65,7 @@ func Test_TokenListing(t *testing.T) {
 			"echo something else",
 			"someone_else",
 			"my_other_channel",
+			"",
 		)
 		mocks.Must(t, "could not create token", err)
 
@@ -0,0 +1,243 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/auth"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+)
+
+// Limits that can reject a request, used to label the rejections
+const (
+	LimitUser     = "user"
+	LimitCommand  = "command"
+	LimitCooldown = "cooldown"
+)
+
+// Limit allows a number of requests per period, refilling them at a constant pace
+type Limit struct {
+	Requests int `yaml:"requests"`
+	// Per is the period in seconds
+	Per time.Duration `yaml:"per"`
+}
+
+func (l Limit) enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+func (l Limit) period() time.Duration {
+	return l.Per * time.Second
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d requests per %s", l.Requests, l.period())
+}
+
+// Config is the rate limiting configuration
+type Config struct {
+	// Default is the limit of every user that has no explicit one
+	Default Limit `yaml:"default"`
+	// Users overrides the default limit of specific users
+	Users map[string]Limit `yaml:"users"`
+	// Groups overrides the default limit of the users of a group, when a user
+	// is in many groups the most generous limit is used
+	Groups map[string]Limit `yaml:"groups"`
+	// Commands limits the requests of a command, shared by all the users
+	Commands map[string]Limit `yaml:"commands"`
+}
+
+// Error is returned when a request goes over a limit
+type Error struct {
+	Limit   string
+	Reason  string
+	RetryIn time.Duration
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s, try again in %s", e.Reason, e.RetryIn.Round(time.Second))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last time the bucket was used
+func (b *bucket) refill(l Limit, now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = float64(l.Requests)
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * float64(l.Requests) / l.period().Seconds()
+		if b.tokens > float64(l.Requests) {
+			b.tokens = float64(l.Requests)
+		}
+	}
+	b.last = now
+}
+
+// retryIn returns how long until the bucket has a token again
+func (b *bucket) retryIn(l Limit) time.Duration {
+	missing := 1 - b.tokens
+	return time.Duration(missing * float64(l.period()) / float64(l.Requests))
+}
+
+type limiter struct {
+	config Config
+
+	users    map[string]*bucket
+	commands map[string]*bucket
+	lastRuns map[string]time.Time
+	m        sync.Mutex
+}
+
+var rateLimiter = struct {
+	current *limiter
+	m       sync.RWMutex
+}{
+	current: newLimiter(Config{}),
+}
+
+func newLimiter(cnf Config) *limiter {
+	return &limiter{
+		config:   cnf,
+		users:    make(map[string]*bucket),
+		commands: make(map[string]*bucket),
+		lastRuns: make(map[string]time.Time),
+	}
+}
+
+// Configure sets the limits, forgetting the requests that were already counted
+func Configure(cnf Config) {
+	l := newLimiter(cnf)
+
+	rateLimiter.m.Lock()
+	defer rateLimiter.m.Unlock()
+	rateLimiter.current = l
+}
+
+// Check takes a request from the user and command buckets, failing with an
+// Error when any of them is empty or the command is cooling down in the channel.
+// Commands that are not recorded, like the builtin ones, are never limited
+func Check(req meeseeks.Request, cmd meeseeks.Command) error {
+	if !cmd.MustRecord() {
+		return nil
+	}
+	rateLimiter.m.RLock()
+	l := rateLimiter.current
+	rateLimiter.m.RUnlock()
+
+	return l.check(req, cmd, time.Now())
+}
+
+func (l *limiter) check(req meeseeks.Request, cmd meeseeks.Command, now time.Time) error {
+	defer l.m.Unlock()
+	l.m.Lock()
+
+	cooldownKey := req.Command + "@" + req.ChannelID
+	cooldown := time.Duration(0)
+	if c, ok := cmd.(meeseeks.CooldownCommand); ok {
+		cooldown = c.GetCooldown()
+	}
+	if last, ok := l.lastRuns[cooldownKey]; ok && now.Sub(last) < cooldown {
+		return Error{
+			Limit:   LimitCooldown,
+			Reason:  fmt.Sprintf("%s ran in this channel %s ago", req.Command, now.Sub(last).Round(time.Second)),
+			RetryIn: cooldown - now.Sub(last),
+		}
+	}
+
+	userLimit := l.userLimit(req.Username)
+	userBucket := l.bucket(l.users, req.Username, userLimit, now)
+	if userBucket != nil && userBucket.tokens < 1 {
+		return Error{
+			Limit:   LimitUser,
+			Reason:  fmt.Sprintf("%s is limited to %s", req.Username, userLimit),
+			RetryIn: userBucket.retryIn(userLimit),
+		}
+	}
+
+	cmdLimit := l.config.Commands[req.Command]
+	cmdBucket := l.bucket(l.commands, req.Command, cmdLimit, now)
+	if cmdBucket != nil && cmdBucket.tokens < 1 {
+		return Error{
+			Limit:   LimitCommand,
+			Reason:  fmt.Sprintf("%s is limited to %s", req.Command, cmdLimit),
+			RetryIn: cmdBucket.retryIn(cmdLimit),
+		}
+	}
+
+	// Only take the tokens once the request passed all the limits
+	if userBucket != nil {
+		userBucket.tokens--
+	}
+	if cmdBucket != nil {
+		cmdBucket.tokens--
+	}
+	if cooldown > 0 {
+		l.lastRuns[cooldownKey] = now
+	}
+	return nil
+}
+
+// bucket returns the refilled bucket for the key, or nil when it has no limit
+func (l *limiter) bucket(buckets map[string]*bucket, key string, limit Limit, now time.Time) *bucket {
+	if !limit.enabled() {
+		return nil
+	}
+	b, ok := buckets[key]
+	if !ok {
+		b = &bucket{}
+		buckets[key] = b
+	}
+	b.refill(limit, now)
+	return b
+}
+
+func (l *limiter) userLimit(username string) Limit {
+	if limit, ok := l.config.Users[username]; ok {
+		return limit
+	}
+
+	if len(l.config.Groups) == 0 {
+		return l.config.Default
+	}
+
+	var best Limit
+	found := false
+	for group, users := range auth.GetGroups() {
+		limit, ok := l.config.Groups[group]
+		if !ok || !contains(users, username) {
+			continue
+		}
+		if !found || moreGenerous(limit, best) {
+			best, found = limit, true
+		}
+	}
+	if found {
+		return best
+	}
+	return l.config.Default
+}
+
+// moreGenerous returns whether the limit a allows more requests than b, a
+// disabled limit allows them all
+func moreGenerous(a, b Limit) bool {
+	if !a.enabled() {
+		return b.enabled()
+	}
+	if !b.enabled() {
+		return false
+	}
+	return float64(a.Requests)/a.period().Seconds() > float64(b.Requests)/b.period().Seconds()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
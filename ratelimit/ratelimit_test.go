@@ -0,0 +1,125 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/auth"
+	"gitlab.com/yakshaving.art/meeseeks-box/commands/builtins"
+	"gitlab.com/yakshaving.art/meeseeks-box/commands/shell"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/ratelimit"
+)
+
+var echo = shell.New(meeseeks.CommandOpts{Cmd: "echo"})
+
+func request(command, username, channelID string) meeseeks.Request {
+	return meeseeks.Request{Command: command, Username: username, ChannelID: channelID}
+}
+
+func limitOf(t *testing.T, err error) string {
+	if err == nil {
+		t.Fatal("request was not limited")
+	}
+	e, ok := err.(ratelimit.Error)
+	if !ok {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	return e.Limit
+}
+
+func TestUsersAreLimited(t *testing.T) {
+	auth.Configure(map[string][]string{
+		"admin": {"admin_user"},
+		"ops":   {"admin_user", "ops_user"},
+	})
+	ratelimit.Configure(ratelimit.Config{
+		Default: ratelimit.Limit{Requests: 1, Per: 3600},
+		Users: map[string]ratelimit.Limit{
+			"vip": {Requests: 3, Per: 3600},
+		},
+		Groups: map[string]ratelimit.Limit{
+			"admin": {Requests: 3, Per: 3600},
+			"ops":   {Requests: 2, Per: 3600},
+		},
+	})
+
+	tt := []struct {
+		username string
+		allowed  int
+	}{
+		{username: "someone", allowed: 1},
+		{username: "vip", allowed: 3},
+		{username: "ops_user", allowed: 2},
+		{username: "admin_user", allowed: 3},
+	}
+	for _, tc := range tt {
+		t.Run(tc.username, func(t *testing.T) {
+			for i := 0; i < tc.allowed; i++ {
+				mocks.Must(t, "request should be allowed", ratelimit.Check(request("echo", tc.username, "C1"), echo))
+			}
+			err := ratelimit.Check(request("echo", tc.username, "C1"), echo)
+			mocks.AssertEquals(t, ratelimit.LimitUser, limitOf(t, err))
+		})
+	}
+}
+
+func TestCommandsAreLimitedForEveryone(t *testing.T) {
+	ratelimit.Configure(ratelimit.Config{
+		Commands: map[string]ratelimit.Limit{
+			"echo": {Requests: 2, Per: 60},
+		},
+	})
+
+	mocks.Must(t, "first request", ratelimit.Check(request("echo", "someone", "C1"), echo))
+	mocks.Must(t, "second request", ratelimit.Check(request("echo", "someone_else", "C2"), echo))
+
+	err := ratelimit.Check(request("echo", "another_one", "C3"), echo)
+	mocks.AssertEquals(t, ratelimit.LimitCommand, limitOf(t, err))
+	mocks.AssertEquals(t, "echo is limited to 2 requests per 1m0s, try again in 30s", err.Error())
+
+	mocks.Must(t, "other commands are not limited", ratelimit.Check(request("other", "someone", "C1"), echo))
+}
+
+func TestCommandsCoolDownInTheChannel(t *testing.T) {
+	ratelimit.Configure(ratelimit.Config{})
+	deploy := shell.New(meeseeks.CommandOpts{Cmd: "deploy", Cooldown: time.Minute})
+
+	mocks.Must(t, "first deploy", ratelimit.Check(request("deploy", "someone", "C1"), deploy))
+
+	err := ratelimit.Check(request("deploy", "someone_else", "C1"), deploy)
+	mocks.AssertEquals(t, ratelimit.LimitCooldown, limitOf(t, err))
+	mocks.AssertEquals(t, "deploy ran in this channel 0s ago, try again in 1m0s", err.Error())
+
+	mocks.Must(t, "deploy in another channel", ratelimit.Check(request("deploy", "someone", "C2"), deploy))
+}
+
+func TestBuiltinCommandsAreNotLimited(t *testing.T) {
+	ratelimit.Configure(ratelimit.Config{
+		Default: ratelimit.Limit{Requests: 1, Per: 3600},
+	})
+	version := builtins.Commands[builtins.BuiltinVersionCommand]
+
+	for i := 0; i < 3; i++ {
+		mocks.Must(t, "builtin request", ratelimit.Check(request("version", "someone", "C1"), version))
+	}
+}
+
+func TestConfigureWhileChecking(t *testing.T) {
+	ratelimit.Configure(ratelimit.Config{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ratelimit.Configure(ratelimit.Config{
+				Default: ratelimit.Limit{Requests: 1000, Per: 3600},
+			})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		mocks.Must(t, "request should be allowed", ratelimit.Check(request("echo", "someone", "C1"), echo))
+	}
+	<-done
+}
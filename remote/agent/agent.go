@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"sync"
 	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/commands"
+	"gitlab.com/yakshaving.art/meeseeks-box/commands/params"
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
 	"gitlab.com/yakshaving.art/meeseeks-box/remote/api"
@@ -36,16 +38,27 @@ type RemoteClient struct {
 	ctx        context.Context
 	cancelFunc context.CancelFunc
 
-	agentID string
+	agentID  string
+	hostname string
+
+	// jobs holds the stop functions of the running jobs
+	jobs  map[uint64]func(meeseeks.Stop)
+	jobsM sync.Mutex
 }
 
 // New creates a new remote requester
 func New(c Configuration) *RemoteClient {
 	logrus.Debugf("creating new remote agent with configuration %#v", c)
+	hostname, err := os.Hostname()
+	if err != nil {
+		logrus.Errorf("could not get the hostname of the agent: %s", err)
+	}
 	return &RemoteClient{
-		agentID: uuid.New().String(),
-		config:  c,
-		wg:      sync.WaitGroup{},
+		agentID:  uuid.New().String(),
+		hostname: hostname,
+		config:   c,
+		wg:       sync.WaitGroup{},
+		jobs:     make(map[uint64]func(meeseeks.Stop)),
 	}
 }
 
@@ -139,14 +152,20 @@ Service:
 
 			logrus.Debugf("received command from pipeline: %#v", cmd)
 
+			if cancel := cmd.GetCancel(); cancel != nil {
+				r.stopJob(cancel)
+				continue
+			}
+
 			r.wg.Add(1)
-			go r.runCommand(*cmd)
+			go r.runCommand(r.trackJob(cmd.GetJobID()), *cmd)
 		}
 	}
 }
 
-func (r *RemoteClient) runCommand(cmd api.CommandRequest) {
+func (r *RemoteClient) runCommand(ctx context.Context, cmd api.CommandRequest) {
 	defer r.wg.Done()
+	defer r.untrackJob(cmd.GetJobID())
 
 	// add a metric to account for remotely received commands
 	rq := meeseeks.Request{
@@ -168,23 +187,51 @@ func (r *RemoteClient) runCommand(cmd api.CommandRequest) {
 		defer cancel()
 
 		r.cmdClient.Finish(ctx, &api.CommandFinish{
-			AgentID: r.agentID,
-			JobID:   cmd.GetJobID(),
-			Error:   fmt.Sprintf("could not find command %s in remote agent", cmd.GetCommand()),
+			AgentID:  r.agentID,
+			JobID:    cmd.GetJobID(),
+			Error:    fmt.Sprintf("could not find command %s in remote agent", cmd.GetCommand()),
+			ExitCode: -1,
+			Hostname: r.hostname,
 		})
 		return
 	}
 
 	logrus.Debugf("found command %#v", localCmd)
-	ctx, cancelShellCmd := context.WithTimeout(r.ctx, localCmd.GetTimeout())
+	if c, ok := localCmd.(meeseeks.ParameterizedCommand); ok && len(c.GetParams()) > 0 {
+		args, err := params.Parse(c.GetParams(), rq.Args)
+		if err != nil {
+			ctx, cancel := context.WithTimeout(r.ctx, r.config.GetGRPCTimeout())
+			defer cancel()
+
+			r.cmdClient.Finish(ctx, &api.CommandFinish{
+				AgentID:  r.agentID,
+				JobID:    cmd.GetJobID(),
+				Error:    fmt.Sprintf("invalid arguments: %s", err),
+				ExitCode: -1,
+				Hostname: r.hostname,
+			})
+			return
+		}
+		rq.Args = args
+	}
+
+	ctx, cancelShellCmd := context.WithTimeout(ctx, localCmd.GetTimeout())
 	defer cancelShellCmd()
 
-	content, err := localCmd.Execute(ctx, meeseeks.Job{
+	job := meeseeks.Job{
 		ID:        cmd.GetJobID(),
 		Request:   rq,
 		Status:    meeseeks.JobRunningStatus,
 		StartTime: time.Now(),
-	})
+	}
+	result := meeseeks.JobResult{ExitCode: -1, Hostname: r.hostname}
+	var content string
+	var err error
+	if c, ok := localCmd.(meeseeks.ReportingCommand); ok {
+		content, result, err = c.ExecuteWithResult(ctx, job)
+	} else {
+		content, err = localCmd.Execute(ctx, job)
+	}
 
 	var errString string
 	if err != nil {
@@ -196,14 +243,58 @@ func (r *RemoteClient) runCommand(cmd api.CommandRequest) {
 
 	logrus.Debugf("sending command finish event %#v", cmd)
 	r.cmdClient.Finish(ctx, &api.CommandFinish{
-		AgentID: r.agentID,
-		JobID:   cmd.GetJobID(),
-		Content: content,
-		Error:   errString,
+		AgentID:     r.agentID,
+		JobID:       cmd.GetJobID(),
+		Content:     content,
+		Error:       errString,
+		ExitCode:    int32(result.ExitCode),
+		Signal:      result.Signal,
+		Hostname:    result.Hostname,
+		OutputBytes: result.OutputBytes,
+		Truncated:   result.Truncated,
 	})
 	logrus.Debugf("command %#v finished execution", cmd)
 }
 
+// trackJob returns the context the job runs with, which is stopped when the
+// server cancels the job
+func (r *RemoteClient) trackJob(jobID uint64) context.Context {
+	defer r.jobsM.Unlock()
+	r.jobsM.Lock()
+
+	ctx, stop := meeseeks.WithStop(r.ctx)
+	r.jobs[jobID] = stop
+	return ctx
+}
+
+// untrackJob releases the context of a finished job
+func (r *RemoteClient) untrackJob(jobID uint64) {
+	defer r.jobsM.Unlock()
+	r.jobsM.Lock()
+
+	if stop, ok := r.jobs[jobID]; ok {
+		delete(r.jobs, jobID)
+		stop(meeseeks.Stop{})
+	}
+}
+
+// stopJob stops a running job the way the server asks, the job then reports
+// how it finished as any other
+func (r *RemoteClient) stopJob(cancel *api.CancelJob) {
+	defer r.jobsM.Unlock()
+	r.jobsM.Lock()
+
+	stop, ok := r.jobs[cancel.GetJobID()]
+	if !ok {
+		logrus.Debugf("could not stop job %d because it is not running", cancel.GetJobID())
+		return
+	}
+	delete(r.jobs, cancel.GetJobID())
+
+	logrus.Infof("stopping job %d (%s) on behalf of %s", cancel.GetJobID(), cancel.GetStop(), cancel.GetUsername())
+	stop(meeseeks.Stop{Kind: cancel.GetStop(), Username: cancel.GetUsername()})
+}
+
 // Shutdown will close the stream and wait for all the commands to finish execution
 func (r *RemoteClient) Shutdown() {
 	if r.pipeline != nil {
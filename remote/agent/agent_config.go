@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/commands"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/remote/api"
 
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
@@ -78,7 +79,7 @@ func (c *Configuration) createAgentConfiguration(agentID string) *api.AgentConfi
 func (c *Configuration) createRemoteCommands() map[string]*api.RemoteCommand {
 	remoteCommands := make(map[string]*api.RemoteCommand, 0)
 	for name, cmd := range commands.All() {
-		remoteCommands[name] = &api.RemoteCommand{
+		remoteCommand := &api.RemoteCommand{
 			Timeout:         cmd.GetTimeout().Nanoseconds(),
 			AuthStrategy:    cmd.GetAuthStrategy(),
 			AllowedGroups:   cmd.GetAllowedGroups(),
@@ -89,6 +90,19 @@ func (c *Configuration) createRemoteCommands() map[string]*api.RemoteCommand {
 				Args:    cmd.GetHelp().GetArgs(),
 			},
 		}
+		if c, ok := cmd.(meeseeks.LockedCommand); ok {
+			remoteCommand.Lock = c.GetLock()
+			remoteCommand.LockPolicy = c.GetLockPolicy()
+		}
+		if c, ok := cmd.(meeseeks.GracefulCommand); ok {
+			remoteCommand.GracePeriod = c.GetGracePeriod().Nanoseconds()
+		}
+		if c, ok := cmd.(meeseeks.ExitCodesCommand); ok {
+			for _, code := range c.GetWarningExitCodes() {
+				remoteCommand.WarningExitCodes = append(remoteCommand.WarningExitCodes, int32(code))
+			}
+		}
+		remoteCommands[name] = remoteCommand
 	}
 	return remoteCommands
 }
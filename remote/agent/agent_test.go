@@ -23,6 +23,9 @@ import (
 var wg = sync.WaitGroup{}
 var ch = make(chan api.CommandFinish)
 
+// logged gets the ID of the jobs that sent a log line, which tells they are running
+var logged = make(chan uint64, 10)
+
 func init() {
 	logrus.AddHook(filename.NewHook())
 	logrus.SetFormatter(&logrus.TextFormatter{
@@ -36,6 +39,12 @@ var echoCmd = shell.New(meeseeks.CommandOpts{
 	Help: meeseeks.NewHelp("echo"),
 })
 
+var sleepCmd = shell.New(meeseeks.CommandOpts{
+	Cmd:  "sh",
+	Args: []string{"-c", "echo started; exec sleep 10"},
+	Help: meeseeks.NewHelp("sleep"),
+})
+
 type MockServer struct{}
 
 func (m MockServer) RegisterAgent(in *api.AgentConfiguration, agent api.CommandPipeline_RegisterAgentServer) error {
@@ -68,6 +77,40 @@ func (m MockServer) RegisterAgent(in *api.AgentConfiguration, agent api.CommandP
 	}
 	wg.Add(1)
 
+	err = agent.Send(&api.CommandRequest{
+		JobID:  4,
+		Cancel: &api.CancelJob{JobID: 4, Stop: meeseeks.StopKill, Username: "admin"},
+	})
+	logrus.Infof("mock server: cancellation of an unknown job sent to agent")
+	if err != nil {
+		return fmt.Errorf("failed to send cancellation: %s", err)
+	}
+
+	err = agent.Send(&api.CommandRequest{
+		JobID:    3,
+		Command:  "sleep",
+		Args:     []string{},
+		Channel:  "channel",
+		Username: "someone",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send command request: %s", err)
+	}
+	wg.Add(1)
+
+	if err := waitForLog(3); err != nil {
+		return err
+	}
+
+	err = agent.Send(&api.CommandRequest{
+		JobID:  3,
+		Cancel: &api.CancelJob{JobID: 3, Stop: meeseeks.StopKill, Username: "admin"},
+	})
+	logrus.Infof("mock server: cancellation sent to agent")
+	if err != nil {
+		return fmt.Errorf("failed to send cancellation: %s", err)
+	}
+
 	wg.Wait()
 	close(ch)
 
@@ -76,6 +119,21 @@ func (m MockServer) RegisterAgent(in *api.AgentConfiguration, agent api.CommandP
 	return nil
 }
 
+// waitForLog waits until the job sends a log line
+func waitForLog(jobID uint64) error {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case id := <-logged:
+			if id == jobID {
+				return nil
+			}
+		case <-timeout:
+			return fmt.Errorf("job %d never started", jobID)
+		}
+	}
+}
+
 func (m MockServer) Finish(ctx context.Context, fin *api.CommandFinish) (*api.Empty, error) {
 	logrus.Infof("mock server: storing finished command")
 
@@ -97,9 +155,13 @@ func (l MockLogger) Append(writer api.LogWriter_AppendServer) error {
 		return fmt.Errorf("error when appending to log: %s", err)
 	}
 	l.logs = append(l.logs, fmt.Sprintf("%d-%s", log.GetJobID(), log.GetLine()))
+	select {
+	case logged <- log.GetJobID():
+	default:
+	}
 	logrus.Infof("mock server: done appending to logs")
 
-	return nil
+	return writer.SendAndClose(&api.Empty{})
 }
 
 func (MockLogger) SetError(ctx context.Context, entry *api.ErrorLogEntry) (*api.Empty, error) {
@@ -116,6 +178,10 @@ func TestAgentTalksToServer(t *testing.T) {
 					Name: "echo",
 					Cmd:  echoCmd,
 				},
+				{
+					Name: "sleep",
+					Cmd:  sleepCmd,
+				},
 			},
 		}))
 	defer commands.Reset()
@@ -175,4 +241,12 @@ func TestAgentTalksToServer(t *testing.T) {
 	mocks.AssertEquals(t, "", finished.GetContent())
 	mocks.AssertEquals(t, "could not find command invalid in remote agent", finished.GetError())
 	mocks.AssertEquals(t, uint64(2), finished.GetJobID())
+
+	finished = cmds[3]
+	mocks.AssertEquals(t, "context canceled", finished.GetError())
+	mocks.AssertEquals(t, int32(-1), finished.GetExitCode())
+	mocks.AssertEquals(t, "killed", finished.GetSignal())
+
+	_, ok := cmds[4]
+	mocks.AssertEquals(t, false, ok)
 }
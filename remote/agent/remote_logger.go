@@ -17,7 +17,7 @@ type grpcLogWriter struct {
 }
 
 // Append implements LogWritter.Append
-func (g grpcLogWriter) Append(jobID uint64, content string) error {
+func (g grpcLogWriter) Append(jobID uint64, line meeseeks.LogLine) error {
 	ctx, cancel := context.WithTimeout(context.Background(), g.timeoutSeconds)
 	defer cancel()
 
@@ -26,13 +26,25 @@ func (g grpcLogWriter) Append(jobID uint64, content string) error {
 		return fmt.Errorf("Failed to get a remote appender for job %d: %s", jobID, err)
 	}
 
-	logrus.Debugf("sending log job %d - '%s'", jobID, content)
-	err = w.Send(&api.LogEntry{
-		JobID: jobID,
-		Line:  content,
-	})
+	entry := &api.LogEntry{
+		JobID:  jobID,
+		Line:   line.Line,
+		Stream: line.Stream,
+	}
+	if !line.Time.IsZero() {
+		entry.Time = line.Time.UnixNano()
+	}
+
+	logrus.Debugf("sending log job %d - '%s'", jobID, line.Line)
+	err = w.Send(entry)
 	if err != nil {
 		logrus.Errorf("failed to send log to remote appender %d - '%s'", jobID, err)
+		return err
+	}
+
+	// Wait for the server to store the line, or cancelling the context may drop it
+	if _, err = w.CloseAndRecv(); err != nil {
+		logrus.Errorf("failed to close remote appender %d - '%s'", jobID, err)
 	}
 	return err
 }
@@ -44,14 +56,14 @@ func (g grpcLogWriter) SetError(jobID uint64, err error) error {
 type nullReader struct {
 }
 
-func (nullReader) Get(_ uint64) (meeseeks.JobLog, error) {
+func (nullReader) Get(_ uint64, _ string) (meeseeks.JobLog, error) {
 	return meeseeks.JobLog{}, nil
 }
 
-func (nullReader) Head(_ uint64, _ int) (meeseeks.JobLog, error) {
+func (nullReader) Head(_ uint64, _ string, _ int) (meeseeks.JobLog, error) {
 	return meeseeks.JobLog{}, nil
 }
 
-func (nullReader) Tail(_ uint64, _ int) (meeseeks.JobLog, error) {
+func (nullReader) Tail(_ uint64, _ string, _ int) (meeseeks.JobLog, error) {
 	return meeseeks.JobLog{}, nil
 }
@@ -174,6 +174,11 @@ type CommandFinish struct {
 	Content              string   `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
 	Error                string   `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	AgentID              string   `protobuf:"bytes,4,opt,name=agentID,proto3" json:"agentID,omitempty"`
+	ExitCode             int32    `protobuf:"varint,5,opt,name=exitCode,proto3" json:"exitCode,omitempty"`
+	Signal               string   `protobuf:"bytes,6,opt,name=signal,proto3" json:"signal,omitempty"`
+	Hostname             string   `protobuf:"bytes,7,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	OutputBytes          uint64   `protobuf:"varint,8,opt,name=outputBytes,proto3" json:"outputBytes,omitempty"`
+	Truncated            bool     `protobuf:"varint,9,opt,name=truncated,proto3" json:"truncated,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -231,6 +236,41 @@ func (m *CommandFinish) GetAgentID() string {
 	return ""
 }
 
+func (m *CommandFinish) GetExitCode() int32 {
+	if m != nil {
+		return m.ExitCode
+	}
+	return 0
+}
+
+func (m *CommandFinish) GetSignal() string {
+	if m != nil {
+		return m.Signal
+	}
+	return ""
+}
+
+func (m *CommandFinish) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
+func (m *CommandFinish) GetOutputBytes() uint64 {
+	if m != nil {
+		return m.OutputBytes
+	}
+	return 0
+}
+
+func (m *CommandFinish) GetTruncated() bool {
+	if m != nil {
+		return m.Truncated
+	}
+	return false
+}
+
 type Help struct {
 	Summary              string   `protobuf:"bytes,1,opt,name=Summary,proto3" json:"Summary,omitempty"`
 	Args                 []string `protobuf:"bytes,2,rep,name=Args,proto3" json:"Args,omitempty"`
@@ -285,6 +325,10 @@ type RemoteCommand struct {
 	AllowedChannels      []string `protobuf:"bytes,5,rep,name=AllowedChannels,proto3" json:"AllowedChannels,omitempty"`
 	Help                 *Help    `protobuf:"bytes,6,opt,name=help,proto3" json:"help,omitempty"`
 	HasHandshake         bool     `protobuf:"varint,7,opt,name=hasHandshake,proto3" json:"hasHandshake,omitempty"`
+	Lock                 string   `protobuf:"bytes,8,opt,name=lock,proto3" json:"lock,omitempty"`
+	LockPolicy           string   `protobuf:"bytes,9,opt,name=lockPolicy,proto3" json:"lockPolicy,omitempty"`
+	WarningExitCodes     []int32  `protobuf:"varint,10,rep,packed,name=warningExitCodes,proto3" json:"warningExitCodes,omitempty"`
+	GracePeriod          int64    `protobuf:"varint,11,opt,name=gracePeriod,proto3" json:"gracePeriod,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -363,6 +407,34 @@ func (m *RemoteCommand) GetHasHandshake() bool {
 	return false
 }
 
+func (m *RemoteCommand) GetLock() string {
+	if m != nil {
+		return m.Lock
+	}
+	return ""
+}
+
+func (m *RemoteCommand) GetLockPolicy() string {
+	if m != nil {
+		return m.LockPolicy
+	}
+	return ""
+}
+
+func (m *RemoteCommand) GetWarningExitCodes() []int32 {
+	if m != nil {
+		return m.WarningExitCodes
+	}
+	return nil
+}
+
+func (m *RemoteCommand) GetGracePeriod() int64 {
+	if m != nil {
+		return m.GracePeriod
+	}
+	return 0
+}
+
 type Empty struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -394,19 +466,20 @@ func (m *Empty) XXX_DiscardUnknown() {
 var xxx_messageInfo_Empty proto.InternalMessageInfo
 
 type CommandRequest struct {
-	Command              string   `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
-	Args                 []string `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
-	Username             string   `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
-	UserID               string   `protobuf:"bytes,4,opt,name=userID,proto3" json:"userID,omitempty"`
-	UserLink             string   `protobuf:"bytes,5,opt,name=userLink,proto3" json:"userLink,omitempty"`
-	Channel              string   `protobuf:"bytes,6,opt,name=channel,proto3" json:"channel,omitempty"`
-	ChannelID            string   `protobuf:"bytes,7,opt,name=channelID,proto3" json:"channelID,omitempty"`
-	ChannelLink          string   `protobuf:"bytes,8,opt,name=channelLink,proto3" json:"channelLink,omitempty"`
-	IsIM                 bool     `protobuf:"varint,9,opt,name=isIM,proto3" json:"isIM,omitempty"`
-	JobID                uint64   `protobuf:"varint,10,opt,name=jobID,proto3" json:"jobID,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Command              string     `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	Args                 []string   `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+	Username             string     `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
+	UserID               string     `protobuf:"bytes,4,opt,name=userID,proto3" json:"userID,omitempty"`
+	UserLink             string     `protobuf:"bytes,5,opt,name=userLink,proto3" json:"userLink,omitempty"`
+	Channel              string     `protobuf:"bytes,6,opt,name=channel,proto3" json:"channel,omitempty"`
+	ChannelID            string     `protobuf:"bytes,7,opt,name=channelID,proto3" json:"channelID,omitempty"`
+	ChannelLink          string     `protobuf:"bytes,8,opt,name=channelLink,proto3" json:"channelLink,omitempty"`
+	IsIM                 bool       `protobuf:"varint,9,opt,name=isIM,proto3" json:"isIM,omitempty"`
+	JobID                uint64     `protobuf:"varint,10,opt,name=jobID,proto3" json:"jobID,omitempty"`
+	Cancel               *CancelJob `protobuf:"bytes,11,opt,name=cancel,proto3" json:"cancel,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
 func (m *CommandRequest) Reset()         { *m = CommandRequest{} }
@@ -503,9 +576,18 @@ func (m *CommandRequest) GetJobID() uint64 {
 	return 0
 }
 
+func (m *CommandRequest) GetCancel() *CancelJob {
+	if m != nil {
+		return m.Cancel
+	}
+	return nil
+}
+
 type LogEntry struct {
 	JobID                uint64   `protobuf:"varint,1,opt,name=jobID,proto3" json:"jobID,omitempty"`
 	Line                 string   `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`
+	Stream               string   `protobuf:"bytes,3,opt,name=stream,proto3" json:"stream,omitempty"`
+	Time                 int64    `protobuf:"varint,4,opt,name=time,proto3" json:"time,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -549,6 +631,20 @@ func (m *LogEntry) GetLine() string {
 	return ""
 }
 
+func (m *LogEntry) GetStream() string {
+	if m != nil {
+		return m.Stream
+	}
+	return ""
+}
+
+func (m *LogEntry) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
 type ErrorLogEntry struct {
 	JobID                uint64   `protobuf:"varint,1,opt,name=jobID,proto3" json:"jobID,omitempty"`
 	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
@@ -595,6 +691,60 @@ func (m *ErrorLogEntry) GetError() string {
 	return ""
 }
 
+type CancelJob struct {
+	JobID                uint64   `protobuf:"varint,1,opt,name=jobID,proto3" json:"jobID,omitempty"`
+	Stop                 string   `protobuf:"bytes,2,opt,name=stop,proto3" json:"stop,omitempty"`
+	Username             string   `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CancelJob) Reset()         { *m = CancelJob{} }
+func (m *CancelJob) String() string { return proto.CompactTextString(m) }
+func (*CancelJob) ProtoMessage()    {}
+func (*CancelJob) Descriptor() ([]byte, []int) {
+	return fileDescriptor_api_117924c65de44d70, []int{10}
+}
+func (m *CancelJob) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CancelJob.Unmarshal(m, b)
+}
+func (m *CancelJob) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CancelJob.Marshal(b, m, deterministic)
+}
+func (dst *CancelJob) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CancelJob.Merge(dst, src)
+}
+func (m *CancelJob) XXX_Size() int {
+	return xxx_messageInfo_CancelJob.Size(m)
+}
+func (m *CancelJob) XXX_DiscardUnknown() {
+	xxx_messageInfo_CancelJob.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CancelJob proto.InternalMessageInfo
+
+func (m *CancelJob) GetJobID() uint64 {
+	if m != nil {
+		return m.JobID
+	}
+	return 0
+}
+
+func (m *CancelJob) GetStop() string {
+	if m != nil {
+		return m.Stop
+	}
+	return ""
+}
+
+func (m *CancelJob) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*AgentRegistration)(nil), "api.AgentRegistration")
 	proto.RegisterType((*AgentPrivateToken)(nil), "api.AgentPrivateToken")
@@ -608,6 +758,7 @@ func init() {
 	proto.RegisterType((*CommandRequest)(nil), "api.CommandRequest")
 	proto.RegisterType((*LogEntry)(nil), "api.LogEntry")
 	proto.RegisterType((*ErrorLogEntry)(nil), "api.ErrorLogEntry")
+	proto.RegisterType((*CancelJob)(nil), "api.CancelJob")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -942,50 +1093,61 @@ var _LogWriter_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("api.proto", fileDescriptor_api_117924c65de44d70) }
 
 var fileDescriptor_api_117924c65de44d70 = []byte{
-	// 711 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x55, 0x4d, 0x6f, 0xd3, 0x4a,
-	0x14, 0x6d, 0x3e, 0x1b, 0xdf, 0x34, 0xaf, 0xef, 0xcd, 0xab, 0xfa, 0xac, 0xe8, 0x21, 0x45, 0x06,
-	0x44, 0x40, 0xa8, 0x42, 0xa1, 0x0b, 0xa0, 0x6c, 0xac, 0x36, 0xd0, 0x48, 0x41, 0x54, 0x6e, 0x25,
-	0xd6, 0x93, 0x76, 0x70, 0x86, 0xd8, 0x33, 0xc6, 0x1e, 0x17, 0x65, 0xc7, 0x9a, 0x25, 0xbf, 0x8c,
-	0x9f, 0x84, 0xe6, 0xce, 0xd8, 0xb1, 0x4b, 0x0b, 0xbb, 0x7b, 0xae, 0xef, 0x39, 0x73, 0xbf, 0x66,
-	0x0c, 0x0e, 0x4d, 0xf8, 0x41, 0x92, 0x4a, 0x25, 0x49, 0x8b, 0x26, 0xdc, 0x9b, 0xc2, 0x3f, 0x7e,
-	0xc8, 0x84, 0x0a, 0x58, 0xc8, 0x33, 0x95, 0x52, 0xc5, 0xa5, 0x20, 0x7b, 0xd0, 0xb9, 0x90, 0x2b,
-	0x26, 0xdc, 0xc6, 0xa8, 0x31, 0x76, 0x02, 0x03, 0xc8, 0x10, 0x7a, 0xa7, 0x32, 0x53, 0x82, 0xc6,
-	0xcc, 0x6d, 0xe2, 0x87, 0x12, 0x7b, 0x8f, 0xad, 0xcc, 0x59, 0xca, 0xaf, 0xa9, 0x62, 0x86, 0x70,
-	0xab, 0x8c, 0xf7, 0xa3, 0x09, 0x04, 0x63, 0x8f, 0xa5, 0xf8, 0xc8, 0xc3, 0xfc, 0xb7, 0x67, 0xfa,
-	0xd0, 0xbb, 0x94, 0x71, 0x4c, 0xc5, 0x55, 0xe6, 0x36, 0x47, 0xad, 0x71, 0x7f, 0xf2, 0xf0, 0x40,
-	0x57, 0xf0, 0xab, 0xc0, 0xc1, 0xb1, 0x8d, 0x9b, 0x0a, 0x95, 0xae, 0x83, 0x92, 0x46, 0x8e, 0xa0,
-	0x3b, 0xa7, 0x0b, 0x16, 0x65, 0x6e, 0x0b, 0x05, 0xee, 0xdf, 0x25, 0x60, 0xa2, 0x0c, 0xdd, 0x52,
-	0x88, 0x0b, 0xdb, 0x54, 0x47, 0xce, 0x4e, 0xdc, 0x36, 0xe6, 0x55, 0xc0, 0xe1, 0x7b, 0x18, 0xd4,
-	0x4e, 0x24, 0x7f, 0x43, 0x6b, 0xc5, 0xd6, 0x36, 0x7d, 0x6d, 0x92, 0x31, 0x74, 0xae, 0x69, 0x94,
-	0x9b, 0x6e, 0xf5, 0x27, 0x04, 0x0f, 0x0e, 0x58, 0x2c, 0x15, 0xb3, 0xd4, 0xc0, 0x04, 0xbc, 0x6a,
-	0xbe, 0x68, 0x0c, 0x5f, 0x42, 0xbf, 0x92, 0xc1, 0x2d, 0x72, 0x7b, 0x55, 0x39, 0xa7, 0x42, 0xf5,
-	0x64, 0x99, 0xcb, 0x1b, 0x2e, 0x78, 0xb6, 0xd4, 0xa1, 0x9f, 0xe4, 0x62, 0x76, 0x82, 0xf4, 0x76,
-	0x60, 0x80, 0x2e, 0xe6, 0x52, 0x0a, 0xc5, 0x84, 0xb2, 0x12, 0x05, 0xd4, 0xf1, 0x2c, 0x4d, 0x65,
-	0xea, 0xb6, 0x8c, 0x34, 0x82, 0xbb, 0x8b, 0xf7, 0x0e, 0xa1, 0x7d, 0xca, 0xa2, 0x44, 0x47, 0x9c,
-	0xe7, 0x71, 0x4c, 0xd3, 0x22, 0xd1, 0x02, 0x12, 0x02, 0x6d, 0x3f, 0x0d, 0xcd, 0xd0, 0x9c, 0x00,
-	0x6d, 0xef, 0x5b, 0x13, 0x06, 0xb5, 0xf2, 0x35, 0xff, 0x82, 0xc7, 0x4c, 0xe6, 0x0a, 0xf9, 0xad,
-	0xa0, 0x80, 0xc4, 0x83, 0x1d, 0x3f, 0x57, 0xcb, 0x73, 0xbd, 0x92, 0x2c, 0x5c, 0xdb, 0x84, 0x6b,
-	0x3e, 0xf2, 0x00, 0x06, 0x7e, 0x14, 0xc9, 0x2f, 0xec, 0xea, 0x6d, 0x2a, 0xf3, 0xc4, 0x0c, 0xd8,
-	0x09, 0xea, 0x4e, 0x32, 0x86, 0xdd, 0xe3, 0x25, 0x15, 0x82, 0x45, 0xa5, 0x98, 0xa9, 0xe6, 0xa6,
-	0x5b, 0x47, 0x5a, 0xaa, 0xfd, 0x92, 0xb9, 0x1d, 0x54, 0xbc, 0xe9, 0x26, 0xf7, 0xa0, 0xbd, 0x64,
-	0x51, 0xe2, 0x76, 0x71, 0xb0, 0x0e, 0x0e, 0x56, 0x37, 0x24, 0x40, 0xb7, 0x4e, 0x7e, 0x49, 0xb3,
-	0x53, 0xbd, 0x1b, 0x4b, 0xba, 0x62, 0xee, 0xf6, 0xa8, 0x31, 0xee, 0x05, 0x35, 0x9f, 0xb7, 0x0d,
-	0x9d, 0x69, 0x9c, 0xa8, 0xb5, 0xf7, 0xbd, 0x09, 0x7f, 0x15, 0xeb, 0xc0, 0x3e, 0xe7, 0x2c, 0x53,
-	0x66, 0x50, 0xe8, 0x29, 0xda, 0x6a, 0xa1, 0x6e, 0x2b, 0xad, 0xb4, 0x55, 0xdb, 0xfa, 0x5e, 0xe6,
-	0x19, 0x4b, 0xf1, 0x5e, 0x9a, 0xf9, 0x95, 0x98, 0xec, 0x43, 0x57, 0xdb, 0xe5, 0x04, 0x2d, 0x2a,
-	0x38, 0x73, 0x2e, 0x56, 0x6e, 0x67, 0xc3, 0xd1, 0x18, 0x4f, 0x37, 0x85, 0x62, 0x7d, 0xfa, 0x74,
-	0x03, 0xc9, 0xff, 0xe0, 0x58, 0x73, 0x76, 0x82, 0x45, 0x39, 0xc1, 0xc6, 0x41, 0x46, 0xd0, 0xb7,
-	0x00, 0x65, 0x7b, 0xf8, 0xbd, 0xea, 0xd2, 0xd9, 0xf3, 0x6c, 0xf6, 0xce, 0x75, 0xb0, 0x1f, 0x68,
-	0x6f, 0x56, 0x15, 0x2a, 0xab, 0xea, 0x1d, 0x42, 0x6f, 0x2e, 0x43, 0x73, 0x13, 0x6e, 0x5f, 0x66,
-	0x02, 0xed, 0x88, 0x8b, 0xe2, 0x32, 0xa0, 0xed, 0x1d, 0xc1, 0x60, 0xaa, 0x37, 0xf7, 0x0f, 0xd4,
-	0x72, 0xdb, 0x9b, 0x95, 0x6d, 0x9f, 0xcc, 0x61, 0xa7, 0xf6, 0x08, 0xbe, 0x86, 0x9e, 0xc1, 0x2c,
-	0x25, 0xfb, 0x9b, 0x37, 0xa3, 0x1a, 0x33, 0xac, 0xf8, 0xab, 0x2f, 0x9f, 0xb7, 0x35, 0xf9, 0xda,
-	0x80, 0x5d, 0x3b, 0xd5, 0x33, 0x9e, 0x30, 0x9d, 0x1e, 0xf1, 0xf5, 0xfa, 0x1b, 0x45, 0xa4, 0x90,
-	0xff, 0xee, 0x78, 0x8a, 0x86, 0xff, 0xe2, 0x87, 0xfa, 0x56, 0x78, 0x5b, 0xcf, 0x1a, 0xe4, 0x09,
-	0x74, 0xed, 0x15, 0x27, 0xd5, 0x10, 0xe3, 0x1b, 0x02, 0xfa, 0xcc, 0x5a, 0x6d, 0x4d, 0x16, 0xe0,
-	0xcc, 0x65, 0xf8, 0x21, 0xe5, 0xba, 0x82, 0x47, 0xd0, 0xf5, 0x93, 0x84, 0x89, 0x2b, 0x32, 0xc0,
-	0xa0, 0xa2, 0x45, 0x75, 0xce, 0xb8, 0x41, 0x9e, 0x42, 0xef, 0x9c, 0x29, 0x6c, 0xa3, 0x3d, 0xa3,
-	0xd6, 0xd2, 0x7a, 0xfc, 0xa2, 0x8b, 0xbf, 0x92, 0xe7, 0x3f, 0x03, 0x00, 0x00, 0xff, 0xff, 0xde,
-	0xce, 0xc9, 0x96, 0x57, 0x06, 0x00, 0x00,
+	// 892 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x56, 0xdd, 0x92, 0xdb, 0x34,
+	0x14, 0xde, 0xc4, 0x9b, 0x6c, 0x7c, 0xd2, 0xb4, 0x45, 0x74, 0x8a, 0x27, 0x03, 0x4c, 0xc6, 0xfc,
+	0x85, 0x0e, 0xb3, 0xc3, 0x04, 0x2e, 0x80, 0x72, 0x13, 0xb2, 0x81, 0x5d, 0x26, 0x0c, 0x8b, 0xb6,
+	0x33, 0xdc, 0xa2, 0x24, 0xc2, 0x11, 0xb1, 0x25, 0x23, 0xcb, 0x2d, 0xb9, 0xe3, 0x01, 0x78, 0x22,
+	0x9e, 0x82, 0x37, 0x82, 0xd1, 0x91, 0xec, 0xd8, 0xed, 0x6e, 0xb9, 0xda, 0xf3, 0x7d, 0x39, 0xe7,
+	0xe8, 0xf8, 0x3b, 0x9f, 0xe5, 0x85, 0x90, 0xe5, 0xe2, 0x3c, 0xd7, 0xca, 0x28, 0x12, 0xb0, 0x5c,
+	0xc4, 0x4b, 0x78, 0x63, 0x9e, 0x70, 0x69, 0x28, 0x4f, 0x44, 0x61, 0x34, 0x33, 0x42, 0x49, 0xf2,
+	0x08, 0x7a, 0xcf, 0xd4, 0x9e, 0xcb, 0xa8, 0x33, 0xe9, 0x4c, 0x43, 0xea, 0x00, 0x19, 0xc3, 0xe0,
+	0x52, 0x15, 0x46, 0xb2, 0x8c, 0x47, 0x5d, 0xfc, 0xa1, 0xc6, 0xf1, 0xc7, 0xbe, 0xcd, 0xb5, 0x16,
+	0xcf, 0x99, 0xe1, 0xae, 0xe0, 0xd6, 0x36, 0xf1, 0x3f, 0x5d, 0x20, 0x98, 0xbb, 0x50, 0xf2, 0x57,
+	0x91, 0x94, 0xaf, 0x3d, 0x73, 0x0e, 0x83, 0x8d, 0xca, 0x32, 0x26, 0xb7, 0x45, 0xd4, 0x9d, 0x04,
+	0xd3, 0xe1, 0xec, 0x83, 0x73, 0xfb, 0x04, 0xaf, 0x36, 0x38, 0x5f, 0xf8, 0xbc, 0xa5, 0x34, 0xfa,
+	0x40, 0xeb, 0x32, 0xf2, 0x14, 0xfa, 0x2b, 0xb6, 0xe6, 0x69, 0x11, 0x05, 0xd8, 0xe0, 0xbd, 0xbb,
+	0x1a, 0xb8, 0x2c, 0x57, 0xee, 0x4b, 0x48, 0x04, 0x67, 0xcc, 0x66, 0x5e, 0x5d, 0x44, 0xa7, 0x38,
+	0x57, 0x05, 0xc7, 0x3f, 0xc2, 0xa8, 0x75, 0x22, 0x79, 0x08, 0xc1, 0x9e, 0x1f, 0xfc, 0xf8, 0x36,
+	0x24, 0x53, 0xe8, 0x3d, 0x67, 0x69, 0xe9, 0xd4, 0x1a, 0xce, 0x08, 0x1e, 0x4c, 0x79, 0xa6, 0x0c,
+	0xf7, 0xa5, 0xd4, 0x25, 0x7c, 0xd5, 0xfd, 0xa2, 0x33, 0xfe, 0x12, 0x86, 0x8d, 0x09, 0x6e, 0x69,
+	0xf7, 0xa8, 0xd9, 0x2e, 0x6c, 0x94, 0xc6, 0xff, 0x76, 0xea, 0x61, 0xbe, 0x15, 0x52, 0x14, 0x3b,
+	0x9b, 0xfb, 0x9b, 0x5a, 0x5f, 0x5d, 0x60, 0xfd, 0x29, 0x75, 0xc0, 0x3e, 0xcd, 0x46, 0x49, 0xc3,
+	0xa5, 0xf1, 0x3d, 0x2a, 0x68, 0xf3, 0xb9, 0xd6, 0x4a, 0x47, 0x81, 0xeb, 0x8d, 0xe0, 0xee, 0xa7,
+	0xb7, 0x5e, 0xe0, 0x7f, 0x08, 0xb3, 0x50, 0x5b, 0x1e, 0xf5, 0x26, 0x9d, 0x69, 0x8f, 0xd6, 0x98,
+	0x3c, 0x86, 0x7e, 0x21, 0x12, 0xc9, 0xd2, 0xa8, 0x8f, 0x45, 0x1e, 0xd9, 0x9a, 0x5d, 0xe5, 0x9f,
+	0x33, 0xe7, 0x9f, 0x0a, 0x93, 0x09, 0x0c, 0x55, 0x69, 0xf2, 0xd2, 0x7c, 0x73, 0x30, 0xbc, 0x88,
+	0x06, 0x38, 0x75, 0x93, 0x22, 0x6f, 0x43, 0x68, 0x74, 0x29, 0x37, 0xcc, 0xf0, 0x6d, 0x14, 0x4e,
+	0x3a, 0xd3, 0x01, 0x3d, 0x12, 0xf1, 0xe7, 0x70, 0x7a, 0xc9, 0xd3, 0xdc, 0x4e, 0x7c, 0x53, 0x66,
+	0x19, 0xd3, 0x95, 0x72, 0x15, 0x24, 0x04, 0x4e, 0xe7, 0x3a, 0x71, 0x2e, 0x0a, 0x29, 0xc6, 0xf1,
+	0x5f, 0x01, 0x8c, 0x5a, 0xfb, 0xb0, 0xf5, 0xcf, 0x44, 0xc6, 0x55, 0x69, 0xb0, 0x3e, 0xa0, 0x15,
+	0x24, 0x31, 0xdc, 0x9b, 0x97, 0x66, 0x77, 0x63, 0xdf, 0x11, 0x9e, 0x1c, 0xbc, 0x80, 0x2d, 0x8e,
+	0xbc, 0x0f, 0xa3, 0x79, 0x9a, 0xaa, 0x17, 0x7c, 0xfb, 0x9d, 0x56, 0x65, 0xee, 0x1c, 0x17, 0xd2,
+	0x36, 0x49, 0xa6, 0xf0, 0x60, 0xb1, 0x63, 0x52, 0xf2, 0xb4, 0x6e, 0xe6, 0xd4, 0x7d, 0x99, 0xb6,
+	0x99, 0xbe, 0xd4, 0xff, 0x52, 0x44, 0x3d, 0xec, 0xf8, 0x32, 0x4d, 0xde, 0x81, 0xd3, 0x1d, 0x4f,
+	0x73, 0x54, 0x7c, 0x38, 0x0b, 0xd1, 0x69, 0x56, 0x10, 0x8a, 0xb4, 0x1d, 0x7e, 0xc7, 0x8a, 0x4b,
+	0x6b, 0xd6, 0x1d, 0xdb, 0x3b, 0xf9, 0x07, 0xb4, 0xc5, 0x59, 0x81, 0x52, 0xb5, 0xd9, 0xa3, 0xf6,
+	0x21, 0xc5, 0x98, 0xbc, 0x0b, 0x60, 0xff, 0x5e, 0xab, 0x54, 0x6c, 0x0e, 0xa8, 0x7a, 0x48, 0x1b,
+	0x0c, 0x79, 0x02, 0x0f, 0x5f, 0x30, 0x2d, 0x85, 0x4c, 0x96, 0x7e, 0xfb, 0x45, 0x04, 0x93, 0x60,
+	0xda, 0xa3, 0xaf, 0xf0, 0x76, 0xc5, 0x89, 0x66, 0x1b, 0x7e, 0xcd, 0xb5, 0x50, 0xdb, 0x68, 0x88,
+	0xf2, 0x36, 0xa9, 0xf8, 0x0c, 0x7a, 0xcb, 0x2c, 0x37, 0x87, 0xf8, 0xef, 0x2e, 0xdc, 0xaf, 0xde,
+	0x10, 0xfe, 0x7b, 0xc9, 0x0b, 0xe3, 0xac, 0x8b, 0x4c, 0xb5, 0x58, 0x0f, 0xed, 0xdc, 0xac, 0xb1,
+	0x58, 0x1b, 0x5b, 0xab, 0x95, 0x05, 0xd7, 0x68, 0x35, 0xe7, 0xe8, 0x1a, 0x5b, 0x7b, 0xda, 0xb8,
+	0xf6, 0xb4, 0x47, 0x55, 0xcd, 0x4a, 0xc8, 0x7d, 0xd4, 0x3b, 0xd6, 0x58, 0x8c, 0xa7, 0x3b, 0xa9,
+	0xbd, 0xa7, 0x2b, 0x68, 0x6d, 0xe9, 0xc3, 0xab, 0x0b, 0xef, 0xea, 0x23, 0x61, 0x9f, 0xd9, 0x03,
+	0x6c, 0xeb, 0xa4, 0x6d, 0x52, 0x76, 0x7a, 0x51, 0x5c, 0xfd, 0xe0, 0x1d, 0x8d, 0xf1, 0xf1, 0xe5,
+	0x85, 0xe6, 0xcb, 0xfb, 0x21, 0xf4, 0x37, 0x4c, 0x6e, 0x78, 0x8a, 0xd2, 0x0d, 0x67, 0xf7, 0x71,
+	0xc9, 0x0b, 0xa4, 0xbe, 0x57, 0x6b, 0xea, 0x7f, 0x8d, 0x7f, 0x81, 0xc1, 0x4a, 0x25, 0xee, 0x12,
+	0xb9, 0xfd, 0x1a, 0xb0, 0x9b, 0x16, 0xb2, 0xba, 0x47, 0x30, 0xc6, 0x97, 0xd6, 0x68, 0xce, 0x32,
+	0xaf, 0x97, 0x47, 0x36, 0xd7, 0x88, 0x8c, 0xa3, 0x56, 0x01, 0xc5, 0x38, 0x7e, 0x0a, 0xa3, 0xa5,
+	0xbd, 0x1f, 0xfe, 0xe7, 0x98, 0xfa, 0x4e, 0xe9, 0x36, 0xee, 0x94, 0xf8, 0x27, 0x08, 0xeb, 0x99,
+	0xef, 0x9e, 0xaf, 0x30, 0x2a, 0xaf, 0xe6, 0xb3, 0xf1, 0xeb, 0x36, 0x3a, 0x5b, 0xc1, 0xbd, 0xd6,
+	0xe7, 0xeb, 0x6b, 0x18, 0x38, 0xcc, 0x35, 0x79, 0x7c, 0xbc, 0xed, 0x9b, 0x39, 0xe3, 0x06, 0xdf,
+	0xfc, 0x66, 0xc5, 0x27, 0xb3, 0x3f, 0x3b, 0xf0, 0xc0, 0x9b, 0xef, 0x5a, 0xe4, 0x1c, 0xd5, 0x99,
+	0xc3, 0xc8, 0x55, 0x73, 0x8d, 0x25, 0xe4, 0xad, 0x3b, 0x3e, 0x22, 0xe3, 0x37, 0xdd, 0x56, 0x5a,
+	0xe6, 0x8d, 0x4f, 0x3e, 0xed, 0x90, 0x27, 0xd0, 0xf7, 0x77, 0x33, 0x69, 0xa6, 0x38, 0x6e, 0x0c,
+	0xc8, 0x39, 0xf7, 0x9f, 0xcc, 0xd6, 0x10, 0xae, 0x54, 0xf2, 0xb3, 0x16, 0xf6, 0x09, 0x3e, 0x82,
+	0xfe, 0x3c, 0xcf, 0xb9, 0xdc, 0x92, 0x11, 0x26, 0x55, 0xaa, 0xb7, 0x6b, 0xa6, 0x1d, 0xf2, 0x09,
+	0x0c, 0x6e, 0xb8, 0xc1, 0xcd, 0xf8, 0x33, 0x5a, 0x5b, 0x6a, 0xe7, 0xaf, 0xfb, 0xf8, 0x4f, 0xc0,
+	0x67, 0xff, 0x0d, 0x00, 0x59, 0x0a, 0x5a, 0xf9, 0x11, 0x08, 0x00, 0x00,
 }
@@ -22,6 +22,10 @@ type finishPayload struct {
 	err error
 }
 
+// finishTimeout is how long a stopped job waits for its agent to report how it
+// finished, on top of the grace period of the command
+const finishTimeout = 5 * time.Second
+
 type commandPipelineServer struct {
 	runningJobs map[uint64]chan finishedJob
 
@@ -38,6 +42,7 @@ func newCommandPipelineServer() *commandPipelineServer {
 
 type jobStarter interface {
 	StartJob(req api.CommandRequest) chan finishedJob
+	PopJob(jobID uint64) (chan finishedJob, error)
 }
 
 // RegisterAgent registers a new agent service
@@ -70,6 +75,7 @@ Loop:
 				agentID: in.GetAgentID(),
 				content: "",
 				err:     fmt.Sprintf("remote agent %s erred out with EOF, it seems to be gone", in.GetAgentID()),
+				result:  meeseeks.JobResult{ExitCode: -1, AgentID: in.GetAgentID()},
 			})
 			close(pipe)
 			break Loop
@@ -106,6 +112,14 @@ func (p *commandPipelineServer) Finish(ctx context.Context, fin *api.CommandFini
 		jobID:   fin.GetJobID(),
 		content: fin.GetContent(),
 		err:     fin.GetError(),
+		result: meeseeks.JobResult{
+			ExitCode:    int(fin.GetExitCode()),
+			Signal:      fin.GetSignal(),
+			AgentID:     fin.GetAgentID(),
+			Hostname:    fin.GetHostname(),
+			OutputBytes: fin.GetOutputBytes(),
+			Truncated:   fin.GetTruncated(),
+		},
 	})
 }
 
@@ -138,6 +152,10 @@ func (p *commandPipelineServer) registerAgent(in *api.AgentConfiguration) (chan
 					Help: meeseeks.NewHelp(
 						cmd.GetHelp().GetSummary(),
 						cmd.GetHelp().GetArgs()...),
+					Lock:             cmd.GetLock(),
+					LockPolicy:       cmd.GetLockPolicy(),
+					WarningExitCodes: warningExitCodes(cmd),
+					GracePeriod:      time.Duration(cmd.GetGracePeriod()),
 				},
 			},
 		})
@@ -218,7 +236,8 @@ func (p *commandPipelineServer) StartJob(req api.CommandRequest) chan finishedJo
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	c := make(chan finishedJob)
+	// Buffered so a late finish never blocks once the job stopped waiting
+	c := make(chan finishedJob, 1)
 	p.runningJobs[req.GetJobID()] = c
 	return c
 }
@@ -252,6 +271,23 @@ func (r *remoteAgent) start(req api.CommandRequest) chan finishedJob {
 	return c
 }
 
+// cancel asks the agent to stop a running job
+func (r *remoteAgent) cancel(jobID uint64, stop meeseeks.Stop) {
+	req := api.CommandRequest{
+		JobID: jobID,
+		Cancel: &api.CancelJob{
+			JobID:    jobID,
+			Stop:     stop.Kind,
+			Username: stop.Username,
+		},
+	}
+	select {
+	case r.agentPipe <- req:
+	case <-time.After(finishTimeout):
+		logrus.Errorf("could not send the cancellation of job %d to agent %s", jobID, r.agentID)
+	}
+}
+
 type remoteCommand struct {
 	meeseeks.CommandOpts
 
@@ -259,6 +295,13 @@ type remoteCommand struct {
 }
 
 func (r remoteCommand) Execute(ctx context.Context, job meeseeks.Job) (string, error) {
+	out, _, err := r.ExecuteWithResult(ctx, job)
+	return out, err
+}
+
+// ExecuteWithResult implements ReportingCommand.ExecuteWithResult with the
+// result the agent sends when the job finishes
+func (r remoteCommand) ExecuteWithResult(ctx context.Context, job meeseeks.Job) (string, meeseeks.JobResult, error) {
 	logrus.Debugf("start execution of job %#v", job)
 
 	req := job.Request
@@ -281,15 +324,51 @@ func (r remoteCommand) Execute(ctx context.Context, job meeseeks.Job) (string, e
 
 	select {
 	case <-ctx.Done():
-		logrus.Debugf("job %#v failed with error %s", job, ctx.Err())
-		return "", fmt.Errorf("command failed because of context done: %s", ctx.Err())
+		logrus.Debugf("job %#v failed with error %s, stopping it in agent %s", job, ctx.Err(), r.agent.agentID)
+		return r.stop(ctx, job, c)
 
 	case f := <-c:
 		logrus.Debugf("successful execution of job %#v with result %#v", job, f)
 		// TODO: check that the agent that finished the command is the same that started it
-		return f.getContent(), f.getError()
+		return f.getContent(), f.result, f.getError()
+
+	}
+}
+
+// stop propagates the cancellation of the context to the agent, and waits for
+// it to report how the job finished. Jobs that time out are killed
+func (r remoteCommand) stop(ctx context.Context, job meeseeks.Job, c chan finishedJob) (string, meeseeks.JobResult, error) {
+	err := fmt.Errorf("command failed because of context done: %s", ctx.Err())
+
+	stop, ok := meeseeks.StopOf(ctx)
+	if !ok {
+		stop = meeseeks.Stop{Kind: meeseeks.StopKill}
+	}
+	r.agent.cancel(job.ID, stop)
+
+	wait := finishTimeout
+	if stop.Kind == meeseeks.StopCancel {
+		wait += r.GetGracePeriod()
+	}
+
+	select {
+	case f := <-c:
+		logrus.Debugf("agent %s stopped job %#v with result %#v", r.agent.agentID, job, f)
+		return f.getContent(), f.result, err
+
+	case <-time.After(wait):
+		logrus.Warnf("agent %s did not report job %d finished after stopping it", r.agent.agentID, job.ID)
+		r.agent.PopJob(job.ID)
+		return "", meeseeks.JobResult{ExitCode: -1, AgentID: r.agent.agentID}, err
+	}
+}
 
+func warningExitCodes(cmd *api.RemoteCommand) []int {
+	codes := make([]int, 0, len(cmd.GetWarningExitCodes()))
+	for _, code := range cmd.GetWarningExitCodes() {
+		codes = append(codes, int(code))
 	}
+	return codes
 }
 
 type finishedJob struct {
@@ -297,6 +376,7 @@ type finishedJob struct {
 	jobID   uint64
 	content string
 	err     string
+	result  meeseeks.JobResult
 }
 
 func (f finishedJob) getContent() string {
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"io"
+	"time"
 
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
 	"gitlab.com/yakshaving.art/meeseeks-box/remote/api"
 	"github.com/sirupsen/logrus"
@@ -39,7 +41,14 @@ Loop:
 
 		}
 
-		err = persistence.LogWriter().Append(entry.GetJobID(), entry.GetLine())
+		line := meeseeks.LogLine{
+			Line:   entry.GetLine(),
+			Stream: entry.GetStream(),
+		}
+		if entry.GetTime() != 0 {
+			line.Time = time.Unix(0, entry.GetTime())
+		}
+		err = persistence.LogWriter().Append(entry.GetJobID(), line)
 		if err != nil {
 			logrus.Errorf("got error receiving log entry: %s", err)
 		} else {
@@ -125,3 +125,86 @@ func TestUsingTLS(t *testing.T) {
 	<-c
 	time.Sleep(1 * time.Millisecond)
 }
+
+func TestStoppedJobsAreCancelledInTheAgent(t *testing.T) {
+	mocks.WithTmpDB(func(_ string) {
+		s, err := server.New(server.Config{})
+		mocks.Must(t, "failed to create grpc server", err)
+		defer s.Shutdown()
+
+		c := make(chan interface{})
+		go func() {
+			c <- true
+			mocks.Must(t, "Failed to start server", s.Listen(":9701"))
+		}()
+
+		<-c
+		time.Sleep(1 * time.Millisecond)
+
+		client, err := grpc.Dial("localhost:9701", grpc.WithInsecure())
+		mocks.Must(t, "could not create grpc client", err)
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		cmdClient := api.NewCommandPipelineClient(client)
+		pipeline, err := cmdClient.RegisterAgent(ctx, &api.AgentConfiguration{
+			AgentID: "agentID2",
+			Token:   "mytoken",
+			Commands: map[string]*api.RemoteCommand{
+				"deploy": {
+					AuthStrategy:    "any",
+					ChannelStrategy: "any",
+					Timeout:         10,
+				},
+			},
+		})
+		mocks.Must(t, "could not register client", err)
+
+		time.Sleep(10 * time.Millisecond)
+
+		jobCtx, stop := meeseeks.WithStop(context.Background())
+		type execution struct {
+			result meeseeks.JobResult
+			err    error
+		}
+		executed := make(chan execution)
+		go func() {
+			cmd, ok := commands.Find(&meeseeks.Request{Command: "deploy"})
+			mocks.AssertEquals(t, true, ok)
+
+			_, result, err := cmd.(meeseeks.ReportingCommand).ExecuteWithResult(jobCtx, meeseeks.Job{
+				ID:      28,
+				Request: meeseeks.Request{Command: "deploy"},
+				Status:  meeseeks.JobRunningStatus,
+			})
+			executed <- execution{result: result, err: err}
+		}()
+
+		cmdReq, err := pipeline.Recv()
+		mocks.Must(t, "failed receiving command requests", err)
+		mocks.AssertEquals(t, uint64(28), cmdReq.GetJobID())
+
+		stop(meeseeks.Stop{Kind: meeseeks.StopCancel, Username: "someone"})
+
+		cancelReq, err := pipeline.Recv()
+		mocks.Must(t, "failed receiving the cancellation", err)
+		mocks.AssertEquals(t, "cancel", cancelReq.GetCancel().GetStop())
+		mocks.AssertEquals(t, "someone", cancelReq.GetCancel().GetUsername())
+		mocks.AssertEquals(t, uint64(28), cancelReq.GetCancel().GetJobID())
+
+		_, err = cmdClient.Finish(ctx, &api.CommandFinish{
+			AgentID:  "agentID2",
+			JobID:    28,
+			Error:    "context canceled",
+			ExitCode: 3,
+			Hostname: "worker-1",
+		})
+		mocks.Must(t, "could not finish the job", err)
+
+		e := <-executed
+		mocks.AssertEquals(t, "command failed because of context done: context canceled", e.err.Error())
+		mocks.AssertEquals(t, meeseeks.JobResult{ExitCode: 3, AgentID: "agentID2", Hostname: "worker-1"}, e.result)
+	})
+}
@@ -0,0 +1,63 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/scheduler"
+)
+
+func TestCronNextFireTime(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2019, time.January, 16, 10, 30, 15, 0, time.UTC)
+
+	tt := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2019, time.January, 16, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2019, time.January, 16, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2019, time.January, 17, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2019, time.January, 16, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1,5", time.Date(2019, time.January, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2019, time.January, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 0", time.Date(2019, time.January, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2019, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2019, time.January, 16, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.expr, func(t *testing.T) {
+			c, err := scheduler.ParseCron(tc.expr)
+			mocks.Must(t, "could not parse cron expression", err)
+			mocks.AssertEquals(t, tc.expected, c.Next(now))
+		})
+	}
+}
+
+func TestInvalidCronExpressions(t *testing.T) {
+	tt := []struct {
+		expr     string
+		expected string
+	}{
+		{"* * * *", `cron expression "* * * *" should have 5 fields but has 4`},
+		{"@often", "unknown cron macro @often"},
+		{"60 * * * *", `invalid cron expression "60 * * * *": minute 60 is out of range 0-59`},
+		{"* * 0 * *", `invalid cron expression "* * 0 * *": day of month 0 is out of range 1-31`},
+		{"*/0 * * * *", `invalid cron expression "*/0 * * * *": invalid step in minute */0`},
+		{"5-1 * * * *", `invalid cron expression "5-1 * * * *": invalid range in minute 5-1`},
+		{"a * * * *", `invalid cron expression "a * * * *": invalid minute a`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := scheduler.ParseCron(tc.expr)
+			mocks.AssertEquals(t, tc.expected, err.Error())
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/api"
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
+)
+
+// Schedule is a command that runs periodically on behalf of a user
+type Schedule struct {
+	Cron    string   `yaml:"cron"`
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	User    string   `yaml:"user"`
+	Channel string   `yaml:"channel"`
+
+	// Origin is the chat backend the channel belongs to, the default one when empty
+	Origin string `yaml:"origin"`
+}
+
+// Entry is a configured schedule along with its next fire time
+type Entry struct {
+	Name     string
+	Schedule Schedule
+	Next     time.Time
+}
+
+type entry struct {
+	name     string
+	schedule Schedule
+	cron     Cron
+}
+
+var schedules = struct {
+	entries []entry
+	changed chan struct{}
+	m       sync.RWMutex
+}{
+	changed: make(chan struct{}, 1),
+}
+
+// Validate checks the configured schedules without loading them
+func Validate(configured map[string]Schedule) error {
+	_, err := parse(configured)
+	return err
+}
+
+// Configure parses and loads the configured schedules, replacing the previous ones
+func Configure(configured map[string]Schedule) error {
+	entries, err := parse(configured)
+	if err != nil {
+		return err
+	}
+
+	schedules.m.Lock()
+	schedules.entries = entries
+	schedules.m.Unlock()
+
+	notifyChange()
+	return nil
+}
+
+func parse(configured map[string]Schedule) ([]entry, error) {
+	entries := make([]entry, 0, len(configured))
+	for name, s := range configured {
+		c, err := ParseCron(s.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %s: %s", name, err)
+		}
+		if s.Command == "" {
+			return nil, fmt.Errorf("invalid schedule %s: no command to run", name)
+		}
+		if s.User == "" {
+			return nil, fmt.Errorf("invalid schedule %s: no user to run the command as", name)
+		}
+		entries = append(entries, entry{name: name, schedule: s, cron: c})
+	}
+	return entries, nil
+}
+
+// List returns the configured schedules sorted by their next fire time after now
+func List(now time.Time) []Entry {
+	schedules.m.RLock()
+	defer schedules.m.RUnlock()
+
+	list := make([]Entry, 0, len(schedules.entries))
+	for _, e := range schedules.entries {
+		list = append(list, Entry{
+			Name:     e.name,
+			Schedule: e.schedule,
+			Next:     e.cron.Next(now),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Next.Equal(list[j].Next) {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Next.Before(list[j].Next)
+	})
+	return list
+}
+
+// Scheduler sends the requests of the configured schedules when they are due
+type Scheduler struct {
+	enrichers *api.Enrichers
+	shutdown  chan bool
+}
+
+// New returns a new scheduler that uses the enricher to resolve the channel of
+// the scheduled requests that have no origin
+func New(enricher api.Enricher) *Scheduler {
+	return &Scheduler{
+		enrichers: api.NewEnrichers(enricher),
+		shutdown:  make(chan bool),
+	}
+}
+
+// RegisterEnricher registers the enricher used for the schedules of the chat backend with the given origin
+func (s *Scheduler) RegisterEnricher(origin string, enricher api.Enricher) {
+	s.enrichers.Register(origin, enricher)
+}
+
+// Listen implements the executor.Listener interface
+func (s *Scheduler) Listen(ch chan<- meeseeks.Request) {
+	logrus.Info("Scheduler started")
+	for {
+		now := time.Now()
+		due := s.dueFirings(now)
+
+		var fire <-chan time.Time
+		var timer *time.Timer
+		if len(due) > 0 {
+			timer = time.NewTimer(due[0].next.Sub(now))
+			fire = timer.C
+		}
+
+		select {
+		case <-fire:
+			for _, f := range due {
+				req := f.request()
+				logrus.Infof("Running schedule %s as user %s: %s %s", f.name, req.Username,
+					req.Command, req.Args)
+				ch <- req
+				f.done()
+			}
+
+		case <-schedules.changed:
+			logrus.Debug("Schedules changed, recalculating the next fire time")
+			stopTimer(timer)
+
+		case <-s.shutdown:
+			logrus.Info("Scheduler stopped")
+			stopTimer(timer)
+			return
+		}
+	}
+}
+
+func stopTimer(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// Shutdown stops the scheduler
+func (s *Scheduler) Shutdown() error {
+	s.shutdown <- true
+	return nil
+}
+
+// firing is a request that is due at a given time, the request is only built
+// when it fires because it may need to call the chat api
+type firing struct {
+	name    string
+	next    time.Time
+	request func() meeseeks.Request
+	done    func()
+}
+
+// dueFirings returns the configured and persisted schedules that fire the soonest after now
+func (s *Scheduler) dueFirings(now time.Time) []firing {
+	firings := make([]firing, 0)
+	for _, e := range List(now) {
+		schedule := e.Schedule
+		firings = append(firings, firing{
+			name:    e.Name,
+			next:    e.Next,
+			request: func() meeseeks.Request { return s.request(schedule) },
+			done:    func() {},
+		})
+	}
+
+	persisted, err := persistence.Schedules().Find(meeseeks.ScheduleFilter{})
+	if err != nil {
+		logrus.Errorf("Could not load the persisted schedules: %s", err)
+	}
+	for _, p := range persisted {
+		req := p.Request
+		firings = append(firings, firing{
+			name:    fmt.Sprintf("%d", p.ID),
+			next:    NextRun(p, now),
+			request: func() meeseeks.Request { return req },
+			done:    doneFunc(p),
+		})
+	}
+
+	sort.SliceStable(firings, func(i, j int) bool {
+		return firings[i].next.Before(firings[j].next)
+	})
+
+	due := make([]firing, 0)
+	for _, f := range firings {
+		if f.next.IsZero() {
+			continue
+		}
+		if len(due) > 0 && !f.next.Equal(due[0].next) {
+			break
+		}
+		due = append(due, f)
+	}
+	return due
+}
+
+// doneFunc returns what to do once a persisted schedule fired, which is
+// removing it when it only had to run once
+func doneFunc(s meeseeks.Schedule) func() {
+	if s.IsRecurring() {
+		return func() {}
+	}
+	return func() {
+		if err := persistence.Schedules().Remove(s.ID); err != nil {
+			logrus.Errorf("Could not remove schedule %d after running it: %s", s.ID, err)
+		}
+	}
+}
+
+// NextRun returns when a persisted schedule runs next after now. A one-shot
+// schedule that was due while meeseeks was not running is due right away.
+func NextRun(s meeseeks.Schedule, now time.Time) time.Time {
+	if !s.IsRecurring() {
+		if s.RunAt.Before(now) {
+			return now
+		}
+		return s.RunAt
+	}
+	c, err := ParseCron(s.Cron)
+	if err != nil {
+		logrus.Errorf("Schedule %d has an invalid cron expression: %s", s.ID, err)
+		return time.Time{}
+	}
+	return c.Next(now)
+}
+
+// Add persists a schedule created by a user and lets the scheduler know about it
+func Add(s meeseeks.Schedule) (meeseeks.Schedule, error) {
+	s, err := persistence.Schedules().Create(s)
+	if err != nil {
+		return s, err
+	}
+	notifyChange()
+	return s, nil
+}
+
+// Remove deletes a schedule created by a user and lets the scheduler know about it
+func Remove(id uint64) error {
+	if err := persistence.Schedules().Remove(id); err != nil {
+		return err
+	}
+	notifyChange()
+	return nil
+}
+
+// notifyChange lets the running scheduler know it has to recalculate when to fire
+func notifyChange() {
+	select {
+	case schedules.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) request(schedule Schedule) meeseeks.Request {
+	args := append([]string{}, schedule.Args...)
+	enricher := s.enrichers.For(schedule.Origin)
+	return meeseeks.Request{
+		Command:     schedule.Command,
+		Args:        args,
+		Username:    schedule.User,
+		UserID:      schedule.User,
+		UserLink:    schedule.User,
+		ChannelID:   schedule.Channel,
+		Channel:     enricher.GetChannel(schedule.Channel),
+		ChannelLink: enricher.GetChannelLink(schedule.Channel),
+		IsIM:        enricher.IsIM(schedule.Channel),
+		Origin:      schedule.Origin,
+	}
+}
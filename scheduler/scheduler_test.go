@@ -0,0 +1,80 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/persistence"
+	"gitlab.com/yakshaving.art/meeseeks-box/scheduler"
+)
+
+func TestListingSchedules(t *testing.T) {
+	now := time.Date(2019, time.January, 16, 10, 30, 0, 0, time.UTC)
+
+	mocks.Must(t, "could not configure schedules", scheduler.Configure(map[string]scheduler.Schedule{
+		"nightly": {Cron: "@daily", Command: "backup", User: "someone", Channel: "C1"},
+		"hourly":  {Cron: "@hourly", Command: "report", User: "someone", Channel: "C1"},
+	}))
+
+	list := scheduler.List(now)
+	mocks.AssertEquals(t, 2, len(list))
+	mocks.AssertEquals(t, "hourly", list[0].Name)
+	mocks.AssertEquals(t, time.Date(2019, time.January, 16, 11, 0, 0, 0, time.UTC), list[0].Next)
+	mocks.AssertEquals(t, "nightly", list[1].Name)
+	mocks.AssertEquals(t, time.Date(2019, time.January, 17, 0, 0, 0, 0, time.UTC), list[1].Next)
+
+	err := scheduler.Configure(map[string]scheduler.Schedule{
+		"broken": {Cron: "@often", Command: "backup", User: "someone"},
+	})
+	mocks.AssertEquals(t, "invalid schedule broken: unknown cron macro @often", err.Error())
+
+	err = scheduler.Configure(map[string]scheduler.Schedule{
+		"nobody": {Cron: "@daily", Command: "backup"},
+	})
+	mocks.AssertEquals(t, "invalid schedule nobody: no user to run the command as", err.Error())
+}
+
+func TestSchedulerSendsDueRequests(t *testing.T) {
+	mocks.Must(t, "failed to run tests", mocks.WithTmpDB(func(_ string) {
+		mocks.Must(t, "could not clear the configured schedules", scheduler.Configure(nil))
+
+		s := scheduler.New(mocks.EnricherStub{})
+		ch := make(chan meeseeks.Request)
+		go s.Listen(ch)
+		defer s.Shutdown()
+
+		sch, err := scheduler.Add(meeseeks.Schedule{
+			Request: meeseeks.Request{
+				Command:   "echo",
+				Args:      []string{"hello"},
+				Username:  "someone",
+				ChannelID: "C1",
+				Origin:    "matrix",
+			},
+			Spec:  "soon",
+			RunAt: time.Now().Add(100 * time.Millisecond),
+		})
+		mocks.Must(t, "could not add schedule", err)
+
+		select {
+		case req := <-ch:
+			mocks.AssertEquals(t, "echo", req.Command)
+			mocks.AssertEquals(t, []string{"hello"}, req.Args)
+			mocks.AssertEquals(t, "C1", req.ChannelID)
+			mocks.AssertEquals(t, "matrix", req.Origin)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the scheduled request was never sent")
+		}
+
+		// One-shot schedules are removed once they fire
+		for i := 0; i < 50; i++ {
+			if _, err = persistence.Schedules().Get(sch.ID); err != nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		mocks.AssertEquals(t, meeseeks.ErrNoScheduleWithID, err)
+	}))
+}
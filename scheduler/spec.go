@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var everyDays = map[string]string{
+	"day":       "*",
+	"weekday":   "1-5",
+	"weekend":   "0,6",
+	"sunday":    "0",
+	"monday":    "1",
+	"tuesday":   "2",
+	"wednesday": "3",
+	"thursday":  "4",
+	"friday":    "5",
+	"saturday":  "6",
+}
+
+var atLayouts = []string{
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// ParseEvery parses a recurring schedule, which can be a cron expression or a
+// sentence like "every weekday 09:00", "every monday at 10:30", "every hour"
+// or "every 15 minutes"
+func ParseEvery(spec string) (Cron, error) {
+	fields := strings.Fields(strings.ToLower(spec))
+	if len(fields) == 0 || fields[0] != "every" {
+		return ParseCron(spec)
+	}
+	fields = fields[1:]
+
+	expr, err := everyToCron(fields)
+	if err != nil {
+		return Cron{}, fmt.Errorf("invalid schedule %q: %s", spec, err)
+	}
+	return ParseCron(expr)
+}
+
+func everyToCron(fields []string) (string, error) {
+	if len(fields) == 0 {
+		return "", fmt.Errorf("every what?")
+	}
+
+	every := 1
+	if n, err := strconv.Atoi(fields[0]); err == nil {
+		if n <= 0 || len(fields) != 2 {
+			return "", fmt.Errorf("expected a number of minutes or hours")
+		}
+		every, fields = n, fields[1:]
+	}
+
+	switch strings.TrimSuffix(fields[0], "s") {
+	case "minute":
+		if len(fields) != 1 {
+			return "", fmt.Errorf("unexpected %s", strings.Join(fields[1:], " "))
+		}
+		return fmt.Sprintf("*/%d * * * *", every), nil
+	case "hour":
+		if len(fields) != 1 {
+			return "", fmt.Errorf("unexpected %s", strings.Join(fields[1:], " "))
+		}
+		return fmt.Sprintf("0 */%d * * *", every), nil
+	}
+
+	days, ok := everyDays[fields[0]]
+	if !ok || every != 1 {
+		return "", fmt.Errorf("unknown period %s", fields[0])
+	}
+
+	fields = fields[1:]
+	if len(fields) > 0 && fields[0] == "at" {
+		fields = fields[1:]
+	}
+	hour, minute := 0, 0
+	switch len(fields) {
+	case 0:
+	case 1:
+		t, err := time.Parse("15:04", fields[0])
+		if err != nil {
+			return "", fmt.Errorf("invalid time of the day %s, it should look like 15:04", fields[0])
+		}
+		hour, minute = t.Hour(), t.Minute()
+	default:
+		return "", fmt.Errorf("unexpected %s", strings.Join(fields[1:], " "))
+	}
+	return fmt.Sprintf("%d %d * * %s", minute, hour, days), nil
+}
+
+// ParseAt parses the time a one-shot schedule runs at, which can be a time of
+// the day like 18:00, meaning its next occurrence after now, or a full date
+// like 2006-01-02T15:04
+func ParseAt(spec string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("15:04", spec, now.Location()); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil
+	}
+
+	for _, layout := range atLayouts {
+		at, err := time.ParseInLocation(layout, spec, now.Location())
+		if err != nil {
+			continue
+		}
+		if !at.After(now) {
+			return time.Time{}, fmt.Errorf("%s is in the past", spec)
+		}
+		return at, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s, it should look like 15:04 or 2006-01-02T15:04", spec)
+}
@@ -0,0 +1,88 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/scheduler"
+)
+
+func TestParseEvery(t *testing.T) {
+	tt := []struct {
+		spec     string
+		expected string
+	}{
+		{spec: "every weekday 09:00", expected: "0 9 * * 1-5"},
+		{spec: "every Monday at 10:30", expected: "30 10 * * 1"},
+		{spec: "every day", expected: "0 0 * * *"},
+		{spec: "every weekend 12:15", expected: "15 12 * * 0,6"},
+		{spec: "every hour", expected: "0 */1 * * *"},
+		{spec: "every 2 hours", expected: "0 */2 * * *"},
+		{spec: "every 15 minutes", expected: "*/15 * * * *"},
+		{spec: "30 8 * * *", expected: "30 8 * * *"},
+		{spec: "@daily", expected: "@daily"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.spec, func(t *testing.T) {
+			c, err := scheduler.ParseEvery(tc.spec)
+			mocks.Must(t, "could not parse schedule", err)
+			mocks.AssertEquals(t, tc.expected, c.String())
+		})
+	}
+}
+
+func TestInvalidEverySpecs(t *testing.T) {
+	tt := []struct {
+		spec     string
+		expected string
+	}{
+		{spec: "every", expected: `invalid schedule "every": every what?`},
+		{spec: "every fortnight", expected: `invalid schedule "every fortnight": unknown period fortnight`},
+		{spec: "every day 25:00", expected: `invalid schedule "every day 25:00": invalid time of the day 25:00, it should look like 15:04`},
+		{spec: "every hour 09:00", expected: `invalid schedule "every hour 09:00": unexpected 09:00`},
+		{spec: "every 0 minutes", expected: `invalid schedule "every 0 minutes": expected a number of minutes or hours`},
+	}
+	for _, tc := range tt {
+		t.Run(tc.spec, func(t *testing.T) {
+			_, err := scheduler.ParseEvery(tc.spec)
+			mocks.AssertEquals(t, tc.expected, err.Error())
+		})
+	}
+}
+
+func TestParseAt(t *testing.T) {
+	now := time.Date(2019, time.January, 16, 10, 30, 0, 0, time.UTC)
+
+	at, err := scheduler.ParseAt("18:00", now)
+	mocks.Must(t, "could not parse time of the day", err)
+	mocks.AssertEquals(t, time.Date(2019, time.January, 16, 18, 0, 0, 0, time.UTC), at)
+
+	at, err = scheduler.ParseAt("09:00", now)
+	mocks.Must(t, "could not parse time of the day already passed", err)
+	mocks.AssertEquals(t, time.Date(2019, time.January, 17, 9, 0, 0, 0, time.UTC), at)
+
+	at, err = scheduler.ParseAt("2019-02-01T08:15", now)
+	mocks.Must(t, "could not parse date", err)
+	mocks.AssertEquals(t, time.Date(2019, time.February, 1, 8, 15, 0, 0, time.UTC), at)
+
+	_, err = scheduler.ParseAt("2019-01-01T08:15", now)
+	mocks.AssertEquals(t, "2019-01-01T08:15 is in the past", err.Error())
+
+	_, err = scheduler.ParseAt("tomorrow", now)
+	mocks.AssertEquals(t, "invalid time tomorrow, it should look like 15:04 or 2006-01-02T15:04", err.Error())
+}
+
+func TestNextRunOfPersistedSchedules(t *testing.T) {
+	now := time.Date(2019, time.January, 16, 10, 30, 0, 0, time.UTC)
+
+	recurring := meeseeks.Schedule{Cron: "0 9 * * 1-5"}
+	mocks.AssertEquals(t, time.Date(2019, time.January, 17, 9, 0, 0, 0, time.UTC), scheduler.NextRun(recurring, now))
+
+	future := meeseeks.Schedule{RunAt: now.Add(time.Hour)}
+	mocks.AssertEquals(t, now.Add(time.Hour), scheduler.NextRun(future, now))
+
+	missed := meeseeks.Schedule{RunAt: now.Add(-time.Hour)}
+	mocks.AssertEquals(t, now, scheduler.NextRun(missed, now))
+}
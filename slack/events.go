@@ -0,0 +1,193 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+
+	"github.com/nlopes/slack"
+	"github.com/sirupsen/logrus"
+)
+
+// Events api callback types
+const (
+	callbackURLVerification = "url_verification"
+	callbackEvent           = "event_callback"
+)
+
+// maxRequestAge is how old a signed request can be before being considered a replay
+const maxRequestAge = 5 * time.Minute
+
+// pendingSize is how many received messages can wait for the listener before
+// new ones are dropped
+const pendingSize = 100
+
+var errInvalidSignature = errors.New("invalid request signature")
+
+type eventCallback struct {
+	Type      string          `json:"type"`
+	Challenge string          `json:"challenge"`
+	Event     json.RawMessage `json:"event"`
+}
+
+// EventsListener receives slack events api callbacks over http
+type EventsListener struct {
+	client        *Client
+	signingSecret string
+
+	incoming chan *slack.MessageEvent
+	done     chan struct{}
+}
+
+// NewEventsListener creates a new events api listener and registers it in the passed mux and path
+func NewEventsListener(mux *http.ServeMux, client *Client, path, signingSecret string) *EventsListener {
+	e := &EventsListener{
+		client:        client,
+		signingSecret: signingSecret,
+
+		incoming: make(chan *slack.MessageEvent, pendingSize),
+		done:     make(chan struct{}),
+	}
+	mux.HandleFunc(path, e.HandleEvent)
+	return e
+}
+
+// Listen implements the executor.Listener interface
+func (e *EventsListener) Listen(ch chan<- meeseeks.Request) {
+	logrus.Infof("Listening Slack Events API Messages")
+
+	messages := make(chan *slack.MessageEvent)
+	go e.client.listen(messages, ch)
+
+	defer close(messages)
+	for {
+		select {
+		case msg := <-e.incoming:
+			messages <- msg
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// Shutdown stops listening for events
+func (e *EventsListener) Shutdown() error {
+	logrus.Infof("Shutting down Slack Events API listener")
+	close(e.done)
+	return nil
+}
+
+// HandleEvent implements the http handle request function interface
+func (e *EventsListener) HandleEvent(w http.ResponseWriter, r *http.Request) {
+	body, err := readSignedBody(r, e.signingSecret)
+	if err != nil {
+		logrus.Debugf("Rejected slack event: %s", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	cb := eventCallback{}
+	if err := json.Unmarshal(body, &cb); err != nil {
+		http.Error(w, fmt.Sprintf("invalid event payload: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	switch cb.Type {
+	case callbackURLVerification:
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(cb.Challenge))
+		return
+
+	case callbackEvent:
+		if retry := r.Header.Get("X-Slack-Retry-Num"); retry != "" {
+			logrus.Debugf("Ignoring slack event retry %s: %s", retry, r.Header.Get("X-Slack-Retry-Reason"))
+			break
+		}
+		if msg, ok := parseMessageEvent(cb.Event); ok {
+			// Slack expects an answer within 3 seconds, so don't wait for the executor
+			select {
+			case e.incoming <- msg:
+			case <-e.done:
+				logrus.Debugf("Dropping slack event %s, the listener is shut down", msg.Timestamp)
+			default:
+				logrus.Warnf("Dropping slack event %s, too many events are waiting to be processed", msg.Timestamp)
+			}
+		}
+
+	default:
+		logrus.Debugf("Ignored Slack Events API callback %s", cb.Type)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// readSignedBody reads the request body checking that it has been signed with
+// the slack signing secret
+func readSignedBody(r *http.Request, signingSecret string) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read request body: %s", err)
+	}
+	if err := verifySignature(signingSecret, r.Header, body, time.Now()); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func verifySignature(signingSecret string, header http.Header, body []byte, now time.Time) error {
+	if signingSecret == "" {
+		return fmt.Errorf("no signing secret configured")
+	}
+
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp %s", timestamp)
+	}
+	if math.Abs(now.Sub(time.Unix(ts, 0)).Seconds()) > maxRequestAge.Seconds() {
+		return fmt.Errorf("request timestamp %s is too old", timestamp)
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(fmt.Sprintf("v0:%s:", timestamp)))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// parseMessageEvent turns an events api inner event into a message event,
+// returning false if it is not a message or a mention
+func parseMessageEvent(event json.RawMessage) (*slack.MessageEvent, bool) {
+	ev := struct {
+		Type string `json:"type"`
+	}{}
+	if err := json.Unmarshal(event, &ev); err != nil {
+		logrus.Errorf("could not parse event %s: %s", event, err)
+		return nil, false
+	}
+	if ev.Type != "message" && ev.Type != "app_mention" {
+		logrus.Debugf("Ignored Slack Event %s", event)
+		return nil, false
+	}
+
+	msg := &slack.MessageEvent{}
+	if err := json.Unmarshal(event, msg); err != nil {
+		logrus.Errorf("could not parse message event %s: %s", event, err)
+		return nil, false
+	}
+	return msg, true
+}
@@ -0,0 +1,107 @@
+package slack_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/slack"
+)
+
+func signedRequest(secret, path, body string, ts time.Time) *http.Request {
+	timestamp := fmt.Sprintf("%d", ts.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+func TestEventsListener(t *testing.T) {
+	l := slack.NewEventsListener(http.NewServeMux(), nil, "/slack/events-test", "secret")
+
+	tt := []struct {
+		name           string
+		req            *http.Request
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "url verification",
+			req: signedRequest("secret", "/slack/events-test",
+				`{"type": "url_verification", "challenge": "challenge-accepted"}`, time.Now()),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "challenge-accepted",
+		},
+		{
+			name: "ignored event",
+			req: signedRequest("secret", "/slack/events-test",
+				`{"type": "event_callback", "event": {"type": "reaction_added"}}`, time.Now()),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "wrong secret",
+			req: signedRequest("other-secret", "/slack/events-test",
+				`{"type": "url_verification", "challenge": "challenge-accepted"}`, time.Now()),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "invalid request signature\n",
+		},
+		{
+			name: "replayed request",
+			req: signedRequest("secret", "/slack/events-test",
+				`{"type": "url_verification", "challenge": "challenge-accepted"}`, time.Now().Add(-10*time.Minute)),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unsigned request",
+			req:            httptest.NewRequest(http.MethodPost, "/slack/events-test", strings.NewReader("{}")),
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			l.HandleEvent(w, tc.req)
+
+			mocks.AssertEquals(t, tc.expectedStatus, w.Code)
+			if tc.expectedBody != "" {
+				mocks.AssertEquals(t, tc.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestEventsListenerDoesNotBlockOnceShutDown(t *testing.T) {
+	server, _ := newFakeSlackAPI(t, nil)
+	defer server.Close()
+
+	l := slack.NewEventsListener(http.NewServeMux(), connectToFakeSlackAPI(t, server), "/slack/events-test", "secret")
+	mocks.Must(t, "could not shut down the listener", l.Shutdown())
+
+	w := httptest.NewRecorder()
+	l.HandleEvent(w, signedRequest("secret", "/slack/events-test",
+		`{"type": "event_callback", "event": {"type": "message", "user": "U1", "text": "echo hello", "channel": "D1", "ts": "1.1"}}`, time.Now()))
+	mocks.AssertEquals(t, http.StatusOK, w.Code)
+
+	done := make(chan bool)
+	go func() {
+		l.Listen(make(chan meeseeks.Request, 1))
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("listener did not stop after being shut down")
+	}
+}
@@ -0,0 +1,133 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+
+	"github.com/nlopes/slack"
+	"github.com/sirupsen/logrus"
+)
+
+// Confirmation button actions
+const (
+	actionApprove = "approve"
+	actionCancel  = "cancel"
+)
+
+// ConfirmFunc answers a pending confirmation on behalf of a user, returning the
+// request that was answered
+type ConfirmFunc func(confirmationID, userID string, approved bool) (meeseeks.Request, error)
+
+// InteractionHandler receives the payloads slack sends when a user clicks a message button
+type InteractionHandler struct {
+	signingSecret string
+	confirm       ConfirmFunc
+}
+
+// NewInteractionHandler creates a new interactive payload handler and registers it in the passed mux and path
+func NewInteractionHandler(mux *http.ServeMux, path, signingSecret string, confirm ConfirmFunc) *InteractionHandler {
+	h := &InteractionHandler{
+		signingSecret: signingSecret,
+		confirm:       confirm,
+	}
+	mux.HandleFunc(path, h.HandleInteraction)
+	return h
+}
+
+type interactionPayload struct {
+	CallbackID string `json:"callback_id"`
+	Actions    []struct {
+		Name string `json:"name"`
+	} `json:"actions"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+}
+
+type interactionResponse struct {
+	ResponseType    string `json:"response_type,omitempty"`
+	ReplaceOriginal bool   `json:"replace_original"`
+	Text            string `json:"text"`
+}
+
+// HandleInteraction implements the http handle request function interface
+func (h *InteractionHandler) HandleInteraction(w http.ResponseWriter, r *http.Request) {
+	body, err := readSignedBody(r, h.signingSecret)
+	if err != nil {
+		logrus.Debugf("Rejected slack interaction: %s", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid interaction payload: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	payload := interactionPayload{}
+	if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		http.Error(w, fmt.Sprintf("invalid interaction payload: %s", err), http.StatusBadRequest)
+		return
+	}
+	if len(payload.Actions) == 0 {
+		http.Error(w, "interaction payload has no actions", http.StatusBadRequest)
+		return
+	}
+
+	approved := payload.Actions[0].Name == actionApprove
+	req, err := h.confirm(payload.CallbackID, payload.User.ID, approved)
+	if err != nil {
+		logrus.Debugf("Could not answer confirmation %s from user %s: %s", payload.CallbackID, payload.User.ID, err)
+		// An ephemeral answer is only shown to the user that clicked, leaving the buttons in place
+		writeInteractionResponse(w, interactionResponse{
+			ResponseType: "ephemeral",
+			Text:         err.Error(),
+		})
+		return
+	}
+
+	verb := "cancelled"
+	if approved {
+		verb = "approved"
+	}
+	writeInteractionResponse(w, interactionResponse{
+		ReplaceOriginal: true,
+		Text:            fmt.Sprintf("<@%s> %s `%s`", payload.User.ID, verb, strings.Join(append([]string{req.Command}, req.Args...), " ")),
+	})
+}
+
+func writeInteractionResponse(w http.ResponseWriter, resp interactionResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logrus.Errorf("could not write slack interaction response: %s", err)
+	}
+}
+
+func confirmationAttachment(confirmationID string) slack.Attachment {
+	return slack.Attachment{
+		Fallback:   "Confirm or cancel the request",
+		CallbackID: confirmationID,
+		Actions: []slack.AttachmentAction{
+			{
+				Name:  actionApprove,
+				Text:  "Approve",
+				Type:  "button",
+				Style: "primary",
+				Value: actionApprove,
+			},
+			{
+				Name:  actionCancel,
+				Text:  "Cancel",
+				Type:  "button",
+				Style: "danger",
+				Value: actionCancel,
+			},
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package slack_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/slack"
+)
+
+func TestInteractionHandlerAnswersConfirmations(t *testing.T) {
+	type answer struct {
+		id       string
+		userID   string
+		approved bool
+	}
+	answers := make(chan answer, 1)
+
+	h := slack.NewInteractionHandler(http.NewServeMux(), "/slack/interactive-test", "secret",
+		func(id, userID string, approved bool) (meeseeks.Request, error) {
+			answers <- answer{id, userID, approved}
+			if userID != "U1" {
+				return meeseeks.Request{}, errors.New("only the user that sent the request can answer it")
+			}
+			return meeseeks.Request{Command: "deploy", Args: []string{"prod"}}, nil
+		})
+
+	payload := func(userID, action string) string {
+		return url.Values{"payload": []string{`{"type":"interactive_message","callback_id":"confirmation-1",` +
+			`"actions":[{"name":"` + action + `","type":"button"}],"user":{"id":"` + userID + `"}}`}}.Encode()
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleInteraction(w, signedRequest("other-secret", "/slack/interactive-test", payload("U1", "approve"), time.Now()))
+	mocks.AssertEquals(t, http.StatusUnauthorized, w.Code)
+
+	tt := []struct {
+		name     string
+		userID   string
+		action   string
+		expected map[string]interface{}
+	}{
+		{
+			name:   "approve",
+			userID: "U1",
+			action: "approve",
+			expected: map[string]interface{}{
+				"replace_original": true,
+				"text":             "<@U1> approved `deploy prod`",
+			},
+		},
+		{
+			name:   "cancel",
+			userID: "U1",
+			action: "cancel",
+			expected: map[string]interface{}{
+				"replace_original": true,
+				"text":             "<@U1> cancelled `deploy prod`",
+			},
+		},
+		{
+			name:   "another user",
+			userID: "U2",
+			action: "approve",
+			expected: map[string]interface{}{
+				"response_type":    "ephemeral",
+				"replace_original": false,
+				"text":             "only the user that sent the request can answer it",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.HandleInteraction(w, signedRequest("secret", "/slack/interactive-test", payload(tc.userID, tc.action), time.Now()))
+			mocks.AssertEquals(t, http.StatusOK, w.Code)
+			mocks.AssertEquals(t, answer{"confirmation-1", tc.userID, tc.action == "approve"}, <-answers)
+
+			response := map[string]interface{}{}
+			mocks.Must(t, "could not decode the response", json.NewDecoder(w.Body).Decode(&response))
+			mocks.AssertEquals(t, tc.expected, response)
+		})
+	}
+}
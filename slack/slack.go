@@ -2,9 +2,13 @@ package slack
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/auth"
@@ -26,14 +30,25 @@ const (
 	disabledStyle = "disabled"
 )
 
+// Origin identifies the requests that come from slack
+const Origin = "slack"
+
+// Connection modes
+const (
+	ModeRTM    = "rtm"
+	ModeSocket = "socket"
+	ModeEvents = "events"
+)
+
 // Client is a chat client
 type Client struct {
 	apiClient *slack.Client
-	// TODO: remove the rtm as it should only be inside the message matcher.
-	// It should simply be inside there and it should pop messages matched out
-	// through a channel
-	rtm     *slack.RTM
-	matcher messageMatcher
+	// token and httpClient are used to call the api methods the slack client lacks
+	token      string
+	httpClient slack.HTTPRequester
+	incoming   chan *slack.MessageEvent
+	matcher    messageMatcher
+	seen       *seenMessages
 }
 
 // ParseChannelLink implements the messenger.MessengerClient interface
@@ -102,9 +117,14 @@ func (c Client) getReplyStyle(style string) replyStyle {
 
 // ConnectionOpts groups all the connection options in a single struct
 type ConnectionOpts struct {
-	Debug   bool
-	Token   string
-	Stealth bool
+	Debug    bool
+	Token    string
+	AppToken string
+	Mode     string
+	Stealth  bool
+
+	// APIURL overrides the slack api endpoint, it is only useful for testing
+	APIURL string
 }
 
 // Connect builds a new chat client
@@ -113,49 +133,163 @@ func Connect(opts ConnectionOpts) (*Client, error) {
 		return nil, fmt.Errorf("could not connect to slack: SLACK_TOKEN env var is empty")
 	}
 
-	slackClient := slack.New(opts.Token)
+	var httpClient slack.HTTPRequester = http.DefaultClient
+	if opts.APIURL != "" {
+		httpClient = apiURLClient{
+			apiURL: opts.APIURL,
+			client: http.DefaultClient,
+		}
+	}
+	slackClient := slack.New(opts.Token, slack.OptionHTTPClient(httpClient))
 	slackClient.SetDebug(opts.Debug)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	_, err := slackClient.AuthTestContext(ctx)
+	authInfo, err := slackClient.AuthTestContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not connect to slack: %s", err)
 	}
 
-	rtm := slackClient.NewRTM(slack.RTMOptionUseStart(false))
-	go rtm.ManageConnection()
+	incoming := make(chan *slack.MessageEvent)
+
+	switch opts.Mode {
+	case ModeSocket:
+		if opts.AppToken == "" {
+			return nil, fmt.Errorf("could not connect to slack: socket mode requires an app level token")
+		}
+		socketMode := NewSocketMode(SocketModeOpts{
+			AppToken: opts.AppToken,
+		})
+		go func() {
+			if err := socketMode.Run(incoming); err != nil {
+				logrus.Errorf("Slack socket mode connection is gone: %s", err)
+			}
+			close(incoming)
+		}()
+
+	case ModeRTM, "":
+		rtm := slackClient.NewRTM(slack.RTMOptionUseStart(false))
+		go rtm.ManageConnection()
+		go listenRTM(rtm, incoming)
+
+	case ModeEvents:
+		logrus.Info("Not connecting to slack, messages will be received through the events api")
+
+	default:
+		return nil, fmt.Errorf("invalid slack connection mode %s, valid modes are %s, %s and %s",
+			opts.Mode, ModeRTM, ModeSocket, ModeEvents)
+	}
 
 	if opts.Stealth {
 		logrus.Info("Running in stealth mode")
-		rtm.SetUserPresence("away")
+		slackClient.SetUserPresence("away")
 	}
 
 	return &Client{
-		apiClient: slackClient,
-		rtm:       rtm,
-		matcher:   newMessageMatcher(rtm, opts.Stealth),
+		apiClient:  slackClient,
+		token:      opts.Token,
+		httpClient: httpClient,
+		incoming:   incoming,
+		matcher:    newMessageMatcher(slackClient, authInfo.UserID, opts.Stealth),
+		seen:       newSeenMessages(100),
 	}, nil
 }
 
+// apiURLClient sends the requests meant for the slack api to another endpoint,
+// as the slack client always calls the default one
+type apiURLClient struct {
+	apiURL string
+	client *http.Client
+}
+
+// Do implements slack.HTTPRequester
+func (a apiURLClient) Do(req *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(req.URL.String(), slack.SLACK_API) {
+		u, err := url.Parse(a.apiURL + strings.TrimPrefix(req.URL.String(), slack.SLACK_API))
+		if err != nil {
+			return nil, fmt.Errorf("could not rewrite slack api url %s: %s", req.URL, err)
+		}
+		req.URL = u
+		req.Host = u.Host
+	}
+	return a.client.Do(req)
+}
+
+func listenRTM(rtm *slack.RTM, ch chan<- *slack.MessageEvent) {
+	logrus.Infof("Listening Slack RTM Messages")
+
+	for msg := range rtm.IncomingEvents {
+		switch ev := msg.Data.(type) {
+		case *slack.MessageEvent:
+			ch <- ev
+
+		default:
+			logrus.Debugf("Ignored Slack Event %#v", ev)
+		}
+	}
+	close(ch)
+}
+
+// seenMessages keeps track of the last received messages to drop the ones
+// that are delivered twice, like a mention that is both a message and an
+// app_mention event
+type seenMessages struct {
+	keys  map[string]bool
+	order []string
+	size  int
+	m     sync.Mutex
+}
+
+func newSeenMessages(size int) *seenMessages {
+	return &seenMessages{
+		keys: make(map[string]bool),
+		size: size,
+	}
+}
+
+// Seen records the message and returns true if it was already recorded
+func (s *seenMessages) Seen(msg *slack.MessageEvent) bool {
+	if msg.Timestamp == "" {
+		return false
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	key := msg.Channel + "/" + msg.Timestamp
+	if s.keys[key] {
+		return true
+	}
+
+	s.keys[key] = true
+	s.order = append(s.order, key)
+	if len(s.order) > s.size {
+		delete(s.keys, s.order[0])
+		s.order = s.order[1:]
+	}
+	return false
+}
+
 type messageMatcher struct {
 	botID         string
 	prefixMatches []string
-	rtm           *slack.RTM
+	client        *slack.Client
 	stealth       bool
 }
 
-func newMessageMatcher(rtm *slack.RTM, stealth bool) messageMatcher {
+func newMessageMatcher(client *slack.Client, botID string, stealth bool) messageMatcher {
 	return messageMatcher{
-		rtm:     rtm,
-		stealth: stealth,
+		botID:         botID,
+		prefixMatches: []string{fmt.Sprintf("<@%s>", botID)},
+		client:        client,
+		stealth:       stealth,
 	}
 }
 
 // GetUser finds the username given a userID
 func (m *messageMatcher) getUser(userID string) string {
-	u, err := m.rtm.GetUserInfo(userID)
+	u, err := m.client.GetUserInfo(userID)
 	if err != nil {
 		logrus.Errorf("could not find user with id %s because %s, weeeird", userID, err)
 		return "unknown-user"
@@ -187,7 +321,7 @@ func (m *messageMatcher) getChannel(channelID string) string {
 		return "IM"
 	}
 
-	ch, err := m.rtm.GetChannelInfo(channelID)
+	ch, err := m.client.GetChannelInfo(channelID)
 	if err != nil {
 		logrus.Errorf("could not find channel with id %s: %s", channelID, err)
 		return "unknown-channel"
@@ -195,23 +329,18 @@ func (m *messageMatcher) getChannel(channelID string) string {
 	return ch.Name
 }
 
-// Init has to be delayed until the point in which the RTM is actually working.
-// The simples way to do this lazily is to do it when the message listening starts
-func (m *messageMatcher) init() {
-	if m.botID == "" {
-		m.botID = m.rtm.GetInfo().User.ID
-		m.prefixMatches = []string{fmt.Sprintf("<@%s>", m.botID)}
-	}
-}
-
 func (m *messageMatcher) Matches(msg *slack.MessageEvent) (message, error) {
-	m.init()
-
 	if text, ok := m.shouldCare(msg); ok {
 		username := m.getUser(msg.User)
 		channel := m.getChannel(msg.Channel)
 		isIM := m.isIMChannel(msg.Channel)
 
+		// Messages that are already in a thread are replied in the same thread
+		threadID := msg.ThreadTimestamp
+		if threadID == "" {
+			threadID = msg.Timestamp
+		}
+
 		return message{
 			text:      text,
 			userID:    msg.User,
@@ -219,6 +348,7 @@ func (m *messageMatcher) Matches(msg *slack.MessageEvent) (message, error) {
 			username:  username,
 			channel:   channel,
 			isIM:      isIM,
+			threadID:  threadID,
 		}, nil
 	}
 	return message{}, errIgnoredMessage
@@ -258,36 +388,93 @@ func (m *messageMatcher) shouldCare(message *slack.MessageEvent) (string, bool)
 
 // Listen listens to slack messages and sends the matching ones through the channel as requests
 func (c *Client) Listen(ch chan<- meeseeks.Request) {
-	logrus.Infof("Listening Slack RTM Messages")
-
-	for msg := range c.rtm.IncomingEvents {
-		switch ev := msg.Data.(type) {
-		case *slack.MessageEvent:
-			message, err := c.matcher.Matches(ev)
-			if err != nil {
-				continue
-			}
+	c.listen(c.incoming, ch)
+}
 
-			r, err := requestFromMessage(message)
-			if err != nil {
-				logrus.Debugf("Failed to parse message '%s' as a command: %s", message.GetText(), err)
-				c.Reply(formatter.FailureReply(r, err))
-				continue
-			}
+func (c *Client) listen(incoming <-chan *slack.MessageEvent, ch chan<- meeseeks.Request) {
+	for ev := range incoming {
+		if c.seen.Seen(ev) {
+			logrus.Debugf("Message %s on %s was already received, ignoring", ev.Timestamp, ev.Channel)
+			continue
+		}
 
-			logrus.Debugf("Sending Slack message %#v to messages channel", message)
-			ch <- r
+		message, err := c.matcher.Matches(ev)
+		if err != nil {
+			continue
+		}
 
-		default:
-			logrus.Debugf("Ignored Slack Event %#v", ev)
+		r, err := requestFromMessage(message)
+		if err != nil {
+			logrus.Debugf("Failed to parse message '%s' as a command: %s", message.GetText(), err)
+			c.Reply(formatter.FailureReply(r, err))
+			continue
 		}
+
+		logrus.Debugf("Sending Slack message %#v to messages channel", message)
+		ch <- r
 	}
 	logrus.Infof("Stopped listening to messages")
 }
 
 // Reply replies to the user building a regular message
+//
+// When the output is too long the message only carries a summary and the whole
+// output is uploaded as a file snippet
 func (c *Client) Reply(r formatter.Reply) {
-	c.getReplyStyle(r.ReplyStyle()).Reply(r)
+	style := c.getReplyStyle(r.ReplyStyle())
+	if _, ok := style.(nullReplyStyle); ok || !r.OutputTooLong() {
+		style.Reply(r)
+		return
+	}
+
+	style.Reply(r.Summary())
+	if err := c.uploadOutput(r); err != nil {
+		logrus.Errorf("failed to upload output of job %d to %s: %s", r.JobID(), r.ChannelID(), err)
+	}
+}
+
+func (c *Client) uploadOutput(r formatter.Reply) error {
+	name := "output"
+	if r.JobID() != 0 {
+		name = fmt.Sprintf("job-%d", r.JobID())
+	}
+
+	// Response urls don't accept files, so replies to slash commands get their
+	// summary through the response url and the file uploaded to the channel.
+	// The slack client can't upload files to a thread, so the api is called
+	// directly
+	values := url.Values{
+		"token":    {c.token},
+		"content":  {r.Output()},
+		"filetype": {"text"},
+		"filename": {name + ".txt"},
+		"title":    {name},
+		"channels": {r.ChannelID()},
+	}
+	if r.ThreadID() != "" {
+		values.Set("thread_ts", r.ThreadID())
+	}
+
+	req, err := http.NewRequest("POST", slack.SLACK_API+"files.upload", strings.NewReader(values.Encode()))
+	if err != nil {
+		return fmt.Errorf("could not create upload request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not upload file: %s", err)
+	}
+	defer resp.Body.Close()
+
+	response := slack.SlackResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("could not parse upload response: %s", err)
+	}
+	if !response.Ok {
+		return fmt.Errorf("could not upload file: %s", response.Error)
+	}
+	return nil
 }
 
 type replyStyle interface {
@@ -319,7 +506,7 @@ func (a attachmentReplyStyle) Reply(r formatter.Reply) {
 		},
 	}
 	logrus.Debugf("Replying in Slack %s with %#v", r.ChannelID(), params)
-	if _, _, err = a.client.PostMessage(r.ChannelID(), "", params); err != nil {
+	if err = post(a.client, r, "", params); err != nil {
 		logrus.Errorf("failed post attachment message %s on %s: %s", content, r.ChannelID(), err)
 	}
 }
@@ -342,11 +529,34 @@ func (t textReplyStyle) Reply(r formatter.Reply) {
 		UnfurlMedia: true,
 	}
 	logrus.Debugf("Replying in Slack %s with %#v and text: %s", r.ChannelID(), params, content)
-	if _, _, err = t.client.PostMessage(r.ChannelID(), content, params); err != nil {
+	if err = post(t.client, r, content, params); err != nil {
 		logrus.Errorf("failed post message %s on %s: %s", content, r.ChannelID(), err)
 	}
 }
 
+// post delivers the message through the reply response url when there is one,
+// or as a regular chat message when not or when the response url is no longer
+// valid, adding the confirmation buttons when the reply asks for a confirmation
+func post(client *slack.Client, r formatter.Reply, text string, params slack.PostMessageParameters) error {
+	if confirmationID := r.ConfirmationID(); confirmationID != "" {
+		params.Attachments = append(params.Attachments, confirmationAttachment(confirmationID))
+	}
+	if responseURL := r.ResponseURL(); responseURL != "" {
+		// response urls expire after 30 minutes and only accept 5 messages
+		err := postToResponseURL(responseURL, text, params)
+		if err == nil {
+			return nil
+		}
+		logrus.Warnf("could not reply through the response url, posting on %s instead: %s", r.ChannelID(), err)
+	}
+	if threadID := r.ThreadID(); threadID != "" {
+		params.ThreadTimestamp = threadID
+		params.ReplyBroadcast = r.ThreadBroadcast()
+	}
+	_, _, err := client.PostMessage(r.ChannelID(), text, params)
+	return err
+}
+
 type nullReplyStyle struct{}
 
 func (c nullReplyStyle) Reply(r formatter.Reply) {
@@ -362,6 +572,7 @@ type message struct {
 	username  string
 	userID    string
 	isIM      bool
+	threadID  string
 }
 
 // GetText returns the message text
@@ -404,6 +615,11 @@ func (m message) IsIM() bool {
 	return m.isIM
 }
 
+// GetThreadID returns the timestamp of the thread the message belongs to
+func (m message) GetThreadID() string {
+	return m.threadID
+}
+
 func requestFromMessage(msg meeseeks.Message) (meeseeks.Request, error) {
 	args, err := parser.Parse(msg.GetText())
 	logrus.Debugf("Command '%s' parsed as %#v", msg.GetText(), args)
@@ -426,5 +642,7 @@ func requestFromMessage(msg meeseeks.Message) (meeseeks.Request, error) {
 		ChannelID:   msg.GetChannelID(),
 		ChannelLink: msg.GetChannelLink(),
 		IsIM:        msg.IsIM(),
+		Origin:      Origin,
+		ThreadID:    msg.GetThreadID(),
 	}, nil
 }
@@ -0,0 +1,193 @@
+package slack_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/slack"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/template"
+)
+
+// apiCall is a call received by the fake slack api
+type apiCall struct {
+	method string
+	form   url.Values
+}
+
+var fakeAPIAnswers = map[string]string{
+	"auth.test":        `{"ok": true, "user_id": "UBOT"}`,
+	"users.info":       `{"ok": true, "user": {"id": "U1", "name": "someone"}}`,
+	"channels.info":    `{"ok": true, "channel": {"id": "C1", "name": "general"}}`,
+	"chat.postMessage": `{"ok": true, "channel": "C1", "ts": "1500000000.000200"}`,
+	"files.upload":     `{"ok": true, "file": {"id": "F1"}}`,
+}
+
+// newFakeSlackAPI starts a fake slack api that records the calls it receives,
+// user lookups wait for lookups to be closed when it is not nil
+func newFakeSlackAPI(t *testing.T, lookups <-chan struct{}) (*httptest.Server, chan apiCall) {
+	calls := make(chan apiCall, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		mocks.Must(t, "could not parse api call", r.ParseForm())
+		if method == "users.info" && lookups != nil {
+			<-lookups
+		}
+		calls <- apiCall{method: method, form: r.PostForm}
+
+		w.Header().Set("Content-Type", "application/json")
+		answer, ok := fakeAPIAnswers[method]
+		if !ok {
+			answer = `{"ok": false, "error": "unknown_method"}`
+		}
+		w.Write([]byte(answer))
+	}))
+	return server, calls
+}
+
+func connectToFakeSlackAPI(t *testing.T, server *httptest.Server) *slack.Client {
+	c, err := slack.Connect(slack.ConnectionOpts{
+		Token:  "xoxb-test",
+		Mode:   slack.ModeEvents,
+		APIURL: server.URL + "/",
+	})
+	mocks.Must(t, "could not connect to the fake slack api", err)
+	return c
+}
+
+// waitForCall returns the first call to the passed method, skipping the rest
+func waitForCall(t *testing.T, calls <-chan apiCall, method string) apiCall {
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case call := <-calls:
+			if call.method == method {
+				return call
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for a call to %s", method)
+		}
+	}
+}
+
+func TestMessagesAreRepliedInTheirThread(t *testing.T) {
+	server, _ := newFakeSlackAPI(t, nil)
+	defer server.Close()
+
+	l := slack.NewEventsListener(http.NewServeMux(), connectToFakeSlackAPI(t, server),
+		"/slack/events-test", "secret")
+	defer l.Shutdown()
+
+	ch := make(chan meeseeks.Request)
+	go l.Listen(ch)
+
+	tt := []struct {
+		name     string
+		event    string
+		expected string
+	}{
+		{
+			name:     "message in a thread",
+			event:    `{"type": "message", "user": "U1", "text": "echo in thread", "channel": "D1", "ts": "1.5", "thread_ts": "1.0"}`,
+			expected: "1.0",
+		},
+		{
+			name:     "message in the channel",
+			event:    `{"type": "message", "user": "U1", "text": "echo in channel", "channel": "D1", "ts": "2.5"}`,
+			expected: "2.5",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			l.HandleEvent(w, signedRequest("secret", "/slack/events-test",
+				`{"type": "event_callback", "event": `+tc.event+`}`, time.Now()))
+			mocks.AssertEquals(t, http.StatusOK, w.Code)
+
+			select {
+			case r := <-ch:
+				mocks.AssertEquals(t, tc.expected, r.ThreadID)
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for the request")
+			}
+		})
+	}
+}
+
+func TestThreadedRepliesArePostedInTheThread(t *testing.T) {
+	server, calls := newFakeSlackAPI(t, nil)
+	defer server.Close()
+	c := connectToFakeSlackAPI(t, server)
+
+	tt := []struct {
+		name              string
+		style             string
+		expectedThread    string
+		expectedBroadcast string
+	}{
+		{name: "not threaded", style: "text"},
+		{name: "threaded", style: "text,thread", expectedThread: "1.0"},
+		{name: "broadcast", style: "text,thread_broadcast", expectedThread: "1.0", expectedBroadcast: "true"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			formatter.Configure(formatter.FormatConfig{
+				Templates: map[string]string{
+					template.Success: "{{ .command }} done",
+				},
+				ReplyStyle: map[string]string{
+					template.Success: tc.style,
+				},
+			})
+
+			c.Reply(formatter.SuccessReply(meeseeks.Request{
+				Command:   "deploy",
+				ChannelID: "C1",
+				ThreadID:  "1.0",
+			}))
+
+			call := waitForCall(t, calls, "chat.postMessage")
+			mocks.AssertEquals(t, "deploy done", call.form.Get("text"))
+			mocks.AssertEquals(t, tc.expectedThread, call.form.Get("thread_ts"))
+			mocks.AssertEquals(t, tc.expectedBroadcast, call.form.Get("reply_broadcast"))
+		})
+	}
+}
+
+func TestLongOutputIsUploadedAsAFile(t *testing.T) {
+	formatter.Configure(formatter.FormatConfig{
+		Templates: map[string]string{
+			template.Success: "{{ .command }} done\n{{ .output }}",
+		},
+		ReplyStyle: map[string]string{
+			template.Success: "text,thread",
+		},
+		MaxOutputSize: 10,
+	})
+
+	server, calls := newFakeSlackAPI(t, nil)
+	defer server.Close()
+
+	c := connectToFakeSlackAPI(t, server)
+	c.Reply(formatter.SuccessReply(meeseeks.Request{
+		Command:   "deploy",
+		ChannelID: "C1",
+		ThreadID:  "1.0",
+	}).WithJobID(42).WithOutput("line 1\nline 2\nline 3\n"))
+
+	post := waitForCall(t, calls, "chat.postMessage")
+	mocks.AssertEquals(t, "1.0", post.form.Get("thread_ts"))
+	mocks.AssertEquals(t, true, strings.HasPrefix(post.form.Get("text"), "deploy done"))
+
+	upload := waitForCall(t, calls, "files.upload")
+	mocks.AssertEquals(t, "job-42.txt", upload.form.Get("filename"))
+	mocks.AssertEquals(t, "C1", upload.form.Get("channels"))
+	mocks.AssertEquals(t, "1.0", upload.form.Get("thread_ts"))
+	mocks.AssertEquals(t, "line 1\nline 2\nline 3\n", upload.form.Get("content"))
+}
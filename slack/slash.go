@@ -0,0 +1,148 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+
+	"github.com/nlopes/slack"
+	"github.com/sirupsen/logrus"
+)
+
+var responseURLClient = &http.Client{Timeout: 30 * time.Second}
+
+// SlashCommandListener receives slack slash commands over http
+type SlashCommandListener struct {
+	client        *Client
+	signingSecret string
+
+	incoming chan meeseeks.Request
+	done     chan struct{}
+}
+
+// NewSlashCommandListener creates a new slash command listener and registers it in the passed mux and path
+func NewSlashCommandListener(mux *http.ServeMux, client *Client, path, signingSecret string) *SlashCommandListener {
+	s := &SlashCommandListener{
+		client:        client,
+		signingSecret: signingSecret,
+
+		incoming: make(chan meeseeks.Request, pendingSize),
+		done:     make(chan struct{}),
+	}
+	mux.HandleFunc(path, s.HandleCommand)
+	return s
+}
+
+// Listen implements the executor.Listener interface
+func (s *SlashCommandListener) Listen(ch chan<- meeseeks.Request) {
+	logrus.Infof("Listening Slack Slash Commands")
+
+	for {
+		select {
+		case r := <-s.incoming:
+			ch <- r
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Shutdown stops listening for slash commands
+func (s *SlashCommandListener) Shutdown() error {
+	logrus.Infof("Shutting down Slack Slash Commands listener")
+	close(s.done)
+	return nil
+}
+
+// HandleCommand implements the http handle request function interface
+func (s *SlashCommandListener) HandleCommand(w http.ResponseWriter, r *http.Request) {
+	body, err := readSignedBody(r, s.signingSecret)
+	if err != nil {
+		logrus.Debugf("Rejected slack slash command: %s", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid slash command payload: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	req, err := requestFromMessage(message{
+		text:      form.Get("text"),
+		userID:    form.Get("user_id"),
+		channelID: form.Get("channel_id"),
+	})
+	if err != nil {
+		logrus.Debugf("Failed to parse slash command '%s': %s", form.Get("text"), err)
+		// An answer without response type is only shown to the calling user
+		w.Write([]byte(fmt.Sprintf("could not run %s %s: %s", form.Get("command"), form.Get("text"), err)))
+		return
+	}
+	req.ResponseURL = form.Get("response_url")
+
+	// Slack expects an answer within 3 seconds, so don't wait for the slack
+	// api to fill in the names, nor for the executor
+	go func() {
+		req = s.enrich(req)
+		select {
+		case s.incoming <- req:
+		case <-s.done:
+			logrus.Debugf("Dropping slash command '%s', the listener is shut down", form.Get("text"))
+		}
+	}()
+
+	// Answering in channel shows the invoked command to everyone
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"response_type": "in_channel"}`))
+}
+
+// enrich fills in the user and channel names, which require calling the slack api
+func (s *SlashCommandListener) enrich(req meeseeks.Request) meeseeks.Request {
+	msg := message{
+		userID:    req.UserID,
+		channelID: req.ChannelID,
+		username:  s.client.GetUsername(req.UserID),
+		channel:   s.client.GetChannel(req.ChannelID),
+		isIM:      s.client.IsIM(req.ChannelID),
+	}
+	req.Username = msg.GetUsername()
+	req.Channel = msg.GetChannel()
+	req.ChannelLink = msg.GetChannelLink()
+	req.IsIM = msg.IsIM()
+	return req
+}
+
+type responseURLMessage struct {
+	ResponseType string             `json:"response_type"`
+	Text         string             `json:"text,omitempty"`
+	Attachments  []slack.Attachment `json:"attachments,omitempty"`
+}
+
+func postToResponseURL(responseURL, text string, params slack.PostMessageParameters) error {
+	payload, err := json.Marshal(responseURLMessage{
+		ResponseType: "in_channel",
+		Text:         text,
+		Attachments:  params.Attachments,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal response url message: %s", err)
+	}
+
+	resp, err := responseURLClient.Post(responseURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("could not post to response url: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response url answered with status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package slack_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/slack"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/formatter"
+	"gitlab.com/yakshaving.art/meeseeks-box/text/template"
+)
+
+func TestSlashCommandRejectsUnsignedRequests(t *testing.T) {
+	l := slack.NewSlashCommandListener(http.NewServeMux(), nil, "/slack/commands-test", "secret")
+
+	w := httptest.NewRecorder()
+	l.HandleCommand(w, httptest.NewRequest(http.MethodPost, "/slack/commands-test",
+		strings.NewReader("command=/meeseeks&text=echo+hello")))
+	mocks.AssertEquals(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	l.HandleCommand(w, signedRequest("other-secret", "/slack/commands-test",
+		"command=/meeseeks&text=echo+hello", time.Now()))
+	mocks.AssertEquals(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRepliesAreDeliveredThroughTheResponseURL(t *testing.T) {
+	formatter.Configure(formatter.FormatConfig{
+		Templates: map[string]string{
+			template.Success: "{{ .command }} done",
+		},
+		ReplyStyle: map[string]string{
+			template.Success: "text",
+		},
+	})
+
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := map[string]interface{}{}
+		mocks.Must(t, "could not decode response url payload", json.NewDecoder(r.Body).Decode(&payload))
+		received <- payload
+	}))
+	defer server.Close()
+
+	c := slack.Client{}
+	c.Reply(formatter.SuccessReply(meeseeks.Request{
+		Command:     "deploy",
+		ChannelID:   "C1",
+		ResponseURL: server.URL,
+	}))
+
+	select {
+	case payload := <-received:
+		mocks.AssertEquals(t, "in_channel", payload["response_type"])
+		mocks.AssertEquals(t, "deploy done", payload["text"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the response url to be called")
+	}
+}
+
+func TestSlashCommandsAreAcknowledgedBeforeCallingTheSlackAPI(t *testing.T) {
+	lookups := make(chan struct{})
+	server, _ := newFakeSlackAPI(t, lookups)
+	defer server.Close()
+
+	l := slack.NewSlashCommandListener(http.NewServeMux(), connectToFakeSlackAPI(t, server),
+		"/slack/commands-test", "secret")
+	defer l.Shutdown()
+
+	ch := make(chan meeseeks.Request)
+	go l.Listen(ch)
+
+	w := httptest.NewRecorder()
+	l.HandleCommand(w, signedRequest("secret", "/slack/commands-test",
+		"command=/meeseeks&text=echo+hello&user_id=U1&channel_id=C1&response_url=http://localhost/hook", time.Now()))
+	mocks.AssertEquals(t, http.StatusOK, w.Code)
+	mocks.AssertEquals(t, `{"response_type": "in_channel"}`, w.Body.String())
+
+	close(lookups)
+	select {
+	case r := <-ch:
+		mocks.AssertEquals(t, "echo", r.Command)
+		mocks.AssertEquals(t, "someone", r.Username)
+		mocks.AssertEquals(t, "general", r.Channel)
+		mocks.AssertEquals(t, "<#C1|general>", r.ChannelLink)
+		mocks.AssertEquals(t, "http://localhost/hook", r.ResponseURL)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the slash command request")
+	}
+}
+
+func TestRepliesArePostedWhenTheResponseURLExpired(t *testing.T) {
+	formatter.Configure(formatter.FormatConfig{
+		Templates: map[string]string{
+			template.Success: "{{ .command }} done",
+		},
+		ReplyStyle: map[string]string{
+			template.Success: "text",
+		},
+	})
+
+	expired := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "expired_url", http.StatusNotFound)
+	}))
+	defer expired.Close()
+
+	server, calls := newFakeSlackAPI(t, nil)
+	defer server.Close()
+
+	c := connectToFakeSlackAPI(t, server)
+	c.Reply(formatter.SuccessReply(meeseeks.Request{
+		Command:     "deploy",
+		ChannelID:   "C1",
+		ResponseURL: expired.URL,
+	}))
+
+	call := waitForCall(t, calls, "chat.postMessage")
+	mocks.AssertEquals(t, "C1", call.form.Get("channel"))
+	mocks.AssertEquals(t, "deploy done", call.form.Get("text"))
+}
@@ -0,0 +1,167 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jpillora/backoff"
+	"github.com/nlopes/slack"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAPIURL is the slack web api endpoint used to open socket mode connections
+const DefaultAPIURL = "https://slack.com/api/"
+
+// Socket mode envelope types
+const (
+	envelopeHello      = "hello"
+	envelopeDisconnect = "disconnect"
+	envelopeEventsAPI  = "events_api"
+)
+
+// SocketModeOpts groups the options to open a socket mode connection
+type SocketModeOpts struct {
+	AppToken string
+	APIURL   string
+}
+
+// SocketMode listens for events api messages through a socket mode websocket
+type SocketMode struct {
+	appToken   string
+	apiURL     string
+	httpClient *http.Client
+	dialer     *websocket.Dialer
+}
+
+// NewSocketMode creates a new socket mode listener
+func NewSocketMode(opts SocketModeOpts) *SocketMode {
+	apiURL := opts.APIURL
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	return &SocketMode{
+		appToken:   opts.AppToken,
+		apiURL:     apiURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		dialer:     websocket.DefaultDialer,
+	}
+}
+
+type envelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Reason     string          `json:"reason"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+type envelopeAck struct {
+	EnvelopeID string `json:"envelope_id"`
+}
+
+type connectionsOpenResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	URL   string `json:"url"`
+}
+
+// Run keeps a socket mode connection open, reconnecting whenever slack asks for
+// it, and sends the received message events through the passed channel.
+//
+// It only returns when a new connection can't be opened
+func (s *SocketMode) Run(ch chan<- *slack.MessageEvent) error {
+	b := &backoff.Backoff{
+		Min:    100 * time.Millisecond,
+		Max:    10 * time.Second,
+		Factor: 2,
+		Jitter: true,
+	}
+
+	for {
+		conn, err := s.connect()
+		if err != nil {
+			if b.Attempt() > 10 {
+				return fmt.Errorf("failed to open socket mode connection: %s", err)
+			}
+			logrus.Warnf("failed to open socket mode connection: %s. Retrying", err)
+			time.Sleep(b.Duration())
+			continue
+		}
+		b.Reset()
+
+		logrus.Infof("Listening Slack Socket Mode Messages")
+		if err := s.serve(conn, ch); err != nil {
+			logrus.Warnf("socket mode connection dropped: %s. Reconnecting", err)
+		}
+		conn.Close()
+	}
+}
+
+func (s *SocketMode) connect() (*websocket.Conn, error) {
+	req, err := http.NewRequest(http.MethodPost, s.apiURL+"apps.connections.open", nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create connections open request: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.appToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not request a socket mode url: %s", err)
+	}
+	defer resp.Body.Close()
+
+	r := connectionsOpenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("could not parse connections open response: %s", err)
+	}
+	if !r.OK {
+		return nil, fmt.Errorf("slack refused to open a connection: %s", r.Error)
+	}
+
+	conn, _, err := s.dialer.Dial(r.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not dial socket mode url: %s", err)
+	}
+	return conn, nil
+}
+
+func (s *SocketMode) serve(conn *websocket.Conn, ch chan<- *slack.MessageEvent) error {
+	for {
+		e := envelope{}
+		if err := conn.ReadJSON(&e); err != nil {
+			return fmt.Errorf("could not read envelope: %s", err)
+		}
+
+		// Envelopes have to be acknowledged right away, else slack will retry them
+		if e.EnvelopeID != "" {
+			if err := conn.WriteJSON(envelopeAck{EnvelopeID: e.EnvelopeID}); err != nil {
+				return fmt.Errorf("could not acknowledge envelope %s: %s", e.EnvelopeID, err)
+			}
+		}
+
+		switch e.Type {
+		case envelopeHello:
+			logrus.Debugf("Socket mode connection is ready")
+
+		case envelopeDisconnect:
+			logrus.Infof("Slack requested a socket mode disconnection: %s", e.Reason)
+			return nil
+
+		case envelopeEventsAPI:
+			cb := eventCallback{}
+			if err := json.Unmarshal(e.Payload, &cb); err != nil {
+				logrus.Errorf("could not parse events api payload %s: %s", e.Payload, err)
+				continue
+			}
+			if msg, ok := parseMessageEvent(cb.Event); ok {
+				ch <- msg
+			}
+
+		default:
+			logrus.Debugf("Ignored Slack Socket Mode envelope %#v", e)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package slack_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/yakshaving.art/meeseeks-box/mocks"
+	"gitlab.com/yakshaving.art/meeseeks-box/slack"
+
+	"github.com/gorilla/websocket"
+	slackapi "github.com/nlopes/slack"
+)
+
+func TestSocketModeReceivesMessages(t *testing.T) {
+	acks := make(chan string, 1)
+	upgrader := websocket.Upgrader{}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		mocks.AssertEquals(t, "Bearer xapp-token", r.Header.Get("Authorization"))
+		fmt.Fprintf(w, `{"ok": true, "url": "ws%s/link"}`, strings.TrimPrefix(server.URL, "http"))
+	})
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		mocks.Must(t, "could not upgrade connection", err)
+		defer conn.Close()
+
+		mocks.Must(t, "could not send hello", conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"type": "hello"}`)))
+		mocks.Must(t, "could not send reaction event", conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"type": "events_api", "envelope_id": "env-1", "payload": {"type": "event_callback",
+			"event": {"type": "reaction_added", "user": "U1"}}}`)))
+		mocks.Must(t, "could not send message event", conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"type": "events_api", "envelope_id": "env-2", "payload": {"type": "event_callback",
+			"event": {"type": "message", "user": "U1", "channel": "C1", "text": "<@BOT> echo hello", "ts": "1.2"}}}`)))
+
+		for i := 0; i < 2; i++ {
+			ack := struct {
+				EnvelopeID string `json:"envelope_id"`
+			}{}
+			mocks.Must(t, "could not read ack", conn.ReadJSON(&ack))
+			acks <- ack.EnvelopeID
+		}
+	})
+
+	ch := make(chan *slackapi.MessageEvent)
+	go slack.NewSocketMode(slack.SocketModeOpts{
+		AppToken: "xapp-token",
+		APIURL:   server.URL + "/",
+	}).Run(ch)
+
+	select {
+	case msg := <-ch:
+		mocks.AssertEquals(t, "U1", msg.User)
+		mocks.AssertEquals(t, "C1", msg.Channel)
+		mocks.AssertEquals(t, "<@BOT> echo hello", msg.Text)
+		mocks.AssertEquals(t, "1.2", msg.Timestamp)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a message event")
+	}
+
+	for _, expected := range []string{"env-1", "env-2"} {
+		select {
+		case ack := <-acks:
+			mocks.AssertEquals(t, expected, ack)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for ack %s", expected)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"gitlab.com/yakshaving.art/meeseeks-box/meeseeks"
 	"gitlab.com/yakshaving.art/meeseeks-box/text/template"
@@ -17,10 +18,26 @@ const (
 	DefaultErrColorMessage     = "danger"
 )
 
+// DefaultMaxOutputSize is the output size in bytes above which replies carry only a summary
+const DefaultMaxOutputSize = 3000
+
+// summaryLines is the number of trailing output lines shown in a summary
+const summaryLines = 5
+
+// Reply style options, they can be appended to a reply style separated by commas,
+// as in "attachment,thread"
+const (
+	// ThreadOption replies in a thread under the message that triggered the command
+	ThreadOption = "thread"
+	// ThreadBroadcastOption replies in the thread and also sends the reply to the channel
+	ThreadBroadcastOption = "thread_broadcast"
+)
+
 // MessageColors contains the configured reply message colora
 type MessageColors struct {
 	Info    string `yaml:"info"`
 	Success string `yaml:"success"`
+	Warning string `yaml:"warning"`
 	Error   string `yaml:"error"`
 }
 
@@ -30,13 +47,18 @@ type FormatConfig struct {
 	ReplyStyle map[string]string   `yaml:"reply_styles"`
 	Templates  map[string]string   `yaml:"templates"`
 	Messages   map[string][]string `yaml:"messages"`
+
+	// MaxOutputSize is the output size above which the output is uploaded as a file,
+	// 0 uses the default and a negative value disables it
+	MaxOutputSize int `yaml:"max_output_size"`
 }
 
 // Formatter keeps the colors and templates used to format a reply message
 type Formatter struct {
-	colors     MessageColors
-	templates  *template.TemplatesBuilder
-	replyStyle replyStyle
+	colors        MessageColors
+	templates     *template.TemplatesBuilder
+	replyStyle    replyStyle
+	maxOutputSize int
 }
 
 var formatter *Formatter
@@ -44,10 +66,15 @@ var formatter *Formatter
 // Configure sets up the singleton formatter
 func Configure(cnf FormatConfig) {
 	builder := template.NewBuilder().WithMessages(cnf.Messages).WithTemplates(cnf.Templates)
+	maxOutputSize := cnf.MaxOutputSize
+	if maxOutputSize == 0 {
+		maxOutputSize = DefaultMaxOutputSize
+	}
 	formatter = &Formatter{
-		replyStyle: replyStyle{cnf.ReplyStyle},
-		colors:     cnf.Colors,
-		templates:  builder,
+		replyStyle:    replyStyle{cnf.ReplyStyle},
+		colors:        cnf.Colors,
+		templates:     builder,
+		maxOutputSize: maxOutputSize,
 	}
 }
 
@@ -76,20 +103,69 @@ func SuccessReply(req meeseeks.Request) Reply {
 	return formatter.newReplier(template.Success, req)
 }
 
+// WarningReply creates a reply for a command that finished with warnings
+func WarningReply(req meeseeks.Request) Reply {
+	return formatter.newReplier(template.Warning, req)
+}
+
+// ConfirmationReply creates a reply asking the user to confirm the request identified by confirmationID
+func ConfirmationReply(req meeseeks.Request, confirmationID string) Reply {
+	r := formatter.newReplier(template.Confirmation, req)
+	r.confirmationID = confirmationID
+	return r
+}
+
+// ConfirmationExpiredReply creates a reply for a request that was not confirmed in time
+func ConfirmationExpiredReply(req meeseeks.Request) Reply {
+	return formatter.newReplier(template.ConfirmationExpired, req)
+}
+
+// PendingApprovalReply creates a reply for a job that is waiting to be approved
+func PendingApprovalReply(req meeseeks.Request) Reply {
+	return formatter.newReplier(template.PendingApproval, req)
+}
+
+// QueuedReply creates a reply for a job that is waiting behind other jobs to run
+func QueuedReply(req meeseeks.Request, jobsAhead int) Reply {
+	r := formatter.newReplier(template.Queued, req)
+	r.jobsAhead = jobsAhead
+	return r
+}
+
+// RateLimitedReply creates a reply for a request that went over a rate limit
+func RateLimitedReply(req meeseeks.Request, err error) Reply {
+	return formatter.newReplier(template.RateLimited, req).WithError(err)
+}
+
 func (f Formatter) newReplier(action string, req meeseeks.Request) Reply {
-	style := f.replyStyle.Get(action)
+	style, thread := parseReplyStyle(f.replyStyle.Get(action))
 	logrus.Debugf("creating replier '%s' for action %s", style, action)
 
 	return Reply{
 		action:  action,
 		request: req,
 
-		templates: f.templates.Clone(),
-		style:     style,
-		colors:    f.colors,
+		templates:     f.templates.Clone(),
+		style:         style,
+		thread:        thread,
+		colors:        f.colors,
+		maxOutputSize: f.maxOutputSize,
 	}
 }
 
+// parseReplyStyle splits the configured reply style from its thread option
+func parseReplyStyle(configured string) (style, thread string) {
+	for _, part := range strings.Split(configured, ",") {
+		switch part = strings.TrimSpace(part); part {
+		case ThreadOption, ThreadBroadcastOption:
+			thread = part
+		default:
+			style = part
+		}
+	}
+	return
+}
+
 type replyStyle struct {
 	styles map[string]string
 }
@@ -100,7 +176,13 @@ func (r replyStyle) Get(mode string) string {
 		template.UnknownCommand,
 		template.Unauthorized,
 		template.Failure,
-		template.Success:
+		template.Success,
+		template.Warning,
+		template.Confirmation,
+		template.ConfirmationExpired,
+		template.PendingApproval,
+		template.Queued,
+		template.RateLimited:
 
 		if style, ok := r.styles[mode]; ok {
 			return style
@@ -113,12 +195,18 @@ func (r replyStyle) Get(mode string) string {
 type Reply struct {
 	action  string
 	request meeseeks.Request
+	jobID   uint64
 	output  string
 	err     error
 
-	colors    MessageColors
-	templates *template.TemplatesBuilder
-	style     string
+	confirmationID string
+	jobsAhead      int
+
+	colors        MessageColors
+	templates     *template.TemplatesBuilder
+	style         string
+	thread        string
+	maxOutputSize int
 }
 
 // WithOutput stores the text payload to render in the reply
@@ -127,6 +215,12 @@ func (r Reply) WithOutput(output string) Reply {
 	return r
 }
 
+// WithJobID stores the ID of the job the reply is about
+func (r Reply) WithJobID(jobID uint64) Reply {
+	r.jobID = jobID
+	return r
+}
+
 // WithError stores an error to render
 func (r Reply) WithError(err error) Reply {
 	r.err = err
@@ -149,15 +243,82 @@ func (r Reply) Render() (string, error) {
 
 	payload["error"] = r.err
 	payload["output"] = r.output
+	payload["jobid"] = r.jobID
+	payload["confirmationid"] = r.confirmationID
+	payload["jobsahead"] = r.jobsAhead
 
 	return r.templates.Build().Render(r.action, payload)
 }
 
+// JobID returns the ID of the job the reply is about, 0 when there is none
+func (r Reply) JobID() uint64 {
+	return r.jobID
+}
+
+// Output returns the whole output of the reply
+func (r Reply) Output() string {
+	return r.output
+}
+
+// OutputTooLong returns whether the output is over the configured size and
+// should not be sent inline
+func (r Reply) OutputTooLong() bool {
+	return r.maxOutputSize > 0 && len(r.output) > r.maxOutputSize
+}
+
+// Summary returns a copy of the reply that only carries the last lines of the output
+func (r Reply) Summary() Reply {
+	lines := strings.Split(strings.TrimRight(r.output, "\n"), "\n")
+	if len(lines) > summaryLines {
+		lines = lines[len(lines)-summaryLines:]
+	}
+	summary := strings.Join(lines, "\n")
+	if r.maxOutputSize > 0 && len(summary) > r.maxOutputSize {
+		start := len(summary) - r.maxOutputSize
+		for start < len(summary) && !utf8.RuneStart(summary[start]) {
+			start++
+		}
+		summary = summary[start:]
+	}
+
+	r.output = "...\n" + summary + "\n"
+	return r
+}
+
+// ConfirmationID returns the ID the user has to answer to confirm the request,
+// empty when the reply is not asking for a confirmation
+func (r Reply) ConfirmationID() string {
+	return r.confirmationID
+}
+
 // ChannelID returns the channel ID in which to reply
 func (r Reply) ChannelID() string {
 	return r.request.ChannelID
 }
 
+// Origin returns the chat backend the request came from
+func (r Reply) Origin() string {
+	return r.request.Origin
+}
+
+// ResponseURL returns the url through which to deliver the reply, if any
+func (r Reply) ResponseURL() string {
+	return r.request.ResponseURL
+}
+
+// ThreadID returns the thread in which to reply, empty when the reply style is not threaded
+func (r Reply) ThreadID() string {
+	if r.thread == "" {
+		return ""
+	}
+	return r.request.ThreadID
+}
+
+// ThreadBroadcast returns whether a threaded reply should also be sent to the channel
+func (r Reply) ThreadBroadcast() bool {
+	return r.thread == ThreadBroadcastOption
+}
+
 // ReplyStyle returns the style to use to reply
 func (r Reply) ReplyStyle() string {
 	return r.style
@@ -166,10 +327,13 @@ func (r Reply) ReplyStyle() string {
 // Color returns the color to use when decorating the reply
 func (r Reply) Color() string {
 	switch r.action {
-	case template.Handshake:
+	case template.Handshake, template.Confirmation, template.PendingApproval, template.Queued:
 		return r.colors.Info
-	case template.UnknownCommand, template.Unauthorized, template.Failure:
+	case template.UnknownCommand, template.Unauthorized, template.Failure, template.ConfirmationExpired,
+		template.RateLimited:
 		return r.colors.Error
+	case template.Warning:
+		return r.colors.Warning
 	default:
 		return r.colors.Success
 	}
@@ -15,6 +15,7 @@ func TestFormatterTemplates(t *testing.T) {
 		Templates: map[string]string{
 			template.Handshake:      "{{ .command }} hello",
 			template.Success:        "{{ .command }} success!{{ .output }}",
+			template.Warning:        "{{ .command }} warning!",
 			template.Failure:        "{{ .command }} failure! {{ .error }}",
 			template.Unauthorized:   "{{ .command }} unauthorized!",
 			template.UnknownCommand: "{{ .command }} unknown!",
@@ -22,11 +23,13 @@ func TestFormatterTemplates(t *testing.T) {
 		ReplyStyle: map[string]string{
 			template.Handshake:      "text",
 			template.Success:        "text",
+			template.Warning:        "text",
 			template.Unauthorized:   "attachment",
 			template.UnknownCommand: "attachment",
 		},
 		Colors: formatter.MessageColors{
 			Success: "green",
+			Warning: "yellow",
 			Error:   "red",
 			Info:    "blue",
 		},
@@ -81,6 +84,12 @@ func TestFormatterTemplates(t *testing.T) {
 			expectedText:  "test success!",
 			expectedStyle: "text",
 			expectedColor: "green",
+		}, {
+			name:          template.Warning,
+			f:             formatter.WarningReply,
+			expectedText:  "test warning!",
+			expectedStyle: "text",
+			expectedColor: "yellow",
 		}, {
 			name:          template.Unauthorized,
 			f:             formatter.UnauthorizedCommandReply,
@@ -111,3 +120,84 @@ func TestFormatterTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatterThreadedReplies(t *testing.T) {
+	formatter.Configure(formatter.FormatConfig{
+		ReplyStyle: map[string]string{
+			template.Handshake: "text,thread",
+			template.Success:   "attachment",
+			template.Failure:   "attachment, thread_broadcast",
+		},
+	})
+	req := meeseeks.Request{
+		Command:  "test",
+		ThreadID: "1234.5678",
+	}
+
+	tt := []struct {
+		name              string
+		reply             formatter.Reply
+		expectedStyle     string
+		expectedThreadID  string
+		expectedBroadcast bool
+	}{
+		{
+			name:             "threaded",
+			reply:            formatter.HandshakeReply(req),
+			expectedStyle:    "text",
+			expectedThreadID: "1234.5678",
+		},
+		{
+			name:          "not threaded",
+			reply:         formatter.SuccessReply(req),
+			expectedStyle: "attachment",
+		},
+		{
+			name:              "thread broadcast",
+			reply:             formatter.FailureReply(req, errors.New("some error")),
+			expectedStyle:     "attachment",
+			expectedThreadID:  "1234.5678",
+			expectedBroadcast: true,
+		},
+		{
+			name:  "threaded without thread",
+			reply: formatter.HandshakeReply(meeseeks.Request{Command: "test"}),
+			// The thread option does not change the style
+			expectedStyle: "text",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mocks.AssertEquals(t, tc.expectedStyle, tc.reply.ReplyStyle())
+			mocks.AssertEquals(t, tc.expectedThreadID, tc.reply.ThreadID())
+			mocks.AssertEquals(t, tc.expectedBroadcast, tc.reply.ThreadBroadcast())
+		})
+	}
+}
+
+func TestFormatterSummarizesLongOutputs(t *testing.T) {
+	formatter.Configure(formatter.FormatConfig{
+		Templates: map[string]string{
+			template.Success: "{{ .output }}",
+		},
+		MaxOutputSize: 20,
+	})
+
+	short := formatter.SuccessReply(meeseeks.Request{}).WithJobID(1).WithOutput("short output")
+	mocks.AssertEquals(t, false, short.OutputTooLong())
+
+	output := "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\n"
+	long := formatter.SuccessReply(meeseeks.Request{}).WithJobID(2).WithOutput(output)
+	mocks.AssertEquals(t, true, long.OutputTooLong())
+	mocks.AssertEquals(t, uint64(2), long.JobID())
+
+	summary := long.Summary()
+	s, err := summary.Render()
+	mocks.Must(t, "could not render summary", err)
+	mocks.AssertEquals(t, "...\nline 4\nline 5\nline 6\n", s)
+	mocks.AssertEquals(t, output, long.Output())
+
+	formatter.Configure(formatter.FormatConfig{MaxOutputSize: -1})
+	disabled := formatter.SuccessReply(meeseeks.Request{}).WithOutput(output)
+	mocks.AssertEquals(t, false, disabled.OutputTooLong())
+}
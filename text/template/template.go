@@ -15,9 +15,16 @@ import (
 const (
 	Handshake      = "handshake"
 	Success        = "success"
+	Warning        = "warning"
 	Failure        = "failure"
 	UnknownCommand = "unknowncommand"
 	Unauthorized   = "unauthorized"
+
+	Confirmation        = "confirmation"
+	ConfirmationExpired = "confirmationexpired"
+	PendingApproval     = "pendingapproval"
+	Queued              = "queued"
+	RateLimited         = "ratelimited"
 )
 
 // Default command templates
@@ -25,12 +32,24 @@ var (
 	DefaultHandshakeTemplate = fmt.Sprintf("{{ AnyValue \"%s\" . }}", Handshake)
 	DefaultSuccessTemplate   = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }}"+
 		"{{ with $out := .output }}\n```\n{{ $out }}```{{ end }}", Success)
+	DefaultWarningTemplate = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }} :warning:"+
+		"{{ with $out := .output }}\n```\n{{ $out }}```{{ end }}", Warning)
 	DefaultFailureTemplate = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }} :disappointed: {{ .error }}"+
 		"{{ with $out := .output }}\n```\n{{ $out }}```{{ end }}", Failure)
 	DefaultUnknownCommandTemplate = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }} {{ .command }}",
 		UnknownCommand)
 	DefaultUnauthorizedTemplate = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }} {{ .command }}: {{ .error }}",
 		Unauthorized)
+	DefaultConfirmationTemplate = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }} {{ .command }} {{ .args }}? "+
+		"Answer with `confirm {{ .confirmationid }}` or `dismiss {{ .confirmationid }}`", Confirmation)
+	DefaultConfirmationExpiredTemplate = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }} {{ .command }} {{ .args }}",
+		ConfirmationExpired)
+	DefaultPendingApprovalTemplate = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }} {{ .command }} {{ .args }}, "+
+		"job {{ .jobid }} will run once it's approved with `approve {{ .jobid }}`", PendingApproval)
+	DefaultQueuedTemplate = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }} {{ .command }} {{ .args }}, "+
+		"job {{ .jobid }} is queued behind {{ .jobsahead }} jobs", Queued)
+	DefaultRateLimitedTemplate = fmt.Sprintf("{{ .userlink }} {{ AnyValue \"%s\" . }} {{ .command }}: {{ .error }}",
+		RateLimited)
 )
 
 // GetDefaultTemplates returns a map with the default templates
@@ -38,9 +57,16 @@ func GetDefaultTemplates() map[string]string {
 	return map[string]string{
 		Handshake:      DefaultHandshakeTemplate,
 		Success:        DefaultSuccessTemplate,
+		Warning:        DefaultWarningTemplate,
 		Failure:        DefaultFailureTemplate,
 		UnknownCommand: DefaultUnknownCommandTemplate,
 		Unauthorized:   DefaultUnauthorizedTemplate,
+
+		Confirmation:        DefaultConfirmationTemplate,
+		ConfirmationExpired: DefaultConfirmationExpiredTemplate,
+		PendingApproval:     DefaultPendingApprovalTemplate,
+		Queued:              DefaultQueuedTemplate,
+		RateLimited:         DefaultRateLimitedTemplate,
 	}
 }
 
@@ -50,9 +76,16 @@ var (
 		"Ooh, yeah! Can do!", "Ooh, ok!", "Yes, siree!",
 		"Ooh, I'm Mr. Meeseeks! Look at me!"}
 	DefaultSuccessMessages        = []string{"All done!", "Mr Meeseeks", "Uuuuh, nice!"}
+	DefaultWarningMessages        = []string{"Uuuh, done, but with warnings"}
 	DefaultFailedMessages         = []string{"Uuuh!, no, it failed"}
 	DefaultUnauthorizedMessages   = []string{"Uuuuh, yeah! you are not allowed to do"}
 	DefaultUnknownCommandMessages = []string{"Uuuh! no, I don't know how to do"}
+
+	DefaultConfirmationMessages        = []string{"Uuuh, are you sure you want me to run"}
+	DefaultConfirmationExpiredMessages = []string{"Uuuh, nobody confirmed, so I'm not running"}
+	DefaultPendingApprovalMessages     = []string{"Uuuh, somebody has to approve"}
+	DefaultQueuedMessages              = []string{"Uuuh, I'm busy, I'll run"}
+	DefaultRateLimitedMessages         = []string{"Uuuh, slow down! I can't run"}
 )
 
 // GetDefaultMessages returns a map with the default messages
@@ -60,9 +93,16 @@ func GetDefaultMessages() map[string][]string {
 	return map[string][]string{
 		Handshake:      DefaultHandshakeMessages,
 		Success:        DefaultSuccessMessages,
+		Warning:        DefaultWarningMessages,
 		Failure:        DefaultFailedMessages,
 		UnknownCommand: DefaultUnknownCommandMessages,
 		Unauthorized:   DefaultUnauthorizedMessages,
+
+		Confirmation:        DefaultConfirmationMessages,
+		ConfirmationExpired: DefaultConfirmationExpiredMessages,
+		PendingApproval:     DefaultPendingApprovalMessages,
+		Queued:              DefaultQueuedMessages,
+		RateLimited:         DefaultRateLimitedMessages,
 	}
 }
 